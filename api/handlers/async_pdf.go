@@ -3,6 +3,8 @@ package handlers
 import (
 	"designmypdf/pkg/key"
 	"designmypdf/pkg/pdfjob"
+	"designmypdf/pkg/template"
+	"errors"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
@@ -10,6 +12,8 @@ import (
 
 // GeneratePdfAsync enqueues a PDF generation job and returns its ID immediately.
 // Auth: dmp_KEY header (same as the synchronous route).
+// Query: priority=high|normal|low, at most the priority of the key (its
+// default).
 func GeneratePdfAsync(jobSvc *pdfjob.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		keyService := key.NewService(key.Repository{})
@@ -33,16 +37,39 @@ func GeneratePdfAsync(jobSvc *pdfjob.Service) fiber.Handler {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "No template provided"})
 		}
 
-		format := c.Query("format", "A4")
+		templateEntity, templateVersion, status, err := resolveTemplate(c, templateID)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{"message": err.Error()})
+		}
+		if err := template.ValidatePayload(templateEntity, c.Body()); err != nil {
+			var payloadErr *template.PayloadError
+			if errors.As(err, &payloadErr) {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(payloadErrorBody(payloadErr.Errors))
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+		}
+
+		opts, err := generateOptionsFromRequest(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
+		opts.TemplateVersion = templateVersion
+		priority, err := pdfjob.ResolvePriority(c.Query("priority"), keyEntity, false)
+		if err != nil {
+			return c.Status(priorityErrorStatus(err)).JSON(fiber.Map{"message": err.Error()})
+		}
 
-		job, err := jobSvc.EnqueueJob(keyEntity.ID, templateID, c.Body(), format)
+		job, err := jobSvc.EnqueueJob(keyEntity.ID, templateID, c.Body(), opts, priority)
 		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": fmt.Sprintf("failed to enqueue job: %v", err)})
+			return c.Status(enqueueErrorStatus(err)).JSON(fiber.Map{"message": fmt.Sprintf("failed to enqueue job: %v", err)})
 		}
 
 		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
-			"job_id": job.ID,
-			"status": job.Status,
+			"job_id":           job.ID,
+			"status":           job.Status,
+			"output":           job.Output,
+			"priority":         job.Priority,
+			"template_version": job.TemplateVersion,
 		})
 	}
 }
@@ -75,10 +102,15 @@ func GetJobStatus(jobSvc *pdfjob.Service) fiber.Handler {
 		}
 
 		return c.JSON(fiber.Map{
-			"job_id": job.ID,
-			"status": job.Status,
-			"path":   job.ResultPath,
-			"error":  job.ErrorMessage,
+			"job_id":           job.ID,
+			"status":           job.Status,
+			"output":           job.Output,
+			"priority":         job.Priority,
+			"path":             job.ResultPath,
+			"cache_hit":        job.CacheHit,
+			"template_version": job.TemplateVersion,
+			"attempts":         job.Attempts,
+			"error":            job.ErrorMessage,
 		})
 	}
 }
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"designmypdf/pkg/key"
+	"designmypdf/pkg/pdfjob"
+	"designmypdf/pkg/template"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBatchFieldErrors caps the schema errors reported for a rejected batch.
+const maxBatchFieldErrors = 100
+
+// GeneratePdfBatch enqueues one job per payload for the same template and
+// returns the batch ID immediately.
+// Auth: dmp_KEY header (same as the single async route).
+//
+// Body: a JSON array of payload objects, or NDJSON (one object per line) sent
+// as application/x-ndjson or as a multipart "file" upload.
+// Query: version (published by default), format, orientation, margin, locale (template defaults when omitted),
+// zip=true to build a single ZIP of all results, cache=false to skip the
+// shared result cache, priority=high|normal|low (low by default, at most the
+// priority of the key).
+// When the template declares a variables schema, any invalid payload rejects
+// the whole batch with 422; error fields are prefixed by the payload index.
+func GeneratePdfBatch(jobSvc *pdfjob.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keyService := key.NewService(key.Repository{})
+
+		keyValue := c.Get("dmp_KEY")
+		if keyValue == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "No key provided"})
+		}
+
+		keyEntity, err := keyService.GetKeyByValue(keyValue)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid key"})
+		}
+
+		templateID := c.Params("templateId")
+		if templateID == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "No template provided"})
+		}
+
+		templateEntity, templateVersion, status, err := resolveTemplate(c, templateID)
+		if err != nil {
+			return c.Status(status).JSON(fiber.Map{"message": err.Error()})
+		}
+
+		payloads, err := batchPayloadsFromRequest(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
+		if len(payloads) == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "batch must contain at least one payload"})
+		}
+		if len(payloads) > pdfjob.MaxBatchSize {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+				"message": fmt.Sprintf("batch too large: %d payloads (max %d)", len(payloads), pdfjob.MaxBatchSize),
+			})
+		}
+
+		var fieldErrors []template.FieldError
+		for i, payload := range payloads {
+			err := template.ValidatePayload(templateEntity, payload)
+			if err == nil {
+				continue
+			}
+			var payloadErr *template.PayloadError
+			if !errors.As(err, &payloadErr) {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": err.Error()})
+			}
+			for _, fe := range payloadErr.Errors {
+				fe.Field = strings.TrimSuffix(fmt.Sprintf("%d.%s", i, fe.Field), ".")
+				fieldErrors = append(fieldErrors, fe)
+			}
+			if len(fieldErrors) >= maxBatchFieldErrors {
+				break
+			}
+		}
+		if len(fieldErrors) > 0 {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(payloadErrorBody(fieldErrors))
+		}
+
+		if keyEntity.KeyCountUsed+len(payloads) > keyEntity.KeyCount {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"message":   "Key usage limit reached",
+				"remaining": keyEntity.KeyCount - keyEntity.KeyCountUsed,
+			})
+		}
+
+		opts, err := generateOptionsFromRequest(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": err.Error()})
+		}
+		opts.TemplateVersion = templateVersion
+		zip := c.QueryBool("zip", false)
+		priority, err := pdfjob.ResolvePriority(c.Query("priority"), keyEntity, true)
+		if err != nil {
+			return c.Status(priorityErrorStatus(err)).JSON(fiber.Map{"message": err.Error()})
+		}
+
+		batch, err := jobSvc.EnqueueBatch(keyEntity.ID, templateID, payloads, opts, zip, priority)
+		if err != nil {
+			return c.Status(enqueueErrorStatus(err)).JSON(fiber.Map{"message": fmt.Sprintf("failed to enqueue batch: %v", err)})
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"batch_id":    batch.ID,
+			"status":      batch.Status,
+			"total_count": batch.TotalCount,
+			"priority":    priority,
+		})
+	}
+}
+
+// GetBatchStatus returns aggregated progress and child job results of a batch.
+// Auth: same dmp_KEY that created the batch must be provided.
+func GetBatchStatus(jobSvc *pdfjob.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keyService := key.NewService(key.Repository{})
+
+		keyValue := c.Get("dmp_KEY")
+		if keyValue == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "No key provided"})
+		}
+
+		keyEntity, err := keyService.GetKeyByValue(keyValue)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid key"})
+		}
+
+		progress, err := jobSvc.GetBatchProgress(c.Params("batchId"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"message": "Batch not found"})
+		}
+
+		if progress.Batch.KeyID != keyEntity.ID {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"message": "Access denied"})
+		}
+
+		jobs := make([]fiber.Map, len(progress.Jobs))
+		for i, job := range progress.Jobs {
+			jobs[i] = fiber.Map{
+				"job_id":    job.ID,
+				"status":    job.Status,
+				"path":      job.ResultPath,
+				"cache_hit": job.CacheHit,
+				"error":     job.ErrorMessage,
+			}
+		}
+
+		return c.JSON(fiber.Map{
+			"batch_id":        progress.Batch.ID,
+			"status":          progress.Batch.Status,
+			"template_uuid":   progress.Batch.TemplateUUID,
+			"total_count":     progress.Batch.TotalCount,
+			"completed_count": progress.Batch.CompletedCount,
+			"failed_count":    progress.Batch.FailedCount,
+			"counts":          progress.Counts,
+			"progress":        progress.Progress,
+			"zip_path":        progress.Batch.ZipPath,
+			"completed_at":    progress.Batch.CompletedAt,
+			"jobs":            jobs,
+		})
+	}
+}
+
+// batchPayloadsFromRequest reads the batch body as a JSON array or NDJSON.
+func batchPayloadsFromRequest(c *fiber.Ctx) ([]json.RawMessage, error) {
+	contentType := strings.ToLower(c.Get(fiber.HeaderContentType))
+
+	switch {
+	case strings.HasPrefix(contentType, fiber.MIMEMultipartForm):
+		file, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("file is required")
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, errors.New("failed to read file")
+		}
+		defer f.Close()
+		return parseNDJSONPayloads(f)
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonlines"):
+		return parseNDJSONPayloads(bytes.NewReader(c.Body()))
+	default:
+		var payloads []json.RawMessage
+		if err := json.Unmarshal(c.Body(), &payloads); err != nil {
+			return nil, fmt.Errorf("body must be a JSON array of payload objects: %v", err)
+		}
+		for i, p := range payloads {
+			if !isJSONObject(p) {
+				return nil, fmt.Errorf("payload %d is not a JSON object", i)
+			}
+		}
+		return payloads, nil
+	}
+}
+
+func parseNDJSONPayloads(r io.Reader) ([]json.RawMessage, error) {
+	var payloads []json.RawMessage
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if !json.Valid(raw) || !isJSONObject(raw) {
+			return nil, fmt.Errorf("line %d is not a JSON object", line)
+		}
+		payloads = append(payloads, json.RawMessage(append([]byte(nil), raw...)))
+		if len(payloads) > pdfjob.MaxBatchSize {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read NDJSON: %v", err)
+	}
+	return payloads, nil
+}
+
+func isJSONObject(raw []byte) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
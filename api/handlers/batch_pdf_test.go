@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"designmypdf/pkg/pdfjob"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseBatchBody runs batchPayloadsFromRequest on a request with the given body.
+func parseBatchBody(t *testing.T, contentType string, body []byte) ([]json.RawMessage, error) {
+	t.Helper()
+	var (
+		payloads []json.RawMessage
+		parseErr error
+	)
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		payloads, parseErr = batchPayloadsFromRequest(c)
+		return nil
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, contentType)
+	if _, err := app.Test(req); err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	return payloads, parseErr
+}
+
+func multipartBody(t *testing.T, field, content string) (string, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	part, err := w.CreateFormFile(field, "payloads.ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(part, content)
+	w.Close()
+	return w.FormDataContentType(), buf.Bytes()
+}
+
+func TestBatchPayloadsFromRequest(t *testing.T) {
+	ndjson := "{\"name\":\"a\"}\n\n  {\"name\":\"b\"}  \n"
+	multipartType, multipartData := multipartBody(t, "file", ndjson)
+	noFileType, noFileData := multipartBody(t, "other", ndjson)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        []string
+		wantErr     string
+	}{
+		{"json array", fiber.MIMEApplicationJSON, []byte(`[{"name":"a"},{"name":"b"}]`), []string{`{"name":"a"}`, `{"name":"b"}`}, ""},
+		{"json array without content type", "", []byte(`[{"name":"a"}]`), []string{`{"name":"a"}`}, ""},
+		{"json array of scalars", fiber.MIMEApplicationJSON, []byte(`[{"name":"a"},3]`), nil, "payload 1 is not a JSON object"},
+		{"json object", fiber.MIMEApplicationJSON, []byte(`{"name":"a"}`), nil, "body must be a JSON array"},
+		{"ndjson", "application/x-ndjson", []byte(ndjson), []string{`{"name":"a"}`, `{"name":"b"}`}, ""},
+		{"jsonlines", "application/jsonlines", []byte(ndjson), []string{`{"name":"a"}`, `{"name":"b"}`}, ""},
+		{"ndjson invalid line", "application/x-ndjson", []byte("{\"name\":\"a\"}\n[1]\n"), nil, "line 2 is not a JSON object"},
+		{"multipart file", multipartType, multipartData, []string{`{"name":"a"}`, `{"name":"b"}`}, ""},
+		{"multipart without file", noFileType, noFileData, nil, "file is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payloads, err := parseBatchBody(t, tt.contentType, tt.body)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			var got []string
+			for _, p := range payloads {
+				got = append(got, string(p))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("payloads = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNDJSONPayloads_StopsPastSizeCap(t *testing.T) {
+	var body strings.Builder
+	for i := 0; i < pdfjob.MaxBatchSize+10; i++ {
+		body.WriteString("{\"i\":1}\n")
+	}
+	// Never read: parsing stops as soon as the cap is exceeded.
+	body.WriteString("not json\n")
+
+	payloads, err := parseNDJSONPayloads(strings.NewReader(body.String()))
+	if err != nil {
+		t.Fatalf("err = %v", err)
+	}
+	// One payload past the cap, so that the batch is rejected as too large.
+	if len(payloads) != pdfjob.MaxBatchSize+1 {
+		t.Errorf("len(payloads) = %d, want %d", len(payloads), pdfjob.MaxBatchSize+1)
+	}
+}
@@ -0,0 +1,12 @@
+package handlers
+
+import (
+	"designmypdf/pkg/pdfjob"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetBrowserPoolStats returns tab and Chrome process usage of the PDF renderer.
+func GetBrowserPoolStats(c *fiber.Ctx) error {
+	return c.JSON(pdfjob.GetBrowserPool().Stats())
+}
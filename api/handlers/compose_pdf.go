@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/key"
+	"designmypdf/pkg/pdfa"
+	"designmypdf/pkg/pdfjob"
+	"designmypdf/pkg/template"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type composePartRequest struct {
+	TemplateID string                 `json:"template_id"`
+	Data       map[string]interface{} `json:"data"`
+	Format     string                 `json:"format"`
+	Locale     string                 `json:"locale"`
+	Title      string                 `json:"title"`
+	Version    json.RawMessage        `json:"version,omitempty"`
+}
+
+type composeRequest struct {
+	Parts           []composePartRequest `json:"parts"`
+	Format          string               `json:"format"`
+	Orientation     string               `json:"orientation"`
+	Margin          string               `json:"margin"`
+	Locale          string               `json:"locale"`
+	TableOfContents bool                 `json:"table_of_contents"`
+	TocTitle        string               `json:"toc_title"`
+	Sign            *pdfjob.SignOptions  `json:"sign,omitempty"`
+	Protection      *composeProtection   `json:"protection,omitempty"`
+	Conformance     string               `json:"conformance"`
+	Attach          []string             `json:"attach"`
+	Filename        string               `json:"filename"`
+}
+
+// composeProtection is the body form of pdfjob.ProtectOptions, whose
+// passwords are never marshalled. Logged bodies have them redacted.
+type composeProtection struct {
+	UserPassword  string   `json:"user_password"`
+	OwnerPassword string   `json:"owner_password"`
+	Permissions   []string `json:"permissions"`
+}
+
+// ComposePdf renders several template/data pairs and returns them as one PDF.
+// Auth: dmp_KEY header. Delivery follows GeneratePdf: a storage URL by default,
+// the raw bytes with ?delivery=inline or Accept: application/pdf.
+// Each part renders the published template version unless it sets "version"
+// (a number or "draft"), and the document "locale" (or ?locale=) unless it sets
+// its own "locale". The composed document is signed when the body sets "sign"
+// or with the ?sign= query parameters of GeneratePdf, and protected likewise
+// with "protection" or the ?permissions= and password headers of GeneratePdf.
+// "conformance" and "attach" (or the query parameters) produce PDF/A.
+// The document is named by "filename" (or ?filename=), the first part's
+// template file name otherwise.
+func ComposePdf(c *fiber.Ctx) error {
+	startTime := time.Now()
+
+	keyService := key.NewService(key.Repository{})
+
+	keyValue := c.Get("dmp_KEY")
+	if keyValue == "" {
+		return logAndRespond(c, nil, nil, "No key provided", fiber.StatusUnauthorized)
+	}
+
+	keyEntity, err := keyService.GetKeyByValue(keyValue)
+	if err != nil {
+		return logAndRespond(c, nil, nil, "Invalid key", fiber.StatusUnauthorized)
+	}
+
+	if keyEntity.KeyCountUsed >= keyEntity.KeyCount {
+		return logAndRespond(c, keyEntity, nil, "Key usage limit reached", fiber.StatusTooManyRequests)
+	}
+
+	var req composeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return logAndRespond(c, keyEntity, nil, fmt.Sprintf("failed to parse request body: %v", err), fiber.StatusBadRequest)
+	}
+	if len(req.Parts) == 0 {
+		return logAndRespond(c, keyEntity, nil, "at least one part is required", fiber.StatusBadRequest)
+	}
+	if len(req.Parts) > pdfjob.MaxComposeParts {
+		return logAndRespond(c, keyEntity, nil, fmt.Sprintf("too many parts: %d (max %d)", len(req.Parts), pdfjob.MaxComposeParts), fiber.StatusBadRequest)
+	}
+
+	templateService := template.NewService(template.Repository{})
+	parts := make([]pdfjob.ComposePart, len(req.Parts))
+	for i, p := range req.Parts {
+		templateID := strings.TrimSpace(p.TemplateID)
+		if templateID == "" {
+			return logAndRespond(c, keyEntity, nil, fmt.Sprintf("part %d: template_id is required", i+1), fiber.StatusBadRequest)
+		}
+		version, err := template.ParseVersion(strings.Trim(string(p.Version), `"`))
+		if err != nil {
+			return logAndRespond(c, keyEntity, nil, fmt.Sprintf("part %d: %v", i+1, err), fiber.StatusBadRequest)
+		}
+		templateEntity, _, err := templateService.ResolveByUUID(templateID, version)
+		if err != nil {
+			return logAndRespond(c, keyEntity, nil, fmt.Sprintf("part %d: failed to get template: %v", i+1, err), fiber.StatusNotFound)
+		}
+		if template.HasSchema(templateEntity) {
+			payload := []byte("{}")
+			if p.Data != nil {
+				payload, _ = json.Marshal(p.Data)
+			}
+			if err := template.ValidatePayload(templateEntity, payload); err != nil {
+				var payloadErr *template.PayloadError
+				if !errors.As(err, &payloadErr) {
+					return respondInvalidPayload(c, keyEntity, templateEntity, err)
+				}
+				for j := range payloadErr.Errors {
+					payloadErr.Errors[j].Field = strings.TrimSuffix(fmt.Sprintf("parts.%d.data.%s", i, payloadErr.Errors[j].Field), ".")
+				}
+				return respondInvalidPayload(c, keyEntity, templateEntity, payloadErr)
+			}
+		}
+		if p.Format != "" || p.Locale != "" {
+			if err := (pdfjob.GenerateOptions{Format: p.Format, Locale: p.Locale}).Validate(); err != nil {
+				return logAndRespond(c, keyEntity, nil, fmt.Sprintf("part %d: %v", i+1, err), fiber.StatusBadRequest)
+			}
+		}
+		title := strings.TrimSpace(p.Title)
+		if title == "" {
+			title = templateEntity.Name
+		}
+		if title == "" {
+			title = fmt.Sprintf("Part %d", i+1)
+		}
+		parts[i] = pdfjob.ComposePart{
+			Template: templateEntity,
+			Data:     p.Data,
+			Format:   strings.ToUpper(strings.TrimSpace(p.Format)),
+			Locale:   strings.TrimSpace(p.Locale),
+			Title:    title,
+		}
+	}
+
+	format := req.Format
+	if format == "" {
+		format = c.Query("format")
+	}
+	orientation := req.Orientation
+	if orientation == "" {
+		orientation = c.Query("orientation")
+	}
+	margin := req.Margin
+	if margin == "" {
+		margin = c.Query("margin")
+	}
+	locale := req.Locale
+	if locale == "" {
+		locale = c.Query("locale")
+	}
+	sign := req.Sign
+	if sign == nil {
+		if sign, err = signOptionsFromRequest(c); err != nil {
+			return logAndRespond(c, keyEntity, parts[0].Template, err.Error(), fiber.StatusBadRequest)
+		}
+	}
+	var protect *pdfjob.ProtectOptions
+	if p := req.Protection; p != nil {
+		protect = &pdfjob.ProtectOptions{UserPassword: p.UserPassword, OwnerPassword: p.OwnerPassword, Permissions: p.Permissions}
+	} else if protect, err = protectOptionsFromRequest(c); err != nil {
+		return logAndRespond(c, keyEntity, parts[0].Template, err.Error(), fiber.StatusBadRequest)
+	}
+	conformance := req.Conformance
+	if conformance == "" {
+		conformance = c.Query("conformance")
+	}
+	if conformance, err = pdfa.ParseConformance(conformance); err != nil {
+		return logAndRespond(c, keyEntity, parts[0].Template, err.Error(), fiber.StatusBadRequest)
+	}
+	attach := req.Attach
+	if attach == nil {
+		attach = pdfjob.ParseAttachments(c.Query("attach"))
+	}
+	if err := (pdfjob.GenerateOptions{Format: format, Orientation: orientation, Margin: margin, Locale: locale,
+		Sign: sign, Protect: protect, Conformance: conformance, Attach: attach}).Validate(); err != nil {
+		return logAndRespond(c, keyEntity, parts[0].Template, err.Error(), fiber.StatusBadRequest)
+	}
+
+	filename := req.Filename
+	if filename == "" {
+		filename = c.Query("filename")
+	}
+	if filename, err = pdfjob.DocumentFilename(parts[0].Template, parts[0].Data, pdfjob.GenerateOptions{
+		Locale: locale, Filename: filename}); err != nil {
+		return logAndRespond(c, keyEntity, parts[0].Template, err.Error(), renderErrorStatus(err))
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context(), time.Duration(30+15*len(parts))*time.Second)
+	defer cancel()
+
+	pdfBuf, err := pdfjob.ComposePdfForKey(ctx, keyEntity, parts, pdfjob.ComposeOptions{
+		Format:          strings.ToUpper(strings.TrimSpace(format)),
+		Orientation:     orientation,
+		Margin:          margin,
+		Locale:          strings.TrimSpace(locale),
+		TableOfContents: req.TableOfContents,
+		TocTitle:        req.TocTitle,
+		Sign:            sign,
+		Protect:         protect,
+		Conformance:     conformance,
+		Attach:          attach,
+	})
+	if err != nil {
+		return logAndRespond(c, keyEntity, parts[0].Template, err.Error(), renderErrorStatus(err))
+	}
+
+	if wantsInlinePdf(c) {
+		go logPdfGeneration(keyEntity.ID, parts[0].Template.ID, c.Body(), map[string]interface{}{
+			"delivery": deliveryInline,
+			"parts":    len(parts),
+			"size":     len(pdfBuf),
+		}, "", entities.Success)
+		fmt.Printf("Total execution time: %v\n", time.Since(startTime))
+
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		if filename == "" {
+			filename = "document.pdf"
+		}
+		c.Set(fiber.HeaderContentDisposition, inlineDisposition(filename))
+		return c.Send(pdfBuf)
+	}
+
+	pdfURL, err := pdfjob.StorePdf(ctx, pdfBuf, filename)
+	if err != nil {
+		return logAndRespond(c, keyEntity, parts[0].Template, err.Error(), fiber.StatusInternalServerError)
+	}
+
+	go logPdfGeneration(keyEntity.ID, parts[0].Template.ID, c.Body(), map[string]interface{}{
+		"path":  pdfURL,
+		"parts": len(parts),
+	}, "", entities.Success)
+	fmt.Printf("Total execution time: %v\n", time.Since(startTime))
+
+	return c.JSON(fiber.Map{"path": pdfURL})
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"designmypdf/pkg/pdfjob"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListDeadLetterJobs returns the async jobs that failed their last attempt.
+// Auth: X-Admin-Token.
+func ListDeadLetterJobs(jobSvc *pdfjob.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		jobs, err := jobSvc.ListDeadLetters()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"message": fmt.Sprintf("failed to list dead-lettered jobs: %v", err)})
+		}
+		list := make([]fiber.Map, 0, len(jobs))
+		for _, job := range jobs {
+			list = append(list, fiber.Map{
+				"job_id":           job.ID,
+				"key_id":           job.KeyID,
+				"template_uuid":    job.TemplateUUID,
+				"output":           job.Output,
+				"batch_id":         job.BatchID,
+				"attempts":         job.Attempts,
+				"error":            job.ErrorMessage,
+				"dead_lettered_at": job.DeadLetteredAt,
+				"created_at":       job.CreatedAt,
+			})
+		}
+		return c.JSON(fiber.Map{"jobs": list, "count": len(list)})
+	}
+}
+
+type replayDeadLettersRequest struct {
+	// JobIDs selects the jobs to replay; empty replays every dead-lettered job.
+	JobIDs []string `json:"job_ids"`
+}
+
+// ReplayDeadLetterJobs queues dead-lettered jobs again with a fresh attempt
+// count. Auth: X-Admin-Token.
+func ReplayDeadLetterJobs(jobSvc *pdfjob.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req replayDeadLettersRequest
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "Invalid request body"})
+			}
+		}
+
+		replayed, err := jobSvc.ReplayDeadLetters(req.JobIDs)
+		if replayed == nil {
+			replayed = []string{}
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+				"message":  fmt.Sprintf("replay interrupted: %v", err),
+				"replayed": replayed,
+			})
+		}
+		return c.JSON(fiber.Map{"replayed": replayed, "count": len(replayed)})
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"designmypdf/pkg/storage"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path/filepath"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ServeLocalFile serves objects of the local storage backend. URLs must carry
+// the expires/signature parameters added by Put and SignedURL when
+// STORAGE_LOCAL_SECRET is set.
+func ServeLocalFile(store *storage.LocalStorage) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key, err := url.PathUnescape(c.Params("*"))
+		if err != nil || key == "" {
+			c.Status(http.StatusNotFound)
+			return c.JSON(fiber.Map{"status": false, "error": "file not found"})
+		}
+
+		if err := store.Verify(key, c.Query("expires"), c.Query("signature")); err != nil {
+			c.Status(http.StatusForbidden)
+			return c.JSON(fiber.Map{"status": false, "error": err.Error()})
+		}
+
+		rc, err := store.Get(context.Background(), key)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				c.Status(http.StatusNotFound)
+				return c.JSON(fiber.Map{"status": false, "error": "file not found"})
+			}
+			c.Status(http.StatusInternalServerError)
+			return c.JSON(fiber.Map{"status": false, "error": err.Error()})
+		}
+		if ext := filepath.Ext(key); ext != "" {
+			c.Type(ext)
+		}
+		return c.SendStream(rc)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"designmypdf/pkg/amqp"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Health is the liveness probe: always 200 while the process serves requests,
+// with the RabbitMQ state in the body ("degraded" while it is down) so that a
+// broker outage does not get the API restarted. amqpClient is nil when the
+// async routes are disabled.
+func Health(amqpClient *amqp.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		body, _ := healthBody(amqpClient)
+		return c.JSON(body)
+	}
+}
+
+// Ready is the readiness probe: same body as Health, but 503 while the
+// RabbitMQ connection of the async routes is down so that traffic is routed
+// to another instance.
+func Ready(amqpClient *amqp.Client) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		body, ready := healthBody(amqpClient)
+		if !ready {
+			c.Status(fiber.StatusServiceUnavailable)
+		}
+		return c.JSON(body)
+	}
+}
+
+// healthBody reports the RabbitMQ state and whether it is usable. The reason
+// of the last loss is left out: it may reveal internal addresses.
+func healthBody(amqpClient *amqp.Client) (fiber.Map, bool) {
+	if amqpClient == nil {
+		return fiber.Map{"status": "ok", "rabbitmq": "disabled"}, true
+	}
+	state := amqpClient.State()
+	rabbitmq := fiber.Map{
+		"connected":  state.Connected,
+		"since":      state.Since,
+		"reconnects": state.Reconnects,
+	}
+	if !state.Connected {
+		return fiber.Map{"status": "degraded", "rabbitmq": rabbitmq}, false
+	}
+	return fiber.Map{"status": "ok", "rabbitmq": rabbitmq}, true
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"designmypdf/api/handlers/presenter"
 	"designmypdf/pkg/key"
+	"designmypdf/pkg/pdfjob"
 	"errors"
 
 	"github.com/gofiber/fiber/v2"
@@ -86,6 +87,36 @@ func DeleteKey(service key.Service) fiber.Handler {
 	}
 }
 
+// KeyPriorityRequest represents the request payload for setting the priority
+// of a key.
+type KeyPriorityRequest struct {
+	Priority string `json:"priority"`
+}
+
+// SetKeyPriority sets the priority plan of a key: the default priority of its
+// async jobs and the highest one they may ask for. Auth: X-Admin-Token.
+func SetKeyPriority(service key.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keyID, err := c.ParamsInt("keyID")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(presenter.KeyErrorResponse(err))
+		}
+		var requestBody KeyPriorityRequest
+		if err := c.BodyParser(&requestBody); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(presenter.KeyErrorResponse(err))
+		}
+		priority, err := pdfjob.ParsePriority(requestBody.Priority)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(presenter.KeyErrorResponse(err))
+		}
+		key, err := service.SetPriority(uint(keyID), priority)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(presenter.KeyErrorResponse(err))
+		}
+		return c.Status(fiber.StatusOK).JSON(presenter.KeySuccessResponse(key))
+	}
+}
+
 // CheckKey is middleware that checks the validity of the provided key.
 func CheckKey(service key.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"designmypdf/api/handlers/presenter"
+	"designmypdf/pkg/namespace"
+	"designmypdf/pkg/pdfjob"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type PartialRequest struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+var errNamespaceNotFound = errors.New("namespace not found")
+
+// ownedNamespaceID returns the :namespaceID param when the namespace belongs
+// to the authenticated user.
+func ownedNamespaceID(c *fiber.Ctx, namespaceService namespace.Service) (uint, error) {
+	namespaceID, err := strconv.ParseUint(c.Params("namespaceID"), 10, 32)
+	if err != nil {
+		return 0, errNamespaceNotFound
+	}
+	userIDFloat, ok := c.Locals("userID").(float64)
+	if !ok {
+		return 0, errors.New("invalid user ID type")
+	}
+	ns, err := namespaceService.Get(uint(namespaceID))
+	if err != nil || ns.UserID != uint(userIDFloat) {
+		return 0, errNamespaceNotFound
+	}
+	return ns.ID, nil
+}
+
+func partialErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNamespaceNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, namespace.ErrInvalidPartialName), errors.Is(err, namespace.ErrInvalidPartial):
+		return http.StatusBadRequest
+	case errors.Is(err, namespace.ErrPartialExists), errors.Is(err, namespace.ErrPartialInUse):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func partialParams(c *fiber.Ctx, namespaceService namespace.Service) (uint, uint, error) {
+	namespaceID, err := ownedNamespaceID(c, namespaceService)
+	if err != nil {
+		return 0, 0, err
+	}
+	partialID, err := strconv.ParseUint(c.Params("partialID"), 10, 32)
+	if err != nil {
+		return 0, 0, gorm.ErrRecordNotFound
+	}
+	return namespaceID, uint(partialID), nil
+}
+
+// refreshDependentThumbnails re-renders the previews of the templates calling a saved partial.
+func refreshDependentThumbnails(templateIDs []uint) {
+	for _, id := range templateIDs {
+		pdfjob.RefreshThumbnail(id)
+	}
+}
+
+func GetPartials(namespaceService namespace.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespaceID, err := ownedNamespaceID(c, namespaceService)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		result, err := namespaceService.ListPartials(namespaceID)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		return c.JSON(presenter.PartialsSuccessResponse(result))
+	}
+}
+
+func GetPartial(namespaceService namespace.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespaceID, partialID, err := partialParams(c, namespaceService)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		result, err := namespaceService.GetPartial(namespaceID, partialID)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		return c.JSON(presenter.PartialSuccessResponse(result, nil))
+	}
+}
+
+func CreatePartial(namespaceService namespace.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespaceID, err := ownedNamespaceID(c, namespaceService)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		var requestBody PartialRequest
+		if err := c.BodyParser(&requestBody); err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		result, dependents, err := namespaceService.CreatePartial(namespaceID, requestBody.Name, requestBody.Content)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		refreshDependentThumbnails(dependents)
+		c.Status(http.StatusCreated)
+		return c.JSON(presenter.PartialSuccessResponse(result, dependents))
+	}
+}
+
+func UpdatePartial(namespaceService namespace.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespaceID, partialID, err := partialParams(c, namespaceService)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		var requestBody PartialRequest
+		if err := c.BodyParser(&requestBody); err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		result, dependents, err := namespaceService.UpdatePartial(namespaceID, partialID, requestBody.Name, requestBody.Content)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		refreshDependentThumbnails(dependents)
+		return c.JSON(presenter.PartialSuccessResponse(result, dependents))
+	}
+}
+
+func DeletePartial(namespaceService namespace.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		namespaceID, partialID, err := partialParams(c, namespaceService)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		result, err := namespaceService.DeletePartial(namespaceID, partialID)
+		if err != nil {
+			c.Status(partialErrorStatus(err))
+			return c.JSON(presenter.PartialErrorResponse(err))
+		}
+		return c.JSON(presenter.PartialSuccessResponse(result, nil))
+	}
+}
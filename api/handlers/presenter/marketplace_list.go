@@ -13,6 +13,7 @@ type MarketplaceListItem struct {
 	Description    string                 `json:"description"`
 	Category       string                 `json:"category"`
 	CoverImageURL  string                 `json:"cover_image_url"`
+	ThumbnailURL   string                 `json:"thumbnail_url"`
 	Price          int                    `json:"price"`
 	IsMarketplace  bool                   `json:"is_marketplace"`
 	IsPublished    bool                   `json:"is_published"`
@@ -51,6 +52,7 @@ func ToMarketplaceListItem(t *entities.Template) MarketplaceListItem {
 		Description:    t.Description,
 		Category:       t.Category,
 		CoverImageURL:  t.CoverImageURL,
+		ThumbnailURL:   t.ThumbnailURL,
 		Price:          t.Price,
 		IsMarketplace:  t.IsMarketplace,
 		IsPublished:    t.IsPublished,
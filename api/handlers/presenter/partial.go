@@ -0,0 +1,39 @@
+package presenter
+
+import (
+	"designmypdf/pkg/entities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PartialSuccessResponse returns a partial and, after a save, the templates
+// whose previews are re-rendered.
+func PartialSuccessResponse(partial *entities.Partial, dependentTemplates []uint) *fiber.Map {
+	if dependentTemplates == nil {
+		dependentTemplates = []uint{}
+	}
+	return &fiber.Map{
+		"status":              true,
+		"partial":             partial,
+		"dependent_templates": dependentTemplates,
+		"error":               nil,
+	}
+}
+
+// PartialsSuccessResponse is the list SuccessResponse of the partials of a namespace.
+func PartialsSuccessResponse(partials []entities.Partial) *fiber.Map {
+	return &fiber.Map{
+		"status":   true,
+		"partials": partials,
+		"error":    nil,
+	}
+}
+
+// PartialErrorResponse is the ErrorResponse of the partial handlers.
+func PartialErrorResponse(err error) *fiber.Map {
+	return &fiber.Map{
+		"status":  false,
+		"partial": "",
+		"error":   err.Error(),
+	}
+}
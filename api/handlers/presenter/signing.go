@@ -0,0 +1,37 @@
+package presenter
+
+import (
+	"designmypdf/pkg/entities"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SigningCertificateSuccessResponse is the SuccessResponse of a signing certificate.
+func SigningCertificateSuccessResponse(cert *entities.SigningCertificate) *fiber.Map {
+	return &fiber.Map{
+		"status":      true,
+		"certificate": cert,
+		"error":       nil,
+	}
+}
+
+// SigningCertificatesSuccessResponse is the list SuccessResponse of the signing certificates of a user.
+func SigningCertificatesSuccessResponse(certs []entities.SigningCertificate) *fiber.Map {
+	if certs == nil {
+		certs = []entities.SigningCertificate{}
+	}
+	return &fiber.Map{
+		"status":       true,
+		"certificates": certs,
+		"error":        nil,
+	}
+}
+
+// SigningCertificateErrorResponse is the ErrorResponse of the signing certificate handlers.
+func SigningCertificateErrorResponse(err error) *fiber.Map {
+	return &fiber.Map{
+		"status":      false,
+		"certificate": "",
+		"error":       err.Error(),
+	}
+}
@@ -37,6 +37,43 @@ func TemplatesPaginatedSuccessResponse(items []entities.TemplateListItem, total
 	}
 }
 
+// TemplateVersionsSuccessResponse lists the versions of a template without their content.
+func TemplateVersionsSuccessResponse(t *entities.Template, versions []entities.TemplateVersion) *fiber.Map {
+	items := make([]fiber.Map, len(versions))
+	for i, v := range versions {
+		items[i] = fiber.Map{
+			"version":    v.Version,
+			"created_at": v.CreatedAt,
+			"published":  v.Version == t.PublishedVersion,
+		}
+	}
+	return &fiber.Map{
+		"status":            true,
+		"versions":          items,
+		"latest_version":    t.LatestVersion,
+		"published_version": t.PublishedVersion,
+		"error":             nil,
+	}
+}
+
+// TemplateVersionSuccessResponse returns one full template version.
+func TemplateVersionSuccessResponse(v *entities.TemplateVersion) *fiber.Map {
+	return &fiber.Map{
+		"status":  true,
+		"version": v,
+		"error":   nil,
+	}
+}
+
+// TemplateVersionDiffSuccessResponse returns the per-field diff of two versions.
+func TemplateVersionDiffSuccessResponse(diff interface{}) *fiber.Map {
+	return &fiber.Map{
+		"status": true,
+		"diff":   diff,
+		"error":  nil,
+	}
+}
+
 // UserErrorResponse is the ErrorResponse that will be passed in the response by Handler
 func TemplateErrorResponse(err error) *fiber.Map {
 	return &fiber.Map{
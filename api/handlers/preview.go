@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"designmypdf/api/handlers/presenter"
+	"designmypdf/pkg/pdfjob"
+	"designmypdf/pkg/template"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	previewOutputPNG  = "png"
+	previewOutputHTML = "html"
+)
+
+// PreviewTemplate renders a template through the PDF pipeline for the dashboard.
+// GET renders the template variables as sample data, POST the JSON body.
+// Query: output=png (default) or html, page (png, 1-based), width (png, px),
+// version (draft by default, or a number / "published"), format, orientation, margin, locale.
+// PNG previews are cached; they are not charged to any API key.
+func PreviewTemplate(templateService template.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		templateID, err := strconv.ParseUint(c.Params("templateID"), 10, 32)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(errors.New("invalid template ID")))
+		}
+
+		version := template.VersionDraft
+		if c.Query("version") != "" {
+			if version, err = template.ParseVersion(c.Query("version")); err != nil {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(err))
+			}
+		}
+		tpl, err := templateService.Get(uint(templateID))
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		tpl, _, err = templateService.ResolveByUUID(tpl.UUID, version)
+		if err != nil {
+			c.Status(templateVersionErrorStatus(err))
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+
+		data := pdfjob.SampleData(tpl)
+		if c.Method() == fiber.MethodPost && len(c.Body()) > 0 {
+			data = map[string]interface{}{}
+			if err := json.Unmarshal(c.Body(), &data); err != nil {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(fmt.Errorf("failed to parse request body: %v", err)))
+			}
+		}
+
+		opts, err := generateOptionsFromRequest(c)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+
+		ctx, cancel := context.WithTimeout(c.Context(), 30*time.Second)
+		defer cancel()
+
+		switch strings.ToLower(c.Query("output", previewOutputPNG)) {
+		case previewOutputHTML:
+			html, err := pdfjob.RenderPreviewHTML(ctx, tpl, data, opts)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return c.JSON(presenter.TemplateErrorResponse(err))
+			}
+			c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+			return c.SendString(html)
+		case previewOutputPNG:
+		default:
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(errors.New("output must be png or html")))
+		}
+
+		pageNum := c.QueryInt("page", 1)
+		width := c.QueryInt("width", pdfjob.DefaultThumbnailWidth)
+		if pageNum < 1 {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(errors.New("page must be >= 1")))
+		}
+		if width < 1 || width > pdfjob.MaxThumbnailWidth {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(fmt.Errorf("width must be between 1 and %d", pdfjob.MaxThumbnailWidth)))
+		}
+
+		png, _, err := pdfjob.PreviewPNG(ctx, tpl, data, opts, pageNum, width)
+		if err != nil {
+			status := renderErrorStatus(err)
+			if errors.Is(err, pdfjob.ErrPreviewPageOutOfRange) {
+				status = http.StatusNotFound
+			}
+			c.Status(status)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		c.Set(fiber.HeaderContentType, "image/png")
+		c.Set(fiber.HeaderCacheControl, "private, max-age=60")
+		return c.Send(png)
+	}
+}
@@ -5,15 +5,23 @@ import (
 	"designmypdf/pkg/entities"
 	"designmypdf/pkg/key"
 	"designmypdf/pkg/logs"
+	"designmypdf/pkg/pdfa"
 	"designmypdf/pkg/pdfjob"
+	"designmypdf/pkg/secret"
+	"designmypdf/pkg/signing"
 	"designmypdf/pkg/template"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 func GeneratePdf(c *fiber.Ctx) error {
@@ -45,31 +53,353 @@ func GeneratePdf(c *fiber.Ctx) error {
 		return logAndRespond(c, keyEntity, nil, "No template provided", fiber.StatusBadRequest)
 	}
 
-	templateService := template.NewService(template.Repository{})
-	templateEntity, err := templateService.GetByUUID(templateID)
+	templateEntity, templateVersion, status, err := resolveTemplate(c, templateID)
 	if err != nil {
-		return logAndRespond(c, keyEntity, nil, fmt.Sprintf("failed to get template: %v", err), fiber.StatusInternalServerError)
+		return logAndRespond(c, keyEntity, nil, err.Error(), status)
 	}
 
 	var data map[string]interface{}
 	if err := c.BodyParser(&data); err != nil {
 		return logAndRespond(c, nil, nil, fmt.Sprintf("failed to parse request body: %v", err), fiber.StatusBadRequest)
 	}
+	if err := template.ValidatePayload(templateEntity, c.Body()); err != nil {
+		return respondInvalidPayload(c, keyEntity, templateEntity, err)
+	}
+
+	opts, err := generateOptionsFromRequest(c)
+	if err != nil {
+		return logAndRespond(c, keyEntity, templateEntity, err.Error(), fiber.StatusBadRequest)
+	}
+
+	if wantsInlinePdf(c) {
+		filename, err := pdfjob.DocumentFilename(templateEntity, data, opts)
+		if err != nil {
+			return logAndRespond(c, keyEntity, templateEntity, err.Error(), renderErrorStatus(err))
+		}
+		if filename == "" {
+			filename = templateEntity.UUID + "." + pdfjob.OutputExtension(opts.Output)
+		}
+		pdfBuf, err := pdfjob.RenderPdfForKey(ctx, keyEntity, templateEntity, data, opts)
+		if err != nil {
+			return logAndRespond(c, keyEntity, templateEntity, err.Error(), renderErrorStatus(err))
+		}
 
-	format := c.Query("format", "A4")
+		go logPdfGeneration(keyEntity.ID, templateEntity.ID, c.Body(), map[string]interface{}{
+			"delivery": deliveryInline,
+			"size":     len(pdfBuf),
+			"output":   opts.JobOutput(),
+		}, "", entities.Success)
+		fmt.Printf("Total execution time: %v\n", time.Since(startTime))
 
-	pdfURL, err := pdfjob.GeneratePdfForKey(ctx, keyEntity, templateEntity, data, format)
+		c.Set(fiber.HeaderContentType, pdfjob.OutputContentType(opts.Output))
+		c.Set(fiber.HeaderContentDisposition, inlineDisposition(filename))
+		c.Set(headerTemplateVersion, strconv.Itoa(templateVersion))
+		return c.Send(pdfBuf)
+	}
+
+	result, err := pdfjob.GeneratePdfForKey(ctx, keyEntity, templateEntity, data, opts)
 	if err != nil {
-		return logAndRespond(c, keyEntity, templateEntity, err.Error(), fiber.StatusInternalServerError)
+		return logAndRespond(c, keyEntity, templateEntity, err.Error(), renderErrorStatus(err))
 	}
 
-	go logPdfGeneration(keyEntity.ID, templateEntity.ID, c.Body(), pdfURL, "", entities.Success)
+	go logPdfGeneration(keyEntity.ID, templateEntity.ID, c.Body(), map[string]interface{}{
+		"path":             result.URL,
+		"cache_hit":        result.CacheHit,
+		"template_version": templateVersion,
+		"output":           opts.JobOutput(),
+	}, "", entities.Success)
 	fmt.Printf("Total execution time: %v\n", time.Since(startTime))
 
-	return c.JSON(fiber.Map{"path": pdfURL})
+	return c.JSON(fiber.Map{"path": result.URL, "cache_hit": result.CacheHit, "template_version": templateVersion})
+}
+
+// headerTemplateVersion tells inline callers which template version was rendered.
+const headerTemplateVersion = "X-Template-Version"
+
+// resolveTemplate loads the template a generation request renders: the version
+// pinned with ?version= (a number or "draft"), the published one otherwise.
+// On error it also returns the status code to answer with.
+func resolveTemplate(c *fiber.Ctx, templateUUID string) (*entities.Template, int, int, error) {
+	version, err := template.ParseVersion(c.Query("version"))
+	if err != nil {
+		return nil, 0, fiber.StatusBadRequest, err
+	}
+	templateService := template.NewService(template.Repository{})
+	templateEntity, resolved, err := templateService.ResolveByUUID(templateUUID, version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, fiber.StatusNotFound, errors.New("Template not found")
+	}
+	if errors.Is(err, template.ErrVersionNotFound) {
+		return nil, 0, fiber.StatusNotFound, err
+	}
+	if err != nil {
+		return nil, 0, fiber.StatusInternalServerError, fmt.Errorf("failed to get template: %w", err)
+	}
+	return templateEntity, resolved, 0, nil
+}
+
+// generateOptionsFromRequest reads the render options shared by the sync, async
+// and batch routes: ?format=, ?orientation=, ?margin=, ?locale= (template
+// defaults when omitted), the cache opt-out, the signature (?sign=), the
+// protection (?permissions= and the password headers), the PDF/A output
+// (?conformance=pdfa-2b|pdfa-3b, ?attach=payload,factur-x), the file name
+// (?filename=, the template one otherwise) and the image output
+// (?output=png|jpeg|webp, see imageOptionsFromRequest).
+func generateOptionsFromRequest(c *fiber.Ctx) (pdfjob.GenerateOptions, error) {
+	sign, err := signOptionsFromRequest(c)
+	if err != nil {
+		return pdfjob.GenerateOptions{}, err
+	}
+	protect, err := protectOptionsFromRequest(c)
+	if err != nil {
+		return pdfjob.GenerateOptions{}, err
+	}
+	conformance, err := pdfa.ParseConformance(c.Query("conformance"))
+	if err != nil {
+		return pdfjob.GenerateOptions{}, err
+	}
+	output, err := pdfjob.ParseOutput(c.Query("output"))
+	if err != nil {
+		return pdfjob.GenerateOptions{}, err
+	}
+	image, err := imageOptionsFromRequest(c)
+	if err != nil {
+		return pdfjob.GenerateOptions{}, err
+	}
+	opts := pdfjob.GenerateOptions{
+		Format:      c.Query("format"),
+		Orientation: c.Query("orientation"),
+		Margin:      c.Query("margin"),
+		Locale:      c.Query("locale"),
+		Sign:        sign,
+		Protect:     protect,
+		Conformance: conformance,
+		Attach:      pdfjob.ParseAttachments(c.Query("attach")),
+		Filename:    pdfjob.SanitizeFilename(c.Query("filename")),
+		Output:      output,
+		Image:       image,
+		NoCache:     wantsNoCache(c),
+	}
+	return opts, opts.Validate()
+}
+
+// imageOptionsFromRequest reads the settings of an image output: ?page=
+// (default 1) or ?full_page=true for the whole document in one image,
+// ?scale= (device pixel ratio) or ?dpi=, and ?quality= (jpeg and webp).
+// It returns nil when none is given.
+func imageOptionsFromRequest(c *fiber.Ctx) (*pdfjob.ImageOptions, error) {
+	var image pdfjob.ImageOptions
+	given := false
+	invalid := func(name string) error {
+		return fmt.Errorf("%w: invalid %s", pdfjob.ErrInvalidImageOptions, name)
+	}
+	if v := c.Query("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return nil, invalid("page")
+		}
+		image.Page, given = page, true
+	}
+	if v := c.Query("full_page"); v != "" {
+		fullPage, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, invalid("full_page")
+		}
+		image.FullPage, given = fullPage, true
+	}
+	scale, dpi := c.Query("scale"), c.Query("dpi")
+	if scale != "" && dpi != "" {
+		return nil, fmt.Errorf("%w: give scale or dpi, not both", pdfjob.ErrInvalidImageOptions)
+	}
+	if scale != "" {
+		v, err := strconv.ParseFloat(scale, 64)
+		if err != nil {
+			return nil, invalid("scale")
+		}
+		image.Scale, given = v, true
+	}
+	if dpi != "" {
+		v, err := strconv.ParseFloat(dpi, 64)
+		if err != nil {
+			return nil, invalid("dpi")
+		}
+		image.Scale, given = v/pdfjob.DefaultImageDPI, true
+	}
+	if v := c.Query("quality"); v != "" {
+		quality, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, invalid("quality")
+		}
+		image.Quality, given = quality, true
+	}
+	if !given {
+		return nil, nil
+	}
+	return &image, nil
+}
+
+// Password headers of a protected PDF. Passwords travel in headers rather
+// than in the query or body so they are never logged.
+const (
+	headerPdfUserPassword  = "X-Pdf-User-Password"
+	headerPdfOwnerPassword = "X-Pdf-Owner-Password"
+)
+
+// protectOptionsFromRequest reads the PDF protection: the password headers and
+// ?permissions=no_print,no_copy,no_modify (or "all" to lift the template
+// default restrictions). It returns nil when none is given.
+func protectOptionsFromRequest(c *fiber.Ctx) (*pdfjob.ProtectOptions, error) {
+	permissions, err := pdfjob.ParsePermissions(c.Query("permissions"))
+	if err != nil {
+		return nil, err
+	}
+	protect := &pdfjob.ProtectOptions{
+		UserPassword:  c.Get(headerPdfUserPassword),
+		OwnerPassword: c.Get(headerPdfOwnerPassword),
+		Permissions:   permissions,
+	}
+	if protect.UserPassword == "" && protect.OwnerPassword == "" && len(permissions) == 0 {
+		return nil, nil
+	}
+	return protect, nil
+}
+
+// signOptionsFromRequest reads ?sign=true (namespace or default certificate)
+// or ?sign=<certificate id>, with the appearance options ?sign_reason=,
+// ?sign_location=, ?sign_visible=, ?sign_page= and ?sign_position=.
+// It returns nil when no signature is requested.
+func signOptionsFromRequest(c *fiber.Ctx) (*pdfjob.SignOptions, error) {
+	value := strings.TrimSpace(c.Query("sign"))
+	if value == "" {
+		return nil, nil
+	}
+	sign := &pdfjob.SignOptions{
+		Reason:   c.Query("sign_reason"),
+		Location: c.Query("sign_location"),
+		Visible:  c.QueryBool("sign_visible", false),
+		Position: c.Query("sign_position"),
+	}
+	switch strings.ToLower(value) {
+	case "false":
+		return nil, nil
+	case "true":
+	default:
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return nil, errors.New("sign must be true, false or a signing certificate id")
+		}
+		sign.CertificateID = uint(id)
+	}
+	if page := c.Query("sign_page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil {
+			return nil, errors.New("sign_page must be a page number")
+		}
+		sign.Page = n
+	}
+	return sign, nil
+}
+
+// wantsNoCache reports whether the caller opted out of the shared result cache:
+// ?cache=false, or Cache-Control: no-cache / no-store.
+func wantsNoCache(c *fiber.Ctx) bool {
+	if !c.QueryBool("cache", true) {
+		return true
+	}
+	cacheControl := strings.ToLower(c.Get(fiber.HeaderCacheControl))
+	return strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "no-store")
 }
 
-func logPdfGeneration(keyID, templateID uint, requestBody []byte, pdfURL, errorMessage string, statusCode entities.StatusCode) {
+const (
+	deliveryInline = "inline"
+	deliveryURL    = "url"
+)
+
+// wantsInlinePdf reports whether the caller asked for the PDF bytes instead of a storage URL:
+// ?delivery=inline, or an Accept header naming application/pdf. An explicit ?delivery=inline|url
+// wins over the Accept header; any other delivery value is ignored.
+func wantsInlinePdf(c *fiber.Ctx) bool {
+	switch strings.ToLower(strings.TrimSpace(c.Query("delivery"))) {
+	case deliveryInline:
+		return true
+	case deliveryURL:
+		return false
+	}
+	return strings.Contains(strings.ToLower(c.Get(fiber.HeaderAccept)), "application/pdf")
+}
+
+// inlineDisposition is the Content-Disposition of an inline PDF named
+// filename: an ASCII fallback name plus the UTF-8 one (RFC 6266).
+func inlineDisposition(filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	if fallback == filename {
+		return fmt.Sprintf(`inline; filename="%s"`, filename)
+	}
+	return fmt.Sprintf(`inline; filename="%s"; filename*=UTF-8''%s`, fallback, strings.ReplaceAll(url.QueryEscape(filename), "+", "%20"))
+}
+
+// renderErrorStatus maps a render failure to a status code: 503 when the
+// browser pool queue timed out, 400/422 when the requested signature,
+// protection, PDF/A conversion or image cannot be produced, 500 otherwise.
+func renderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, pdfjob.ErrBrowserPoolBusy):
+		return fiber.StatusServiceUnavailable
+	case errors.Is(err, signing.ErrNoCertificate), errors.Is(err, signing.ErrInvalidOptions),
+		errors.Is(err, pdfjob.ErrInvalidProtection), errors.Is(err, pdfa.ErrInvalidOptions),
+		errors.Is(err, pdfjob.ErrInvalidImageOptions), errors.Is(err, pdfjob.ErrPreviewPageOutOfRange):
+		return fiber.StatusBadRequest
+	case errors.Is(err, signing.ErrInvalidCertificate), errors.Is(err, pdfa.ErrNotConformant),
+		errors.Is(err, pdfjob.ErrImageTooLarge):
+		return fiber.StatusUnprocessableEntity
+	}
+	return fiber.StatusInternalServerError
+}
+
+// priorityErrorStatus maps a rejected ?priority=: 403 when it is above the
+// priority of the key, 400 when it is not a priority.
+func priorityErrorStatus(err error) int {
+	if errors.Is(err, pdfjob.ErrPriorityNotAllowed) {
+		return fiber.StatusForbidden
+	}
+	return fiber.StatusBadRequest
+}
+
+// enqueueErrorStatus maps a failure to queue a job: 503 when its PDF
+// passwords cannot be sealed (ENCRYPTION_KEY unset), 500 otherwise.
+func enqueueErrorStatus(err error) int {
+	if errors.Is(err, secret.ErrNoKey) {
+		return fiber.StatusServiceUnavailable
+	}
+	return fiber.StatusInternalServerError
+}
+
+// respondInvalidPayload answers 422 with the field errors of a payload that does
+// not match the template schema (500 if the stored schema itself is broken).
+func respondInvalidPayload(c *fiber.Ctx, k *entities.Key, t *entities.Template, err error) error {
+	var payloadErr *template.PayloadError
+	if !errors.As(err, &payloadErr) {
+		return logAndRespond(c, k, t, err.Error(), fiber.StatusInternalServerError)
+	}
+	response := payloadErrorBody(payloadErr.Errors)
+	responseBody, _ := json.Marshal(response)
+	recordErrorLog(c, k, t, responseBody, payloadErr.Error(), fiber.StatusUnprocessableEntity)
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(response)
+}
+
+func payloadErrorBody(fieldErrors []template.FieldError) fiber.Map {
+	return fiber.Map{
+		"message": "payload does not match template schema",
+		"errors":  fieldErrors,
+	}
+}
+
+func logPdfGeneration(keyID, templateID uint, requestBody []byte, response map[string]interface{}, errorMessage string, statusCode entities.StatusCode) {
 	var err error
 	if errorMessage != "" {
 		err = errors.New(errorMessage)
@@ -79,18 +409,23 @@ func logPdfGeneration(keyID, templateID uint, requestBody []byte, pdfURL, errorM
 		templateID,
 		"",
 		requestBody,
-		map[string]interface{}{"path": pdfURL},
+		response,
 		statusCode,
 		err,
 	)
 }
 
 func logAndRespond(c *fiber.Ctx, k *entities.Key, t *entities.Template, errorMessage string, statusCode int) error {
+	recordErrorLog(c, k, t, datatypes.JSON([]byte(fmt.Sprintf(`{"message": "%s"}`, errorMessage))), errorMessage, statusCode)
+	return c.Status(statusCode).JSON(fiber.Map{"message": errorMessage})
+}
+
+func recordErrorLog(c *fiber.Ctx, k *entities.Key, t *entities.Template, responseBody datatypes.JSON, errorMessage string, statusCode int) {
 	logService := logs.NewService(logs.Repository{})
 	logEntry := &entities.Log{
 		CalledAt:     time.Now(),
 		RequestBody:  c.Body(),
-		ResponseBody: datatypes.JSON([]byte(fmt.Sprintf(`{"message": "%s"}`, errorMessage))),
+		ResponseBody: responseBody,
 		StatusCode:   entities.StatusCode(statusCode),
 		ErrorMessage: errorMessage,
 	}
@@ -105,6 +440,4 @@ func logAndRespond(c *fiber.Ctx, k *entities.Key, t *entities.Template, errorMes
 	if err := logService.CreateLog(logEntry); err != nil {
 		fmt.Printf("failed to log error: %v\n", err)
 	}
-
-	return c.Status(statusCode).JSON(fiber.Map{"message": errorMessage})
 }
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestWantsInlinePdf(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   bool
+	}{
+		{"default", "", "", false},
+		{"accept json", "", "application/json", false},
+		{"accept pdf", "", "application/pdf", true},
+		{"accept pdf among others", "", "application/json;q=0.5, Application/PDF", true},
+		{"accept any", "", "*/*", false},
+		{"delivery inline", "?delivery=inline", "", true},
+		{"delivery url", "?delivery=url", "", false},
+		{"delivery case and spaces", "?delivery=%20INLINE%20", "", true},
+		{"delivery url wins over accept pdf", "?delivery=url", "application/pdf", false},
+		{"delivery inline wins over accept json", "?delivery=inline", "application/json", true},
+		{"unknown delivery falls back to accept", "?delivery=email", "application/pdf", true},
+		{"unknown delivery without accept", "?delivery=email", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bool
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				got = wantsInlinePdf(c)
+				return nil
+			})
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			if _, err := app.Test(req); err != nil {
+				t.Fatalf("app.Test: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("wantsInlinePdf(%q, Accept %q) = %v, want %v", tt.query, tt.accept, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"designmypdf/api/handlers/presenter"
+	"designmypdf/pkg/key"
+	"designmypdf/pkg/namespace"
+	"designmypdf/pkg/secret"
+	"designmypdf/pkg/signing"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// maxCertificateUpload caps an uploaded .p12 or PEM file.
+const maxCertificateUpload = 1 << 20
+
+func signingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, errNamespaceNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, signing.ErrInvalidCertificate):
+		return http.StatusBadRequest
+	case errors.Is(err, secret.ErrNoKey):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// formBytes reads a multipart file field, or the text field of the same name.
+func formBytes(c *fiber.Ctx, field string) ([]byte, error) {
+	file, err := c.FormFile(field)
+	if err != nil {
+		return []byte(c.FormValue(field)), nil
+	}
+	if file.Size > maxCertificateUpload {
+		return nil, errors.New(field + " is too large (max 1MB)")
+	}
+	return readFormFile(file)
+}
+
+func readFormFile(file *multipart.FileHeader) ([]byte, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	return io.ReadAll(src)
+}
+
+// CreateSigningCertificate uploads a signing certificate as multipart form data:
+// "certificate" (.p12/.pfx) with "password", or "certificate_pem" (with its
+// chain) and "private_key_pem". "namespace_id" scopes it to one namespace,
+// otherwise it is the user default; "name" defaults to the subject common name.
+func CreateSigningCertificate(signingService signing.Service, namespaceService namespace.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := getUserIDFromContext(c.Locals("userID"))
+		if err != nil {
+			c.Status(http.StatusUnauthorized)
+			return c.JSON(presenter.SigningCertificateErrorResponse(err))
+		}
+
+		var namespaceID *uint
+		if value := strings.TrimSpace(c.FormValue("namespace_id")); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				c.Status(http.StatusNotFound)
+				return c.JSON(presenter.SigningCertificateErrorResponse(errNamespaceNotFound))
+			}
+			ns, err := namespaceService.Get(uint(id))
+			if err != nil || ns.UserID != userID {
+				c.Status(http.StatusNotFound)
+				return c.JSON(presenter.SigningCertificateErrorResponse(errNamespaceNotFound))
+			}
+			namespaceID = &ns.ID
+		}
+
+		var identity *signing.Identity
+		p12, err := formBytes(c, "certificate")
+		if err == nil && len(p12) > 0 {
+			identity, err = signing.ParsePKCS12(p12, c.FormValue("password"))
+		} else if err == nil {
+			var certPEM, keyPEM []byte
+			if certPEM, err = formBytes(c, "certificate_pem"); err == nil {
+				if keyPEM, err = formBytes(c, "private_key_pem"); err == nil {
+					identity, err = signing.ParsePEM(certPEM, keyPEM)
+				}
+			}
+		}
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.SigningCertificateErrorResponse(err))
+		}
+
+		result, err := signingService.Upload(userID, namespaceID, strings.TrimSpace(c.FormValue("name")), identity)
+		if err != nil {
+			c.Status(signingErrorStatus(err))
+			return c.JSON(presenter.SigningCertificateErrorResponse(err))
+		}
+		c.Status(http.StatusCreated)
+		return c.JSON(presenter.SigningCertificateSuccessResponse(result))
+	}
+}
+
+func GetSigningCertificates(signingService signing.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := getUserIDFromContext(c.Locals("userID"))
+		if err != nil {
+			c.Status(http.StatusUnauthorized)
+			return c.JSON(presenter.SigningCertificateErrorResponse(err))
+		}
+		result, err := signingService.List(userID)
+		if err != nil {
+			c.Status(signingErrorStatus(err))
+			return c.JSON(presenter.SigningCertificateErrorResponse(err))
+		}
+		return c.JSON(presenter.SigningCertificatesSuccessResponse(result))
+	}
+}
+
+func DeleteSigningCertificate(signingService signing.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := getUserIDFromContext(c.Locals("userID"))
+		if err != nil {
+			c.Status(http.StatusUnauthorized)
+			return c.JSON(presenter.SigningCertificateErrorResponse(err))
+		}
+		certificateID, err := strconv.ParseUint(c.Params("certificateID"), 10, 32)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return c.JSON(presenter.SigningCertificateErrorResponse(gorm.ErrRecordNotFound))
+		}
+		result, err := signingService.Delete(userID, uint(certificateID))
+		if err != nil {
+			c.Status(signingErrorStatus(err))
+			return c.JSON(presenter.SigningCertificateErrorResponse(err))
+		}
+		return c.JSON(presenter.SigningCertificateSuccessResponse(result))
+	}
+}
+
+// VerifyPdf checks the signatures of a PDF sent as the raw body or as the
+// multipart "file" field. Auth: dmp_KEY header; verification is not charged.
+// Certificates uploaded by the key owner count as trusted roots.
+func VerifyPdf(signingService signing.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		keyValue := c.Get("dmp_KEY")
+		if keyValue == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "No key provided"})
+		}
+		keyEntity, err := key.NewService(key.Repository{}).GetKeyByValue(keyValue)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid key"})
+		}
+
+		pdf := c.Body()
+		if file, err := c.FormFile("file"); err == nil {
+			if pdf, err = readFormFile(file); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "failed to read file"})
+			}
+		}
+		// The header may follow a few junk bytes (PDF 32000-1, 7.5.2).
+		if !bytes.Contains(pdf[:min(len(pdf), 1024)], []byte("%PDF-")) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"message": "body is not a PDF"})
+		}
+		return c.JSON(signingService.Verify(keyEntity.UserID, pdf))
+	}
+}
@@ -3,9 +3,11 @@ package handlers
 import (
 	"designmypdf/api/handlers/presenter"
 	"designmypdf/pkg/entities"
+	"designmypdf/pkg/pdfjob"
 	"designmypdf/pkg/template"
 	"designmypdf/utils"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,6 +15,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type TemplateRequest struct {
@@ -29,9 +32,27 @@ type UpdateTemplateRequest struct {
 	Name               *string                 `json:"name,omitempty"`
 	Content            *string                 `json:"content,omitempty"`
 	Variables          *datatypes.JSON         `json:"variables,omitempty"`
+	VariablesSchema    *datatypes.JSON         `json:"variables_schema,omitempty"`
 	Fonts              *entities.MultiString   `json:"fonts,omitempty"`
 	PdfBackgroundColor *string                 `json:"pdf_background_color,omitempty"`
 	PdfContentPadding  *string                 `json:"pdf_content_padding,omitempty"`
+	PdfFormat          *string                 `json:"pdf_format,omitempty"`
+	PdfOrientation     *string                 `json:"pdf_orientation,omitempty"`
+	PdfMargin          *string                 `json:"pdf_margin,omitempty"`
+	PdfHeader          *string                 `json:"pdf_header,omitempty"`
+	PdfFooter          *string                 `json:"pdf_footer,omitempty"`
+	PdfHeaderHeight    *string                 `json:"pdf_header_height,omitempty"`
+	PdfFooterHeight    *string                 `json:"pdf_footer_height,omitempty"`
+	PdfPermissions     *entities.MultiString   `json:"pdf_permissions,omitempty"`
+	PdfTitle           *string                 `json:"pdf_title,omitempty"`
+	PdfAuthor          *string                 `json:"pdf_author,omitempty"`
+	PdfSubject         *string                 `json:"pdf_subject,omitempty"`
+	PdfKeywords        *string                 `json:"pdf_keywords,omitempty"`
+	PdfFilename        *string                 `json:"pdf_filename,omitempty"`
+	Translations       *datatypes.JSON         `json:"translations,omitempty"`
+	DefaultLocale      *string                 `json:"default_locale,omitempty"`
+	// Publish makes the version created by this save the one generate-pdf renders.
+	Publish            *bool                   `json:"publish,omitempty"`
 }
 
 func CreateTemplate(templateService template.Service) fiber.Handler {
@@ -113,6 +134,15 @@ func UpdateTemplate(templateService template.Service) fiber.Handler {
 		if req.Variables != nil {
 			tpl.Variables = *req.Variables
 		}
+		if req.VariablesSchema != nil {
+			tpl.VariablesSchema = *req.VariablesSchema
+			if template.HasSchema(tpl) {
+				if _, err := template.CompileSchema(tpl.VariablesSchema); err != nil {
+					c.Status(http.StatusBadRequest)
+					return c.JSON(presenter.TemplateErrorResponse(fmt.Errorf("invalid variables_schema: %w", err)))
+				}
+			}
+		}
 		if req.Fonts != nil {
 			tpl.Fonts = *req.Fonts
 		}
@@ -126,17 +156,102 @@ func UpdateTemplate(templateService template.Service) fiber.Handler {
 			}
 			tpl.PdfContentPadding = *req.PdfContentPadding
 		}
+		if req.PdfFormat != nil {
+			tpl.PdfFormat = strings.ToUpper(strings.TrimSpace(*req.PdfFormat))
+		}
+		if req.PdfOrientation != nil {
+			tpl.PdfOrientation = strings.ToLower(strings.TrimSpace(*req.PdfOrientation))
+		}
+		if req.PdfMargin != nil {
+			tpl.PdfMargin = strings.TrimSpace(*req.PdfMargin)
+		}
+		if req.PdfHeader != nil {
+			tpl.PdfHeader = *req.PdfHeader
+		}
+		if req.PdfFooter != nil {
+			tpl.PdfFooter = *req.PdfFooter
+		}
+		if req.PdfHeaderHeight != nil {
+			if !utils.IsValidPdfHeaderFooterHeight(*req.PdfHeaderHeight) {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(errors.New("invalid pdf_header_height")))
+			}
+			tpl.PdfHeaderHeight = strings.TrimSpace(*req.PdfHeaderHeight)
+		}
+		if req.PdfFooterHeight != nil {
+			if !utils.IsValidPdfHeaderFooterHeight(*req.PdfFooterHeight) {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(errors.New("invalid pdf_footer_height")))
+			}
+			tpl.PdfFooterHeight = strings.TrimSpace(*req.PdfFooterHeight)
+		}
+		if req.PdfPermissions != nil {
+			if err := pdfjob.ValidatePermissions(*req.PdfPermissions); err != nil {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(err))
+			}
+			tpl.PdfPermissions = *req.PdfPermissions
+		}
+		for _, f := range []struct {
+			name string
+			src  *string
+			dst  *string
+		}{
+			{"pdf_title", req.PdfTitle, &tpl.PdfTitle},
+			{"pdf_author", req.PdfAuthor, &tpl.PdfAuthor},
+			{"pdf_subject", req.PdfSubject, &tpl.PdfSubject},
+			{"pdf_keywords", req.PdfKeywords, &tpl.PdfKeywords},
+			{"pdf_filename", req.PdfFilename, &tpl.PdfFilename},
+		} {
+			if f.src == nil {
+				continue
+			}
+			if err := pdfjob.ValidateMetadataField(f.name, *f.src); err != nil {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(err))
+			}
+			*f.dst = strings.TrimSpace(*f.src)
+		}
+		if req.Translations != nil {
+			if _, err := utils.ParseTranslations(*req.Translations); err != nil {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(fmt.Errorf("invalid translations: %w", err)))
+			}
+			tpl.Translations = *req.Translations
+		}
+		if req.DefaultLocale != nil {
+			tpl.DefaultLocale = ""
+			if strings.TrimSpace(*req.DefaultLocale) != "" {
+				if tpl.DefaultLocale, err = utils.NormalizeLocale(*req.DefaultLocale); err != nil {
+					c.Status(http.StatusBadRequest)
+					return c.JSON(presenter.TemplateErrorResponse(err))
+				}
+			}
+		}
+		if req.PdfFormat != nil || req.PdfOrientation != nil || req.PdfMargin != nil {
+			if _, err := utils.NewPageLayout(tpl.PdfFormat, tpl.PdfOrientation, tpl.PdfMargin); err != nil {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(err))
+			}
+		}
 
 		if strings.TrimSpace(tpl.Name) == "" {
 			c.Status(http.StatusBadRequest)
 			return c.JSON(presenter.TemplateErrorResponse(errors.New("template name cannot be empty")))
 		}
 
-		result, err := templateService.Update(uint(templateID), tpl.Name, tpl.Content, tpl.Variables, tpl.Fonts, tpl.PdfBackgroundColor, tpl.PdfContentPadding)
+		result, err := templateService.Update(uint(templateID), tpl.Name, tpl.Content, tpl.Variables, tpl.VariablesSchema, tpl.Fonts, template.PdfSettingsOf(tpl), template.LocalizationOf(tpl))
 		if err != nil {
 			c.Status(http.StatusInternalServerError)
 			return c.JSON(presenter.TemplateErrorResponse(err))
 		}
+		if req.Publish != nil && *req.Publish && result.LatestVersion != result.PublishedVersion {
+			if result, err = templateService.Publish(result.ID, result.LatestVersion); err != nil {
+				c.Status(http.StatusInternalServerError)
+				return c.JSON(presenter.TemplateErrorResponse(err))
+			}
+		}
+		pdfjob.RefreshThumbnail(result.ID)
 		return c.JSON(presenter.TemplateSuccessResponse(result))
 	}
 }
@@ -247,3 +362,125 @@ func GetTemplate(templateService template.Service) fiber.Handler {
 		return c.JSON(presenter.TemplateSuccessResponse(result))
 	}
 }
+
+// GetTemplateVersions lists the saved versions of a template, newest first.
+func GetTemplateVersions(templateService template.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		templateID, err := strconv.ParseUint(c.Params("templateID"), 10, 32)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(errors.New("invalid template ID")))
+		}
+		tpl, err := templateService.Get(uint(templateID))
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		versions, err := templateService.ListVersions(tpl.ID)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		return c.JSON(presenter.TemplateVersionsSuccessResponse(tpl, versions))
+	}
+}
+
+// GetTemplateVersion returns the full snapshot of one version.
+func GetTemplateVersion(templateService template.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		templateID, version, err := templateVersionParams(c)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		result, err := templateService.GetVersion(templateID, version)
+		if err != nil {
+			c.Status(templateVersionErrorStatus(err))
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		return c.JSON(presenter.TemplateVersionSuccessResponse(result))
+	}
+}
+
+// PublishTemplateVersion makes a version the default of generate-pdf.
+func PublishTemplateVersion(templateService template.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		templateID, version, err := templateVersionParams(c)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		result, err := templateService.Publish(templateID, version)
+		if err != nil {
+			c.Status(templateVersionErrorStatus(err))
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		return c.JSON(presenter.TemplateSuccessResponse(result))
+	}
+}
+
+// RollbackTemplateVersion restores a version as a new published version.
+func RollbackTemplateVersion(templateService template.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		templateID, version, err := templateVersionParams(c)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		result, err := templateService.Rollback(templateID, version)
+		if err != nil {
+			c.Status(templateVersionErrorStatus(err))
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		pdfjob.RefreshThumbnail(result.ID)
+		return c.JSON(presenter.TemplateSuccessResponse(result))
+	}
+}
+
+// DiffTemplateVersions compares ?from= (published by default) with ?to= (draft by default).
+func DiffTemplateVersions(templateService template.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		templateID, err := strconv.ParseUint(c.Params("templateID"), 10, 32)
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(errors.New("invalid template ID")))
+		}
+		from, err := template.ParseVersion(c.Query("from"))
+		if err != nil {
+			c.Status(http.StatusBadRequest)
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		to := template.VersionDraft
+		if c.Query("to") != "" {
+			if to, err = template.ParseVersion(c.Query("to")); err != nil {
+				c.Status(http.StatusBadRequest)
+				return c.JSON(presenter.TemplateErrorResponse(err))
+			}
+		}
+		diff, err := templateService.DiffVersions(uint(templateID), from, to)
+		if err != nil {
+			c.Status(templateVersionErrorStatus(err))
+			return c.JSON(presenter.TemplateErrorResponse(err))
+		}
+		return c.JSON(presenter.TemplateVersionDiffSuccessResponse(diff))
+	}
+}
+
+func templateVersionParams(c *fiber.Ctx) (uint, int, error) {
+	templateID, err := strconv.ParseUint(c.Params("templateID"), 10, 32)
+	if err != nil {
+		return 0, 0, errors.New("invalid template ID")
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil || version < 1 {
+		return 0, 0, errors.New("invalid version")
+	}
+	return uint(templateID), version, nil
+}
+
+func templateVersionErrorStatus(err error) int {
+	if errors.Is(err, template.ErrVersionNotFound) || errors.Is(err, gorm.ErrRecordNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
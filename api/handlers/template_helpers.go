@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"designmypdf/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListTemplateHelpers documents the Handlebars helpers available in every template.
+func ListTemplateHelpers(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"status":  true,
+		"helpers": utils.TemplateHelpers(),
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"designmypdf/pkg/key"
+	"designmypdf/pkg/template"
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetTemplateSchema returns the JSON Schema generation payloads of a template
+// must satisfy (null when the template declares none), for the version
+// selected by ?version= like the generation routes.
+// Auth: dmp_KEY header (same as the generation routes).
+func GetTemplateSchema(c *fiber.Ctx) error {
+	keyService := key.NewService(key.Repository{})
+
+	keyValue := c.Get("dmp_KEY")
+	if keyValue == "" {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "No key provided"})
+	}
+
+	if _, err := keyService.GetKeyByValue(keyValue); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"message": "Invalid key"})
+	}
+
+	templateEntity, templateVersion, status, err := resolveTemplate(c, c.Params("templateId"))
+	if err != nil {
+		return c.Status(status).JSON(fiber.Map{"message": err.Error()})
+	}
+
+	var schema json.RawMessage
+	if template.HasSchema(templateEntity) {
+		schema = json.RawMessage(templateEntity.VariablesSchema)
+	}
+
+	return c.JSON(fiber.Map{
+		"template_uuid":    templateEntity.UUID,
+		"template_version": templateVersion,
+		"schema":           schema,
+	})
+}
@@ -5,7 +5,6 @@ import (
 	"designmypdf/pkg/storage"
 	"fmt"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -13,9 +12,9 @@ import (
 	"github.com/google/uuid"
 )
 
-func UploadCoverImage(b2 *storage.BackblazeStorage) fiber.Handler {
+func UploadCoverImage(store storage.ObjectStore) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		if b2 == nil {
+		if store == nil {
 			c.Status(http.StatusServiceUnavailable)
 			return c.JSON(fiber.Map{"status": false, "error": "storage not configured"})
 		}
@@ -45,16 +44,16 @@ func UploadCoverImage(b2 *storage.BackblazeStorage) fiber.Handler {
 		}
 
 		id := uuid.New().String()
-		tmpPath := fmt.Sprintf("/tmp/cover-%s%s", id, ext)
 		objectName := fmt.Sprintf("covers/%s%s", id, ext)
 
-		if err := c.SaveFile(file, tmpPath); err != nil {
+		src, err := file.Open()
+		if err != nil {
 			c.Status(http.StatusInternalServerError)
-			return c.JSON(fiber.Map{"status": false, "error": "failed to save file"})
+			return c.JSON(fiber.Map{"status": false, "error": "failed to read file"})
 		}
-		defer os.Remove(tmpPath)
+		defer src.Close()
 
-		url, err := b2.UploadFile(context.Background(), tmpPath, objectName)
+		url, err := store.Put(context.Background(), objectName, src, file.Size, ct)
 		if err != nil {
 			c.Status(http.StatusInternalServerError)
 			return c.JSON(fiber.Map{"status": false, "error": "upload failed: " + err.Error()})
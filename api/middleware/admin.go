@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminToken protects operator routes with the shared secret ADMIN_API_TOKEN,
+// sent in the X-Admin-Token header. The routes are disabled while it is unset.
+func AdminToken() func(*fiber.Ctx) error {
+	return func(c *fiber.Ctx) error {
+		token := os.Getenv("ADMIN_API_TOKEN")
+		if token == "" {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"status": "error", "message": "Admin API disabled: ADMIN_API_TOKEN not set", "data": nil})
+		}
+		if subtle.ConstantTimeCompare([]byte(c.Get("X-Admin-Token")), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"status": "error", "message": "Invalid admin token", "data": nil})
+		}
+		return c.Next()
+	}
+}
@@ -15,4 +15,7 @@ func KeyRouter(api fiber.Router, keyService key.Service) {
 	keyRouter.Delete("/:keyID", handlers.DeleteKey(keyService))
 	keyRouter.Put("/:keyID", handlers.UpdateKey(keyService))
 	keyRouter.Get("/", handlers.GetAllUserKeys(keyService))
+
+	// Priority plans (operators only)
+	api.Put("/admin/keys/:keyID/priority", middleware.AdminToken(), handlers.SetKeyPriority(keyService))
 }
@@ -15,4 +15,10 @@ func NampesaceRouter(api fiber.Router, namepsaceService namespace.Service) {
 	namespaceRouter.Delete("/:namespaceID", handlers.DeleteNamespace(namepsaceService))
 	namespaceRouter.Put("/:namespaceID", handlers.UpdateNamespace(namepsaceService))
 	namespaceRouter.Get("/", handlers.GetNamespaces(namepsaceService))
+	// partials shared by the templates of a namespace ({{> name}})
+	namespaceRouter.Get("/:namespaceID/partials", handlers.GetPartials(namepsaceService))
+	namespaceRouter.Post("/:namespaceID/partials", handlers.CreatePartial(namepsaceService))
+	namespaceRouter.Get("/:namespaceID/partials/:partialID", handlers.GetPartial(namepsaceService))
+	namespaceRouter.Put("/:namespaceID/partials/:partialID", handlers.UpdatePartial(namepsaceService))
+	namespaceRouter.Delete("/:namespaceID/partials/:partialID", handlers.DeletePartial(namepsaceService))
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"context"
 	"designmypdf/api/handlers"
+	"designmypdf/api/middleware"
 	"designmypdf/pkg/amqp"
 	"designmypdf/pkg/auth"
 	"designmypdf/pkg/fbadmin"
@@ -11,6 +12,7 @@ import (
 	"designmypdf/pkg/marketplace"
 	"designmypdf/pkg/namespace"
 	"designmypdf/pkg/pdfjob"
+	"designmypdf/pkg/signing"
 	"designmypdf/pkg/storage"
 	"designmypdf/pkg/template"
 	"designmypdf/pkg/user"
@@ -48,44 +50,71 @@ func SetupRoutes(app *fiber.App) {
 	logService := logs.NewService(logs.Repository{})
 	LogRouter(api, logService)
 
+	// PDF signing certificates and signature verification
+	signingService := signing.NewService(signing.Repository{})
+	SigningRouter(api, signingService, namespaceService)
+
 	// Marketplace
 	marketplaceService := marketplace.NewService()
 	MarketplaceRouter(api, marketplaceService)
 
-	// Backblaze upload (same env names as frontend: BACKBLAZE_KEY_ID, BACKBLAZE_APP_KEY, BACKBLAZE_BUCKET_NAME)
-	var b2 *storage.BackblazeStorage
-	keyID, appKey, bucketName, b2OK := storage.B2ConfigFromEnv()
-	if b2OK {
-		var err error
-		b2, err = storage.NewBackblazeStorage(keyID, appKey, bucketName)
-		if err != nil {
-			log.Printf("Warning: Backblaze storage init failed: %v", err)
-		}
+	// Object storage (STORAGE_BACKEND: backblaze by default, minio/s3 or local)
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Printf("Warning: storage backend %q not initialized: %v — image upload disabled", storage.BackendFromEnv(), err)
 	} else {
-		log.Println("Warning: Backblaze env not set or still placeholders (BACKBLAZE_KEY_ID / BACKBLAZE_APP_KEY / BACKBLAZE_BUCKET_NAME or legacy B2_*), image upload disabled")
+		pdfjob.SetStorage(store)
+		if local, ok := store.(*storage.LocalStorage); ok {
+			api.Get("/files/*", handlers.ServeLocalFile(local))
+		}
 	}
-	UploadRouter(api, b2)
+	UploadRouter(api, store)
+
+	// Multi-template composition (registered before :templateId so "compose" is not read as an ID)
+	api.Post("/generate-pdf/compose", handlers.ComposePdf)
 
 	// Synchronous PDF generation (unchanged)
 	api.Post("/generate-pdf/:templateId", handlers.GeneratePdf)
 
+	// JSON Schema the generation payloads of a template are validated against
+	api.Get("/generate-pdf/:templateId/schema", handlers.GetTemplateSchema)
+
+	// Handlebars helpers available in templates
+	api.Get("/template-helpers", handlers.ListTemplateHelpers)
+
+	// Chrome tab/process usage of the renderer
+	api.Get("/browser-pool/stats", middleware.Protected(), handlers.GetBrowserPoolStats)
+
 	// Async PDF generation via RabbitMQ
 	var jobSvc *pdfjob.Service
+	var amqpClient *amqp.Client
 	rabbitmqURL := os.Getenv("RABBITMQ_URL")
 	if rabbitmqURL != "" {
-		amqpClient, err := amqp.NewClient(rabbitmqURL)
+		amqpClient, err = amqp.NewClient(rabbitmqURL)
 		if err != nil {
 			log.Printf("Warning: RabbitMQ connect failed: %v — async routes disabled", err)
 		} else {
 			jobSvc = pdfjob.NewService(amqpClient)
+			go jobSvc.RunOutbox(context.Background())
 		}
 	} else {
 		log.Println("Warning: RABBITMQ_URL not set — async PDF routes disabled")
 	}
 
+	// Liveness (always 200, RabbitMQ state in the body) and readiness (503 while RabbitMQ is unreachable)
+	api.Get("/health", handlers.Health(amqpClient))
+	api.Get("/ready", handlers.Ready(amqpClient))
+
 	if jobSvc != nil {
 		api.Post("/generate-pdf/:templateId/async", handlers.GeneratePdfAsync(jobSvc))
 		api.Get("/pdf-jobs/:jobId", handlers.GetJobStatus(jobSvc))
+		api.Post("/generate-pdf/:templateId/batch", handlers.GeneratePdfBatch(jobSvc))
+		api.Get("/pdf-batches/:batchId", handlers.GetBatchStatus(jobSvc))
+
+		// Dead-lettered async jobs (operators only)
+		admin := api.Group("/admin", middleware.AdminToken())
+		admin.Get("/pdf-jobs/dead-letters", handlers.ListDeadLetterJobs(jobSvc))
+		admin.Post("/pdf-jobs/dead-letters/replay", handlers.ReplayDeadLetterJobs(jobSvc))
 	}
 
 	// AI credits
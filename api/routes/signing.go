@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"designmypdf/api/handlers"
+	"designmypdf/api/middleware"
+	"designmypdf/pkg/namespace"
+	"designmypdf/pkg/signing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func SigningRouter(api fiber.Router, signingService signing.Service, namespaceService namespace.Service) {
+	// certificates used by ?sign= on the generation routes
+	certificates := api.Group("/signing-certificates", middleware.Protected())
+	certificates.Post("/", handlers.CreateSigningCertificate(signingService, namespaceService))
+	certificates.Get("/", handlers.GetSigningCertificates(signingService))
+	certificates.Delete("/:certificateID", handlers.DeleteSigningCertificate(signingService))
+
+	// signature verification (dmp_KEY, not charged)
+	api.Post("/verify-pdf", handlers.VerifyPdf(signingService))
+}
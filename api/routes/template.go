@@ -16,5 +16,14 @@ func TemplateRouter(api fiber.Router, templateService template.Service) {
 	templateRouter.Put("/:templateID", handlers.UpdateTemplate(templateService))
 	templateRouter.Get("/:templateID", handlers.GetTemplate(templateService))
 	templateRouter.Put("/:templateID/namespace/:namespaceID", handlers.ChangeTemplateNamespace(templateService))
+	// versions ("diff" registered before :version)
+	templateRouter.Get("/:templateID/versions", handlers.GetTemplateVersions(templateService))
+	templateRouter.Get("/:templateID/versions/diff", handlers.DiffTemplateVersions(templateService))
+	templateRouter.Get("/:templateID/versions/:version", handlers.GetTemplateVersion(templateService))
+	templateRouter.Post("/:templateID/versions/:version/publish", handlers.PublishTemplateVersion(templateService))
+	templateRouter.Post("/:templateID/versions/:version/rollback", handlers.RollbackTemplateVersion(templateService))
+	// server-side preview (PNG of a page or composed HTML)
+	templateRouter.Get("/:templateID/preview", handlers.PreviewTemplate(templateService))
+	templateRouter.Post("/:templateID/preview", handlers.PreviewTemplate(templateService))
 	templateRouter.Get("/", handlers.GetTemplates(templateService))
 }
@@ -8,7 +8,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func UploadRouter(api fiber.Router, b2 *storage.BackblazeStorage) {
+func UploadRouter(api fiber.Router, store storage.ObjectStore) {
 	upload := api.Group("/upload", middleware.Protected())
-	upload.Post("/cover-image", handlers.UploadCoverImage(b2))
+	upload.Post("/cover-image", handlers.UploadCoverImage(store))
 }
@@ -1,21 +1,35 @@
 package main
 
 import (
+	"context"
 	"designmypdf/config/database"
 	_ "designmypdf/config/env"
 	"designmypdf/pkg/amqp"
+	"designmypdf/pkg/assets"
 	"designmypdf/pkg/pdfjob"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
 )
 
+const defaultShutdownTimeout = 60 * time.Second
+
 func main() {
 	database.Initialize()
 
+	if err := assets.Default().Check(); err != nil {
+		log.Fatalf("renderer assets: %v", err)
+	}
+
 	rabbitmqURL := os.Getenv("RABBITMQ_URL")
 	if rabbitmqURL == "" {
 		log.Fatal("RABBITMQ_URL not set")
@@ -33,7 +47,37 @@ func main() {
 	_ = pdfjob.GetBrowserPool()
 	defer pdfjob.GetBrowserPool().Close()
 
-	deliveries, err := amqpClient.Consume()
+	// By default run as many jobs as the pool has tabs.
+	concurrency := envInt("WORKER_CONCURRENCY", pdfjob.GetBrowserPool().Stats().MaxTabs)
+	prefetch := envInt("WORKER_PREFETCH", concurrency)
+	shutdownTimeout := envDuration("WORKER_SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+	// Lanes served and their weights, e.g. "high" for a worker dedicated to
+	// interactive jobs.
+	lanes, err := amqp.ParseLaneWeights(os.Getenv("WORKER_LANES"))
+	if err != nil {
+		log.Fatalf("invalid WORKER_LANES: %v", err)
+	}
+
+	// Publishes the jobs queued through the outbox, including those
+	// requeued below.
+	outboxCtx, stopOutbox := context.WithCancel(context.Background())
+	defer stopOutbox()
+	go jobSvc.RunOutbox(outboxCtx)
+
+	// Jobs this worker was killed in the middle of, then those of other
+	// workers that died.
+	if n, err := jobSvc.ResumeOwnJobs(); err != nil {
+		fmt.Printf("warning: failed to resume own jobs: %v\n", err)
+	} else if n > 0 {
+		log.Printf("Resumed %d jobs interrupted by the last stop", n)
+	}
+	if n, err := jobSvc.RequeueInterruptedJobs(); err != nil {
+		fmt.Printf("warning: failed to requeue interrupted jobs: %v\n", err)
+	} else if n > 0 {
+		log.Printf("Requeued %d interrupted jobs", n)
+	}
+
+	deliveries, err := amqpClient.Consume(prefetch, lanes)
 	if err != nil {
 		log.Fatalf("failed to start consumer: %v", err)
 	}
@@ -41,35 +85,86 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	log.Println("Worker started. Waiting for jobs...")
-
-	for {
-		select {
-		case d, ok := <-deliveries:
-			if !ok {
-				log.Println("Delivery channel closed, shutting down")
-				return
-			}
-
-			var msg struct {
-				JobID string `json:"job_id"`
-			}
-			if err := json.Unmarshal(d.Body, &msg); err != nil {
-				fmt.Printf("worker: invalid message body: %v\n", err)
-				d.Nack(false, false) // discard malformed message
-				continue
-			}
-
-			if err := jobSvc.ProcessJob(msg.JobID); err != nil {
-				fmt.Printf("worker: job %s failed: %v\n", msg.JobID, err)
-				// Job is already marked failed in DB by ProcessJob; ack to
-				// remove from queue (no requeue — failure is recorded in DB).
-			}
-			d.Ack(false)
-
-		case <-quit:
-			log.Println("Shutdown signal received")
+	pool := startPool(deliveries, concurrency, func(d amqp091.Delivery) {
+		handleDelivery(jobSvc, d)
+	})
+
+	log.Printf("Worker started (concurrency %d, prefetch %d, lanes %s). Waiting for jobs...", concurrency, prefetch, formatLanes(lanes))
+
+	select {
+	case <-pool.done:
+		log.Println("Delivery channel closed, shutting down")
+		return
+	case <-quit:
+	}
+
+	log.Printf("Shutdown signal received, draining %d in-flight jobs (up to %s)", pool.inFlight.Load(), shutdownTimeout)
+	pool.drain()
+	if err := amqpClient.StopConsuming(); err != nil {
+		fmt.Printf("warning: failed to cancel consumer: %v\n", err)
+	}
+
+	select {
+	case <-pool.done:
+		log.Println("All jobs finished")
+	case <-time.After(shutdownTimeout):
+		// Unacked deliveries go back to the queue when the connection
+		// closes; their jobs are requeued on the next start.
+		log.Printf("Shutdown timeout: abandoning %d in-flight jobs", pool.inFlight.Load())
+	case <-quit:
+		log.Printf("Second signal: abandoning %d in-flight jobs", pool.inFlight.Load())
+	}
+}
+
+// handleDelivery processes one message and settles it.
+func handleDelivery(jobSvc *pdfjob.Service, d amqp091.Delivery) {
+	var msg struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.Unmarshal(d.Body, &msg); err != nil {
+		fmt.Printf("worker: invalid message body: %v\n", err)
+		d.Nack(false, false) // discard malformed message
+		return
+	}
+
+	if err := jobSvc.ProcessJob(msg.JobID); err != nil {
+		fmt.Printf("worker: job %s failed: %v\n", msg.JobID, err)
+		if errors.Is(err, pdfjob.ErrRedeliver) {
+			// Neither failed nor scheduled for retry: hand it back.
+			d.Nack(false, true)
 			return
 		}
+		// The failure is recorded in DB and, when retryable, the
+		// retry scheduled through the outbox: ack this delivery.
+	}
+	d.Ack(false)
+}
+
+// formatLanes returns lanes as "high:6,normal:3,low:1", most urgent first.
+func formatLanes(weights map[string]int) string {
+	var parts []string
+	for _, lane := range amqp.Lanes {
+		if w, ok := weights[lane]; ok {
+			parts = append(parts, fmt.Sprintf("%s:%d", lane, w))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func envInt(name string, def int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 1 {
+			return n
+		}
+	}
+	return def
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
 	}
+	return def
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// jobPool handles deliveries on a fixed number of goroutines.
+type jobPool struct {
+	draining atomic.Bool
+	inFlight atomic.Int64
+	// done is closed once the deliveries channel is closed and every job
+	// handled.
+	done chan struct{}
+}
+
+// startPool handles deliveries on concurrency goroutines until the channel
+// is closed. Once drain is called, the deliveries not started yet are handed
+// back to the broker.
+func startPool(deliveries <-chan amqp091.Delivery, concurrency int, handle func(amqp091.Delivery)) *jobPool {
+	p := &jobPool{done: make(chan struct{})}
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for d := range deliveries {
+				if p.draining.Load() {
+					// Prefetched but not started: leave it to another worker.
+					d.Nack(false, true)
+					continue
+				}
+				p.inFlight.Add(1)
+				handle(d)
+				p.inFlight.Add(-1)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(p.done)
+	}()
+	return p
+}
+
+// drain stops starting new jobs; the running ones finish.
+func (p *jobPool) drain() {
+	p.draining.Store(true)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// settlements records how deliveries were settled, by delivery tag.
+type settlements struct {
+	mu      sync.Mutex
+	acked   []uint64
+	requeue []uint64
+}
+
+func (s *settlements) Ack(tag uint64, multiple bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.acked = append(s.acked, tag)
+	return nil
+}
+
+func (s *settlements) Nack(tag uint64, multiple, requeue bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if requeue {
+		s.requeue = append(s.requeue, tag)
+	}
+	return nil
+}
+
+func (s *settlements) Reject(tag uint64, requeue bool) error {
+	return s.Nack(tag, false, requeue)
+}
+
+func TestPoolDrain(t *testing.T) {
+	acks := &settlements{}
+	deliveries := make(chan amqp091.Delivery)
+	started := make(chan uint64, 4)
+	release := make(chan struct{})
+	pool := startPool(deliveries, 2, func(d amqp091.Delivery) {
+		started <- d.DeliveryTag
+		<-release
+		d.Ack(false)
+	})
+
+	deliveries <- amqp091.Delivery{Acknowledger: acks, DeliveryTag: 1}
+	deliveries <- amqp091.Delivery{Acknowledger: acks, DeliveryTag: 2}
+	for range 2 {
+		<-started
+	}
+	if n := pool.inFlight.Load(); n != 2 {
+		t.Fatalf("inFlight = %d, want 2", n)
+	}
+
+	pool.drain()
+	close(release)
+	// Received while draining: handed back, not started.
+	deliveries <- amqp091.Delivery{Acknowledger: acks, DeliveryTag: 3}
+	close(deliveries)
+
+	select {
+	case <-pool.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool not done after its deliveries channel closed")
+	}
+	select {
+	case tag := <-started:
+		t.Errorf("delivery %d started while draining", tag)
+	default:
+	}
+	if len(acks.acked) != 2 || len(acks.requeue) != 1 || acks.requeue[0] != 3 {
+		t.Errorf("acked %v, requeued %v; want 1 and 2 acked, 3 requeued", acks.acked, acks.requeue)
+	}
+}
+
+func TestPoolConcurrency(t *testing.T) {
+	deliveries := make(chan amqp091.Delivery)
+	running := make(chan struct{}, 8)
+	release := make(chan struct{})
+	pool := startPool(deliveries, 3, func(d amqp091.Delivery) {
+		running <- struct{}{}
+		<-release
+	})
+
+	go func() {
+		for range 5 {
+			deliveries <- amqp091.Delivery{Acknowledger: &settlements{}}
+		}
+		close(deliveries)
+	}()
+	for range 3 {
+		<-running
+	}
+	select {
+	case <-running:
+		t.Fatal("more than 3 jobs running at once")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-pool.done
+}
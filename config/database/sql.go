@@ -41,10 +41,16 @@ func InitializeSQL(dbType, host, port, user, password, dbName string) (*gorm.DB,
 		&entities.User{},
 		&entities.Namespace{},
 		&entities.Template{},
+		&entities.TemplateVersion{},
+		&entities.Partial{},
+		&entities.SigningCertificate{},
 		&entities.Key{},
 		&entities.Log{},
 		&entities.Session{},
 		&entities.PdfGenerationJob{},
+		&entities.PdfGenerationBatch{},
+		&entities.PdfJobOutbox{},
+		&entities.PdfCacheEntry{},
 		&entities.WebhookSubscription{},
 		&entities.WebhookSubscriptionKey{},
 		&entities.WebhookEvent{},
@@ -5,6 +5,7 @@ import (
 
 	"designmypdf/api/routes"
 	"designmypdf/config/database"
+	"designmypdf/pkg/assets"
 	"fmt"
 	"log"
 	"os"
@@ -19,7 +20,10 @@ import (
 )
 
 func SetupFiberServer() {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		// Batch generation accepts thousands of payloads in one body.
+		BodyLimit: 50 * 1024 * 1024,
+	})
 	// ** setup CORS
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "https://designmypdf.vercel.app,http://localhost:3000,http://localhost:3001,https://designmypdf.yvesdavinci.tech",
@@ -77,6 +81,11 @@ func main() {
 		fmt.Println("No database connection established")
 	}
 
+	// Refuse offline rendering without the vendored CDN assets
+	if err := assets.Default().Check(); err != nil {
+		log.Fatalf("Renderer assets: %v", err)
+	}
+
 	// Initialize Fiber server
 	SetupFiberServer()
 }
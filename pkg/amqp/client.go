@@ -1,91 +1,361 @@
 package amqp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	amqp091 "github.com/rabbitmq/amqp091-go"
 )
 
-const queueName = "pdf_jobs"
+const (
+	queueName = "pdf_jobs"
+	// retryQueueSuffix names the delay queues of failed jobs, one per lane
+	// and delay in milliseconds (pdf_jobs.high.retry.10000): messages expire
+	// there and are dead-lettered back to their lane. A queue-wide TTL avoids
+	// the head-of-line blocking of per-message TTLs.
+	retryQueueSuffix = ".retry."
+	// DeadLetterQueue holds the jobs that failed their last attempt.
+	DeadLetterQueue = queueName + ".dead"
+	// headerError carries the last error of a dead-lettered job.
+	headerError = "x-error"
+	consumerTag = "pdf-worker"
+	// confirmTimeout bounds the wait for the broker to confirm a message.
+	confirmTimeout = 10 * time.Second
+)
+
+// ErrNacked is returned when the broker refused to take a message.
+var ErrNacked = errors.New("amqp: message nacked by the broker")
 
+// Client publishes and consumes PDF jobs. It reconnects on its own when the
+// connection or channel to RabbitMQ is lost, declares the topology again and
+// resumes the consumer started by Consume; meanwhile publishes fail with
+// ErrNotConnected.
 type Client struct {
-	conn *amqp091.Connection
-	ch   *amqp091.Channel
+	url  string
+	done chan struct{}
+
+	mu          sync.RWMutex
+	conn        *amqp091.Connection
+	ch          *amqp091.Channel
+	state       State
+	retryQueues map[string]bool
+
+	// Set by Consume: the consumers to resume after a reconnection and the
+	// channels their deliveries are forwarded to, by lane.
+	consuming  bool
+	prefetch   int
+	deliveries map[string]chan amqp091.Delivery
+	forwarders *sync.WaitGroup
 }
 
-// NewClient connects to RabbitMQ and declares the durable pdf_jobs queue.
+// NewClient connects to RabbitMQ and declares the durable queues of the
+// lanes and the dead-letter queue. Only this first connection must succeed; later
+// losses are recovered in the background.
 func NewClient(amqpURL string) (*Client, error) {
-	conn, err := amqp091.Dial(amqpURL)
-	if err != nil {
-		return nil, fmt.Errorf("amqp dial: %w", err)
+	c := &Client{url: amqpURL, done: make(chan struct{}), retryQueues: map[string]bool{}}
+	if err := c.connect(); err != nil {
+		return nil, err
 	}
+	go c.watch()
+	return c, nil
+}
 
-	ch, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("amqp channel: %w", err)
-	}
+type jobMessage struct {
+	JobID string `json:"job_id"`
+}
 
-	_, err = ch.QueueDeclare(
-		queueName,
-		true,  // durable
-		false, // auto-delete
-		false, // exclusive
-		false, // no-wait
-		nil,
-	)
+// Publish enqueues a job ID in a lane for the workers to process. Like every
+// publish of the client, it returns once the broker confirmed the message.
+func (c *Client) Publish(jobID, lane string) error {
+	return c.publish(laneQueue(lane), jobID, nil)
+}
+
+// PublishRetry enqueues a job ID again in its lane after delay, through the
+// delay queue of that lane and duration.
+func (c *Client) PublishRetry(jobID, lane string, delay time.Duration) error {
+	name, err := c.retryQueue(lane, delay)
 	if err != nil {
-		ch.Close()
-		conn.Close()
-		return nil, fmt.Errorf("amqp queue declare: %w", err)
+		return err
 	}
-
-	return &Client{conn: conn, ch: ch}, nil
+	return c.publish(name, jobID, nil)
 }
 
-type jobMessage struct {
-	JobID string `json:"job_id"`
+// PublishDeadLetter moves a job ID to the dead-letter queue with its last
+// error, where it stays until replayed to lane.
+func (c *Client) PublishDeadLetter(jobID, lane, reason string) error {
+	return c.publish(DeadLetterQueue, jobID, amqp091.Table{headerError: reason, headerLane: lane})
 }
 
-// Publish enqueues a job ID for the worker to process.
-func (c *Client) Publish(jobID string) error {
+func (c *Client) publish(queue, jobID string, headers amqp091.Table) error {
 	body, err := json.Marshal(jobMessage{JobID: jobID})
 	if err != nil {
 		return err
 	}
-	return c.ch.Publish(
-		"",        // default exchange
-		queueName, // routing key = queue name
-		false,     // mandatory
-		false,     // immediate
+	ch, err := c.channel()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), confirmTimeout)
+	defer cancel()
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx,
+		"",    // default exchange
+		queue, // routing key = queue name
+		false, // mandatory
+		false, // immediate
 		amqp091.Publishing{
 			ContentType:  "application/json",
 			DeliveryMode: amqp091.Persistent,
+			Headers:      headers,
 			Body:         body,
 		},
 	)
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("amqp confirm: %w", err)
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
 }
 
-// Consume returns a channel of deliveries from the pdf_jobs queue.
-func (c *Client) Consume() (<-chan amqp091.Delivery, error) {
-	return c.ch.Consume(
-		queueName,
-		"",    // consumer tag — server-generated
-		false, // auto-ack: we ack manually after processing
+// retryQueue declares, once per connection, the delay queue of lane and
+// delay.
+func (c *Client) retryQueue(lane string, delay time.Duration) (string, error) {
+	ms := delay.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	target := laneQueue(lane)
+	name := fmt.Sprintf("%s%s%d", target, retryQueueSuffix, ms)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.retryQueues[name] {
+		return name, nil
+	}
+	if c.ch == nil {
+		return "", ErrNotConnected
+	}
+	_, err := c.ch.QueueDeclare(
+		name,
+		true,  // durable
+		false, // auto-delete
 		false, // exclusive
-		false, // no-local
 		false, // no-wait
-		nil,
+		amqp091.Table{
+			"x-message-ttl":             ms,
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": target,
+		},
 	)
+	if err != nil {
+		return "", fmt.Errorf("amqp queue declare %s: %w", name, err)
+	}
+	c.retryQueues[name] = true
+	return name, nil
 }
 
-// Close shuts down the channel and connection.
+// DeadLetterAction is what ReplayDeadLetters does with a dead-lettered job.
+type DeadLetterAction int
+
+const (
+	// DeadLetterKeep leaves the job in the dead-letter queue.
+	DeadLetterKeep DeadLetterAction = iota
+	// DeadLetterDrop removes the message, for a job replayed by other means
+	// or that no longer needs it.
+	DeadLetterDrop
+)
+
+// ReplayDeadLetters walks the messages present in the dead-letter queue once
+// and applies the action decide returns for each job.
+func (c *Client) ReplayDeadLetters(decide func(jobID string) DeadLetterAction) error {
+	ch, err := c.channel()
+	if err != nil {
+		return err
+	}
+	remaining := -1
+	for remaining != 0 {
+		d, ok, err := ch.Get(DeadLetterQueue, false)
+		if err != nil {
+			return fmt.Errorf("amqp get %s: %w", DeadLetterQueue, err)
+		}
+		if !ok {
+			return nil
+		}
+		if remaining < 0 {
+			// Kept messages go back to the tail: stop after one pass.
+			remaining = int(d.MessageCount) + 1
+		}
+		remaining--
+
+		var msg jobMessage
+		if err := json.Unmarshal(d.Body, &msg); err != nil || msg.JobID == "" {
+			d.Nack(false, false) // discard malformed message
+			continue
+		}
+		if decide(msg.JobID) == DeadLetterKeep {
+			if err := c.publish(DeadLetterQueue, msg.JobID, d.Headers); err != nil {
+				d.Nack(false, true)
+				return fmt.Errorf("amqp publish %s: %w", DeadLetterQueue, err)
+			}
+		}
+		d.Ack(false)
+	}
+	return nil
+}
+
+// Consume starts a consumer on the queue of each lane of weights and returns
+// their deliveries as one channel: when several lanes have messages waiting,
+// each gets a share of the deliveries proportional to its weight. At most
+// prefetch deliveries, all lanes together, are unacknowledged at a time (0
+// is unlimited). The consumers are resumed after a reconnection, on the same
+// channel; deliveries received before the loss can no longer be
+// acknowledged and are redelivered by the broker. The channel is closed by
+// StopConsuming and Close.
+func (c *Client) Consume(prefetch int, weights map[string]int) (<-chan amqp091.Delivery, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("amqp: no lane to consume")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.consuming {
+		return nil, errors.New("amqp: already consuming")
+	}
+	if c.ch == nil {
+		return nil, ErrNotConnected
+	}
+	c.prefetch = prefetch
+	c.deliveries = make(map[string]chan amqp091.Delivery, len(weights))
+	lanes := make(map[string]chan amqp091.Delivery, len(weights))
+	for lane := range weights {
+		c.deliveries[lane] = make(chan amqp091.Delivery)
+		lanes[lane] = c.deliveries[lane]
+	}
+	c.forwarders = &sync.WaitGroup{}
+	if err := c.startConsumers(); err != nil {
+		return nil, err
+	}
+	c.consuming = true
+
+	out := make(chan amqp091.Delivery)
+	go mergeLanes(lanes, laneSchedule(weights), out, c.done)
+	return out, nil
+}
+
+// startConsumers consumes the queue of each lane on the current channel and
+// forwards the deliveries. The caller holds c.mu.
+func (c *Client) startConsumers() error {
+	// Global: the limit is shared by the consumers of all lanes.
+	if err := c.ch.Qos(c.prefetch, 0, true); err != nil {
+		return fmt.Errorf("amqp qos: %w", err)
+	}
+	for lane, out := range c.deliveries {
+		in, err := c.ch.Consume(
+			laneQueue(lane),
+			consumerTag+"."+lane,
+			false, // auto-ack: we ack manually after processing
+			false, // exclusive
+			false, // no-local
+			false, // no-wait
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("amqp consume %s: %w", laneQueue(lane), err)
+		}
+		c.forwarders.Add(1)
+		go c.forward(in, out, c.forwarders)
+	}
+	return nil
+}
+
+// forward copies the deliveries of one consumer to out until the consumer
+// ends: cancelled, or its channel lost.
+func (c *Client) forward(in <-chan amqp091.Delivery, out chan<- amqp091.Delivery, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		var d amqp091.Delivery
+		var ok bool
+		select {
+		case d, ok = <-in:
+			if !ok {
+				return
+			}
+		case <-c.done:
+			return
+		}
+		select {
+		case out <- d:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// stopDeliveries ends the consumption started by Consume: the lane channels,
+// and then the merged one, are closed once the running consumers forwarded
+// what they received. The caller holds c.mu.
+func (c *Client) stopDeliveries() {
+	if !c.consuming {
+		return
+	}
+	lanes, wg := c.deliveries, c.forwarders
+	go func() {
+		wg.Wait()
+		for _, ch := range lanes {
+			close(ch)
+		}
+	}()
+	c.consuming = false
+	c.deliveries, c.forwarders = nil, nil
+}
+
+// StopConsuming cancels the consumers started by Consume: no new deliveries
+// arrive and the deliveries channel is closed, while the deliveries already
+// received can still be acknowledged.
+func (c *Client) StopConsuming() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.consuming {
+		return nil
+	}
+	lanes := c.deliveries
+	c.stopDeliveries()
+	if c.ch == nil {
+		return nil
+	}
+	for lane := range lanes {
+		if err := c.ch.Cancel(consumerTag+"."+lane, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops the reconnection and shuts down the channel and connection.
 func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+	close(c.done)
 	if c.ch != nil {
 		c.ch.Close()
 	}
 	if c.conn != nil {
 		c.conn.Close()
 	}
+	c.ch, c.conn = nil, nil
+	c.state.Connected = false
+	c.stopDeliveries()
 }
@@ -0,0 +1,78 @@
+package amqp
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// consumingClient is a client consuming the normal lane through the given
+// consumer deliveries, without a broker.
+func consumingClient(in <-chan amqp091.Delivery) *Client {
+	c := &Client{
+		done:       make(chan struct{}),
+		consuming:  true,
+		deliveries: map[string]chan amqp091.Delivery{LaneNormal: make(chan amqp091.Delivery)},
+		forwarders: &sync.WaitGroup{},
+	}
+	c.forwarders.Add(1)
+	go c.forward(in, c.deliveries[LaneNormal], c.forwarders)
+	return c
+}
+
+func TestDeliveriesSurviveConsumerLoss(t *testing.T) {
+	first := make(chan amqp091.Delivery, 1)
+	c := consumingClient(first)
+	out := c.deliveries[LaneNormal]
+
+	first <- amqp091.Delivery{DeliveryTag: 1}
+	close(first) // channel lost
+	if d := <-out; d.DeliveryTag != 1 {
+		t.Fatalf("got delivery %d, want 1", d.DeliveryTag)
+	}
+
+	// Consumer resumed after the reconnection.
+	second := make(chan amqp091.Delivery, 1)
+	c.forwarders.Add(1)
+	go c.forward(second, out, c.forwarders)
+	second <- amqp091.Delivery{DeliveryTag: 2}
+	if d := <-out; d.DeliveryTag != 2 {
+		t.Fatalf("got delivery %d, want 2", d.DeliveryTag)
+	}
+
+	// Cancelled: the deliveries still buffered are forwarded, then out closes.
+	second <- amqp091.Delivery{DeliveryTag: 3}
+	c.stopDeliveries()
+	close(second)
+	if d := <-out; d.DeliveryTag != 3 {
+		t.Fatalf("got delivery %d, want 3", d.DeliveryTag)
+	}
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatal("deliveries channel still open")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deliveries channel not closed")
+	}
+}
+
+func TestCloseWithoutConnection(t *testing.T) {
+	c := consumingClient(make(chan amqp091.Delivery))
+	out := c.deliveries[LaneNormal]
+	c.Close()
+	c.Close() // idempotent
+	select {
+	case <-out:
+	case <-time.After(time.Second):
+		t.Fatal("deliveries channel not closed")
+	}
+	if _, err := c.channel(); err != ErrNotConnected {
+		t.Errorf("channel() error = %v, want ErrNotConnected", err)
+	}
+	if err := c.Publish("job", LaneNormal); err != ErrNotConnected {
+		t.Errorf("publish() error = %v, want ErrNotConnected", err)
+	}
+}
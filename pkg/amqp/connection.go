@@ -0,0 +1,191 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// ErrNotConnected is returned while the client is reconnecting.
+var ErrNotConnected = errors.New("amqp: not connected")
+
+var errClientClosed = errors.New("amqp: client closed")
+
+// State is the connection status of a Client, for health checks.
+type State struct {
+	Connected bool `json:"connected"`
+	// Since is when the client last connected or lost the connection.
+	Since      time.Time `json:"since"`
+	Reconnects int       `json:"reconnects"`
+	// LastError is why the connection was last lost or could not be
+	// established.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// State returns the current connection status.
+func (c *Client) State() State {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.state
+}
+
+// channel returns the current channel, or ErrNotConnected.
+func (c *Client) channel() (*amqp091.Channel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ch == nil {
+		return nil, ErrNotConnected
+	}
+	return c.ch, nil
+}
+
+// connect dials RabbitMQ, opens a channel in confirm mode, declares the
+// queues and resumes the consumers.
+func (c *Client) connect() error {
+	conn, err := amqp091.Dial(c.url)
+	if err != nil {
+		return fmt.Errorf("amqp dial: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("amqp channel: %w", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return fmt.Errorf("amqp confirm mode: %w", err)
+	}
+
+	queues := []string{DeadLetterQueue}
+	for _, lane := range Lanes {
+		queues = append(queues, laneQueue(lane))
+	}
+	for _, name := range queues {
+		_, err = ch.QueueDeclare(
+			name,
+			true,  // durable
+			false, // auto-delete
+			false, // exclusive
+			false, // no-wait
+			nil,
+		)
+		if err != nil {
+			conn.Close()
+			return fmt.Errorf("amqp queue declare %s: %w", name, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		conn.Close()
+		return errClientClosed
+	default:
+	}
+	c.conn, c.ch = conn, ch
+	// Delay queues are declared again on first use.
+	c.retryQueues = map[string]bool{}
+	if c.consuming {
+		if err := c.startConsumers(); err != nil {
+			conn.Close()
+			c.conn, c.ch = nil, nil
+			return err
+		}
+	}
+	c.state.Connected = true
+	c.state.Since = time.Now()
+	return nil
+}
+
+// watch reconnects whenever the connection or the channel closes, until
+// Close is called.
+func (c *Client) watch() {
+	for {
+		c.mu.RLock()
+		conn, ch := c.conn, c.ch
+		c.mu.RUnlock()
+		if conn == nil {
+			return
+		}
+		connClosed := conn.NotifyClose(make(chan *amqp091.Error, 1))
+		chClosed := ch.NotifyClose(make(chan *amqp091.Error, 1))
+
+		var reason *amqp091.Error
+		select {
+		case <-c.done:
+			return
+		case reason = <-connClosed:
+		case reason = <-chClosed:
+		}
+		if !c.disconnected(reason) {
+			return
+		}
+		if !c.reconnect() {
+			return
+		}
+	}
+}
+
+// disconnected drops the current connection after a loss. It returns false
+// when the client was closed.
+func (c *Client) disconnected(reason *amqp091.Error) bool {
+	msg := "connection closed"
+	if reason != nil {
+		msg = reason.Error()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn, c.ch = nil, nil
+	c.state.Connected = false
+	c.state.Since = time.Now()
+	c.state.LastError = msg
+	fmt.Printf("warning: RabbitMQ connection lost: %s, reconnecting\n", msg)
+	return true
+}
+
+// reconnect dials again with exponential backoff. It returns false when the
+// client was closed first.
+func (c *Client) reconnect() bool {
+	delay := minReconnectDelay
+	for {
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(delay):
+		}
+		err := c.connect()
+		if err == nil {
+			c.mu.Lock()
+			c.state.Reconnects++
+			c.mu.Unlock()
+			fmt.Println("RabbitMQ connection restored")
+			return true
+		}
+		if errors.Is(err, errClientClosed) {
+			return false
+		}
+		c.mu.Lock()
+		c.state.LastError = err.Error()
+		c.mu.Unlock()
+		fmt.Printf("warning: RabbitMQ reconnection failed: %v, next try in %s\n", err, min(delay*2, maxReconnectDelay))
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
@@ -0,0 +1,162 @@
+package amqp
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+// Lanes of the job queue, from the most to the least urgent. Each lane is
+// its own queue, so that a bulk run waiting in the low lane never delays an
+// interactive job; the normal lane is the historical pdf_jobs queue.
+const (
+	LaneHigh   = "high"
+	LaneNormal = "normal"
+	LaneLow    = "low"
+)
+
+// Lanes lists the lanes from the most to the least urgent.
+var Lanes = []string{LaneHigh, LaneNormal, LaneLow}
+
+// DefaultLaneWeights is the share of the deliveries each lane gets when all
+// of them have messages waiting.
+var DefaultLaneWeights = map[string]int{LaneHigh: 6, LaneNormal: 3, LaneLow: 1}
+
+// headerLane records on a dead-lettered message the lane of its job.
+const headerLane = "x-lane"
+
+// laneQueue returns the queue of lane; an unknown lane is the normal one.
+func laneQueue(lane string) string {
+	switch lane {
+	case LaneHigh, LaneLow:
+		return queueName + "." + lane
+	}
+	return queueName
+}
+
+// ParseLaneWeights parses a lane list such as "high:6,normal:3,low:1"; a lane
+// without weight weighs 1. Empty is a copy of DefaultLaneWeights.
+func ParseLaneWeights(s string) (map[string]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return maps.Clone(DefaultLaneWeights), nil
+	}
+	weights := map[string]int{}
+	for _, item := range strings.Split(s, ",") {
+		lane, weight, hasWeight := strings.Cut(strings.TrimSpace(item), ":")
+		lane = strings.ToLower(strings.TrimSpace(lane))
+		if lane != LaneHigh && lane != LaneNormal && lane != LaneLow {
+			return nil, fmt.Errorf("unknown lane %q (want high, normal or low)", lane)
+		}
+		w := 1
+		if hasWeight {
+			n, err := strconv.Atoi(strings.TrimSpace(weight))
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid weight %q for lane %s", weight, lane)
+			}
+			w = n
+		}
+		weights[lane] = w
+	}
+	return weights, nil
+}
+
+// laneSchedule interleaves the lanes in proportion to their weights (smooth
+// weighted round-robin): {high: 2, low: 1} gives high, low, high.
+func laneSchedule(weights map[string]int) []string {
+	lanes := make([]string, 0, len(weights))
+	total := 0
+	for lane, w := range weights {
+		lanes = append(lanes, lane)
+		total += w
+	}
+	// Ties go to the most urgent lane.
+	sort.Slice(lanes, func(i, j int) bool { return laneRank(lanes[i]) < laneRank(lanes[j]) })
+
+	current := make(map[string]int, len(lanes))
+	schedule := make([]string, 0, total)
+	for range total {
+		best := ""
+		for _, lane := range lanes {
+			current[lane] += weights[lane]
+			if best == "" || current[lane] > current[best] {
+				best = lane
+			}
+		}
+		current[best] -= total
+		schedule = append(schedule, best)
+	}
+	return schedule
+}
+
+func laneRank(lane string) int {
+	for i, l := range Lanes {
+		if l == lane {
+			return i
+		}
+	}
+	return len(Lanes)
+}
+
+// mergeLanes forwards the deliveries of the lanes to out, one at a time as
+// out is read. When several lanes have deliveries waiting, they take turns
+// following schedule; an idle lane gives its turn away. out is closed once
+// every lane is closed.
+func mergeLanes(lanes map[string]chan amqp091.Delivery, schedule []string, out chan<- amqp091.Delivery, done <-chan struct{}) {
+	defer close(out)
+	open := len(lanes)
+	pos := 0
+	for open > 0 {
+		var d amqp091.Delivery
+		got := false
+		// Non-blocking pass, starting with the lane whose turn it is.
+		for i := 0; i < len(schedule) && !got; i++ {
+			lane := schedule[(pos+i)%len(schedule)]
+			ch := lanes[lane]
+			if ch == nil {
+				continue
+			}
+			select {
+			case delivery, ok := <-ch:
+				if !ok {
+					lanes[lane] = nil
+					open--
+					continue
+				}
+				d, got = delivery, true
+			default:
+			}
+		}
+		pos = (pos + 1) % len(schedule)
+		if !got {
+			if open == 0 {
+				return
+			}
+			var ok bool
+			var lane string
+			select {
+			case d, ok = <-lanes[LaneHigh]:
+				lane = LaneHigh
+			case d, ok = <-lanes[LaneNormal]:
+				lane = LaneNormal
+			case d, ok = <-lanes[LaneLow]:
+				lane = LaneLow
+			case <-done:
+				return
+			}
+			if !ok {
+				lanes[lane] = nil
+				open--
+				continue
+			}
+		}
+		select {
+		case out <- d:
+		case <-done:
+			return
+		}
+	}
+}
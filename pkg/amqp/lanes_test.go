@@ -0,0 +1,101 @@
+package amqp
+
+import (
+	"reflect"
+	"testing"
+
+	amqp091 "github.com/rabbitmq/amqp091-go"
+)
+
+func TestLaneQueue(t *testing.T) {
+	for lane, want := range map[string]string{
+		LaneHigh:   "pdf_jobs.high",
+		LaneNormal: "pdf_jobs",
+		LaneLow:    "pdf_jobs.low",
+		"":         "pdf_jobs",
+	} {
+		if got := laneQueue(lane); got != want {
+			t.Errorf("laneQueue(%q) = %q, want %q", lane, got, want)
+		}
+	}
+}
+
+func TestParseLaneWeights(t *testing.T) {
+	for in, want := range map[string]map[string]int{
+		"":                      DefaultLaneWeights,
+		"low":                   {LaneLow: 1},
+		" High:4 , normal ":     {LaneHigh: 4, LaneNormal: 1},
+		"high:6,normal:3,low:1": {LaneHigh: 6, LaneNormal: 3, LaneLow: 1},
+	} {
+		got, err := ParseLaneWeights(in)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseLaneWeights(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"urgent", "high:0", "low:x"} {
+		if _, err := ParseLaneWeights(in); err == nil {
+			t.Errorf("ParseLaneWeights(%q): no error", in)
+		}
+	}
+
+	got, _ := ParseLaneWeights("")
+	got[LaneLow] = 100
+	if DefaultLaneWeights[LaneLow] != 1 {
+		t.Error("editing the parsed weights changed DefaultLaneWeights")
+	}
+}
+
+func TestLaneSchedule(t *testing.T) {
+	got := laneSchedule(map[string]int{LaneHigh: 2, LaneLow: 1})
+	want := []string{LaneHigh, LaneLow, LaneHigh}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("laneSchedule() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeLanesWeighted(t *testing.T) {
+	lanes := map[string]chan amqp091.Delivery{}
+	for _, lane := range Lanes {
+		lanes[lane] = make(chan amqp091.Delivery, 20)
+		for range 20 {
+			lanes[lane] <- amqp091.Delivery{ConsumerTag: lane}
+		}
+	}
+	out := make(chan amqp091.Delivery)
+	done := make(chan struct{})
+	defer close(done)
+	go mergeLanes(lanes, laneSchedule(DefaultLaneWeights), out, done)
+
+	counts := map[string]int{}
+	for range 20 {
+		counts[(<-out).ConsumerTag]++
+	}
+	if want := map[string]int{LaneHigh: 12, LaneNormal: 6, LaneLow: 2}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("deliveries by lane = %v, want %v", counts, want)
+	}
+}
+
+func TestMergeLanesIdleLane(t *testing.T) {
+	high := make(chan amqp091.Delivery)
+	low := make(chan amqp091.Delivery, 3)
+	for range 3 {
+		low <- amqp091.Delivery{ConsumerTag: LaneLow}
+	}
+	close(low)
+	out := make(chan amqp091.Delivery)
+	done := make(chan struct{})
+	defer close(done)
+	go mergeLanes(map[string]chan amqp091.Delivery{LaneHigh: high, LaneLow: low},
+		laneSchedule(map[string]int{LaneHigh: 6, LaneLow: 1}), out, done)
+
+	// The high lane is idle: low gets every delivery.
+	for range 3 {
+		if d := <-out; d.ConsumerTag != LaneLow {
+			t.Fatalf("got %q, want low", d.ConsumerTag)
+		}
+	}
+	close(high)
+	if _, ok := <-out; ok {
+		t.Error("merged channel still open after every lane closed")
+	}
+}
@@ -0,0 +1,179 @@
+package assets
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// vendor mirrors CDN files as vendor/<host>/<path>, e.g.
+// vendor/cdn.jsdelivr.net/npm/bootstrap@5/dist/css/bootstrap.min.css.
+// Google Fonts stylesheets live under vendor/fonts.googleapis.com/css2/<Family>.css.
+// Populate it with ./vendor-assets.sh before building.
+//
+//go:embed all:vendor
+var embedded embed.FS
+
+// coreAssets are the files every template may load; fonts are optional.
+var coreAssets = []string{
+	"cdn.jsdelivr.net/npm/@tailwindcss/browser@4",
+	"cdn.jsdelivr.net/npm/bootstrap@5/dist/css/bootstrap.min.css",
+	"cdnjs.cloudflare.com/ajax/libs/highlight.js/11.11.1/highlight.min.js",
+	"cdnjs.cloudflare.com/ajax/libs/highlight.js/11.11.1/styles/github-dark.min.css",
+}
+
+// Store resolves renderer requests to locally available copies of CDN assets.
+type Store struct {
+	dir     string
+	files   fs.FS
+	offline bool
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+)
+
+// Default returns the store configured by ASSETS_DIR (on-disk mirror checked
+// before the embedded one) and ASSETS_OFFLINE (block every other request).
+func Default() *Store {
+	defaultStoreOnce.Do(func() {
+		defaultStore = New(os.Getenv("ASSETS_DIR"), os.Getenv("ASSETS_OFFLINE") == "true")
+	})
+	return defaultStore
+}
+
+// New builds a store reading dir first, then the embedded mirror.
+func New(dir string, offline bool) *Store {
+	sub, _ := fs.Sub(embedded, "vendor")
+	return &Store{dir: dir, files: sub, offline: offline}
+}
+
+// Offline reports whether requests missing from the store must be blocked.
+func (s *Store) Offline() bool {
+	return s.offline
+}
+
+// Missing lists the core assets found neither in dir nor in the embedded mirror.
+func (s *Store) Missing() []string {
+	var missing []string
+	for _, name := range coreAssets {
+		if _, ok := s.read(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// Check fails when offline rendering is requested without the core assets:
+// every template would render unstyled. Online, the CDN still serves them,
+// so it only warns.
+func (s *Store) Check() error {
+	missing := s.Missing()
+	if len(missing) == 0 {
+		return nil
+	}
+	if s.offline {
+		return fmt.Errorf("ASSETS_OFFLINE=true but %d core assets are missing (%s): run ./vendor-assets.sh before building or set ASSETS_DIR", len(missing), strings.Join(missing, ", "))
+	}
+	fmt.Printf("warning: %d core assets are not vendored, the renderer will load them from the CDN (run ./vendor-assets.sh)\n", len(missing))
+	return nil
+}
+
+// Lookup returns the local copy of rawURL with its content type.
+func (s *Store) Lookup(rawURL string) ([]byte, string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, "", false
+	}
+
+	if u.Host == "fonts.googleapis.com" && (u.Path == "/css" || u.Path == "/css2") {
+		return s.googleFontsCSS(fontFamilies(u.RawQuery))
+	}
+
+	name := path.Clean(u.Host + "/" + strings.TrimPrefix(u.Path, "/"))
+	body, ok := s.read(name)
+	if !ok {
+		return nil, "", false
+	}
+	return body, contentTypeFor(name), true
+}
+
+// googleFontsCSS concatenates the mirrored stylesheet of every requested family.
+// A single missing family makes the whole lookup miss.
+func (s *Store) googleFontsCSS(families []string) ([]byte, string, bool) {
+	if len(families) == 0 {
+		return nil, "", false
+	}
+	var css []byte
+	for _, family := range families {
+		name, _, _ := strings.Cut(family, ":")
+		body, ok := s.read("fonts.googleapis.com/css2/" + strings.TrimSpace(name) + ".css")
+		if !ok {
+			return nil, "", false
+		}
+		css = append(css, body...)
+		css = append(css, '\n')
+	}
+	return css, "text/css; charset=utf-8", true
+}
+
+// fontFamilies reads every family= value by hand: url.ParseQuery drops pairs
+// containing the ';' separating weights (family=Inter:wght@100;400).
+func fontFamilies(rawQuery string) []string {
+	var families []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		value, ok := strings.CutPrefix(pair, "family=")
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(value); err == nil {
+			families = append(families, unescaped)
+		}
+	}
+	return families
+}
+
+func (s *Store) read(name string) ([]byte, bool) {
+	if !fs.ValidPath(name) {
+		return nil, false
+	}
+	if s.dir != "" {
+		if body, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(name))); err == nil {
+			return body, true
+		}
+	}
+	if s.files != nil {
+		if body, err := fs.ReadFile(s.files, name); err == nil {
+			return body, true
+		}
+	}
+	return nil, false
+}
+
+func contentTypeFor(name string) string {
+	ext := path.Ext(name)
+	switch ext {
+	case ".js":
+		return "application/javascript; charset=utf-8"
+	case ".css":
+		return "text/css; charset=utf-8"
+	case ".woff2":
+		return "font/woff2"
+	}
+	// Extension-less CDN entry points such as @tailwindcss/browser@4 or @4.1.11.
+	if ext == "" || strings.Trim(ext[1:], "0123456789") == "" {
+		return "application/javascript; charset=utf-8"
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,91 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeAsset(t *testing.T, dir, name, body string) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreLookup_MirrorLayout(t *testing.T) {
+	dir := t.TempDir()
+	writeAsset(t, dir, "cdn.jsdelivr.net/npm/@tailwindcss/browser@4", "tw()")
+	writeAsset(t, dir, "cdn.jsdelivr.net/npm/bootstrap@5/dist/css/bootstrap.min.css", "body{}")
+	s := New(dir, false)
+
+	body, contentType, ok := s.Lookup("https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4")
+	if !ok || string(body) != "tw()" || !strings.HasPrefix(contentType, "application/javascript") {
+		t.Errorf("tailwind lookup = %q, %q, %v", body, contentType, ok)
+	}
+	if _, contentType, ok := s.Lookup("https://cdn.jsdelivr.net/npm/bootstrap@5/dist/css/bootstrap.min.css"); !ok || !strings.HasPrefix(contentType, "text/css") {
+		t.Errorf("bootstrap lookup = %q, %v", contentType, ok)
+	}
+	if _, _, ok := s.Lookup("https://cdn.jsdelivr.net/npm/other.js"); ok {
+		t.Error("unknown asset should miss")
+	}
+	if _, _, ok := s.Lookup("https://cdn.jsdelivr.net/../../etc/passwd"); ok {
+		t.Error("path escaping the mirror should miss")
+	}
+}
+
+func TestStoreLookup_GoogleFonts(t *testing.T) {
+	dir := t.TempDir()
+	writeAsset(t, dir, "fonts.googleapis.com/css2/Inter.css", "/* inter */")
+	writeAsset(t, dir, "fonts.googleapis.com/css2/Open Sans.css", "/* open sans */")
+	s := New(dir, false)
+
+	body, _, ok := s.Lookup("https://fonts.googleapis.com/css2?family=Inter:wght@100;400&display=swap&family=Open+Sans")
+	if !ok || !strings.Contains(string(body), "inter") || !strings.Contains(string(body), "open sans") {
+		t.Errorf("fonts lookup = %q, %v", body, ok)
+	}
+	if _, _, ok := s.Lookup("https://fonts.googleapis.com/css2?family=Inter&family=Roboto"); ok {
+		t.Error("a missing family should make the whole stylesheet miss")
+	}
+}
+
+func TestExtractClasses(t *testing.T) {
+	html := `<div class="p-4 flex"><span class='text-sm flex'>x</span><p className="mt-2"></p></div>`
+	want := []string{"flex", "mt-2", "p-4", "text-sm"}
+	if got := ExtractClasses(html); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractClasses() = %v, want %v", got, want)
+	}
+}
+
+func TestStoreCheck(t *testing.T) {
+	dir := t.TempDir()
+	writeAsset(t, dir, "cdn.jsdelivr.net/npm/@tailwindcss/browser@4", "tw()")
+
+	// The embedded mirror may be populated by vendor-assets.sh; only test
+	// against it when it is empty, as in a fresh checkout.
+	if len(New("", false).Missing()) != len(coreAssets) {
+		t.Skip("embedded mirror is populated")
+	}
+	if got := New(dir, false).Missing(); len(got) != len(coreAssets)-1 {
+		t.Errorf("Missing() = %v, want every core asset but tailwind", got)
+	}
+	if err := New(dir, false).Check(); err != nil {
+		t.Errorf("online Check() = %v, want a warning only", err)
+	}
+	if err := New(dir, true).Check(); err == nil {
+		t.Error("offline Check() with missing assets should fail")
+	}
+
+	for _, name := range coreAssets {
+		writeAsset(t, dir, name, "x")
+	}
+	if err := New(dir, true).Check(); err != nil {
+		t.Errorf("offline Check() with every core asset = %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrTailwindUnavailable means no Tailwind CLI was found (TAILWIND_BIN or
+// tailwindcss on PATH); callers fall back to the browser build.
+var ErrTailwindUnavailable = errors.New("tailwind CLI not available")
+
+// tailwindCacheSize bounds the number of compiled stylesheets kept in memory.
+const tailwindCacheSize = 256
+
+// tailwindInput restricts scanning to the extracted class list; without
+// source(none) the CLI would walk the whole working directory.
+const tailwindInput = `@import "tailwindcss" source(none);
+@source "./classes.html";
+`
+
+var classAttrRe = regexp.MustCompile(`class(?:Name)?\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+
+var (
+	tailwindMu    sync.Mutex
+	tailwindCache = map[string]string{}
+	tailwindOrder []string
+)
+
+// TailwindCSS compiles the Tailwind utilities used by html with the standalone
+// CLI. Results are cached by class set, so templates rendered with different
+// data reuse the same stylesheet.
+func TailwindCSS(ctx context.Context, html string) (string, error) {
+	bin := tailwindBin()
+	if bin == "" {
+		return "", ErrTailwindUnavailable
+	}
+
+	classes := ExtractClasses(html)
+	sum := sha256.Sum256([]byte(strings.Join(classes, " ")))
+	key := hex.EncodeToString(sum[:])
+
+	tailwindMu.Lock()
+	css, ok := tailwindCache[key]
+	tailwindMu.Unlock()
+	if ok {
+		return css, nil
+	}
+
+	css, err := compileTailwind(ctx, bin, classes)
+	if err != nil {
+		return "", err
+	}
+
+	tailwindMu.Lock()
+	if _, exists := tailwindCache[key]; !exists {
+		tailwindCache[key] = css
+		tailwindOrder = append(tailwindOrder, key)
+		if len(tailwindOrder) > tailwindCacheSize {
+			delete(tailwindCache, tailwindOrder[0])
+			tailwindOrder = tailwindOrder[1:]
+		}
+	}
+	tailwindMu.Unlock()
+	return css, nil
+}
+
+// ExtractClasses returns the sorted, de-duplicated class tokens of html.
+func ExtractClasses(html string) []string {
+	seen := map[string]struct{}{}
+	for _, m := range classAttrRe.FindAllStringSubmatch(html, -1) {
+		for _, token := range strings.Fields(m[1] + " " + m[2]) {
+			seen[token] = struct{}{}
+		}
+	}
+	classes := make([]string, 0, len(seen))
+	for token := range seen {
+		classes = append(classes, token)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+func compileTailwind(ctx context.Context, bin string, classes []string) (string, error) {
+	dir, err := os.MkdirTemp("", "tailwind-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	source := `<div class="` + strings.Join(classes, " ") + `"></div>`
+	if err := os.WriteFile(filepath.Join(dir, "classes.html"), []byte(source), 0o600); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "input.css"), []byte(tailwindInput), 0o600); err != nil {
+		return "", err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "--input", "input.css", "--output", "output.css", "--minify")
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tailwind CLI failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	css, err := os.ReadFile(filepath.Join(dir, "output.css"))
+	if err != nil {
+		return "", err
+	}
+	return string(css), nil
+}
+
+func tailwindBin() string {
+	if bin := os.Getenv("TAILWIND_BIN"); bin != "" {
+		return bin
+	}
+	if bin, err := exec.LookPath("tailwindcss"); err == nil {
+		return bin
+	}
+	return ""
+}
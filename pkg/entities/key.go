@@ -16,4 +16,5 @@ type Key struct {
 	LastUsedAt   *time.Time `json:"last_used_at"`
 	Logs         []Log      `json:"logs"`
 	UserID       uint       `json:"user_id"`
+	Priority     string     `json:"priority" gorm:"default:''"`
 }
@@ -24,5 +24,11 @@ func (ns *Namespace) BeforeDelete(tx *gorm.DB) error {
 	for _, template := range ns.Templates {
 		tx.Delete(&template)
 	}
+	if err := tx.Where("namespace_id = ?", ns.ID).Delete(&Partial{}).Error; err != nil {
+		return fmt.Errorf("failed to delete partials of namespace %v", err)
+	}
+	if err := tx.Where("namespace_id = ?", ns.ID).Delete(&SigningCertificate{}).Error; err != nil {
+		return fmt.Errorf("failed to delete signing certificates of namespace %v", err)
+	}
 	return nil
 }
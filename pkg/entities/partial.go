@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// Partial is a reusable Handlebars fragment (letterhead, footer…) shared by
+// the templates of a namespace, which call it with {{> name}}.
+type Partial struct {
+	ID          uint      `json:"id" gorm:"primarykey"`
+	NamespaceID uint      `json:"namespace_id" gorm:"not null;uniqueIndex:idx_partials_namespace_name"`
+	Name        string    `json:"name" gorm:"size:64;not null;uniqueIndex:idx_partials_namespace_name"`
+	Content     string    `json:"content" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
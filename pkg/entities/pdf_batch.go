@@ -0,0 +1,23 @@
+package entities
+
+import "time"
+
+// PdfGenerationBatch groups the PdfGenerationJobs created by one batch request.
+// Counters are incremented by the worker as child jobs finish.
+type PdfGenerationBatch struct {
+	ID             string             `json:"id" gorm:"type:varchar(36);primaryKey"`
+	KeyID          uint               `json:"key_id" gorm:"not null;index"`
+	Key            Key                `json:"-" gorm:"foreignKey:KeyID"`
+	TemplateUUID   string             `json:"template_uuid" gorm:"not null"`
+	Format         string             `json:"format" gorm:"default:''"`
+	Status         JobStatus          `json:"status" gorm:"default:'queued'"`
+	TotalCount     int                `json:"total_count"`
+	CompletedCount int                `json:"completed_count" gorm:"default:0"`
+	FailedCount    int                `json:"failed_count" gorm:"default:0"`
+	Zip            bool               `json:"zip" gorm:"default:false"`
+	ZipPath        string             `json:"zip_path"`
+	Jobs           []PdfGenerationJob `json:"jobs,omitempty" gorm:"foreignKey:BatchID"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	CompletedAt    *time.Time         `json:"completed_at"`
+}
@@ -0,0 +1,16 @@
+package entities
+
+import "time"
+
+// PdfCacheEntry maps the content hash of a render (template + data + options)
+// to the object already uploaded for it. Shared by the API and the worker.
+type PdfCacheEntry struct {
+	Hash      string    `json:"hash" gorm:"type:varchar(64);primaryKey"`
+	ObjectKey string    `json:"object_key" gorm:"not null"`
+	URL       string    `json:"url" gorm:"not null"`
+	Size      int64     `json:"size"`
+	HitCount  int       `json:"hit_count" gorm:"default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	LastHitAt time.Time `json:"last_hit_at" gorm:"index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
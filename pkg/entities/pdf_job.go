@@ -11,6 +11,7 @@ type JobStatus string
 const (
 	JobStatusQueued    JobStatus = "queued"
 	JobStatusRunning   JobStatus = "running"
+	JobStatusRetrying  JobStatus = "retrying"
 	JobStatusCompleted JobStatus = "completed"
 	JobStatusFailed    JobStatus = "failed"
 )
@@ -21,10 +22,25 @@ type PdfGenerationJob struct {
 	Key          Key            `json:"key" gorm:"foreignKey:KeyID"`
 	TemplateUUID string         `json:"template_uuid" gorm:"not null"`
 	Payload      datatypes.JSON `json:"payload"`
-	Format       string         `json:"format" gorm:"default:'A4'"`
-	Status       JobStatus      `json:"status" gorm:"default:'queued'"`
-	ResultPath   string         `json:"result_path"`
-	ErrorMessage string         `json:"error_message"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
+	Format       string         `json:"format" gorm:"default:''"`
+	Output       string         `json:"output" gorm:"default:'pdf'"`
+	Options      datatypes.JSON `json:"options,omitempty"`
+	NoCache      bool           `json:"no_cache" gorm:"default:false"`
+	// SealedPasswords are the PDF passwords of the request, sealed with
+	// ENCRYPTION_KEY (see pdfjob.ProtectOptions).
+	SealedPasswords []byte `json:"-"`
+	// TemplateVersion is the version pinned at enqueue time (-1 = draft at render time).
+	TemplateVersion int        `json:"template_version" gorm:"default:0"`
+	CacheHit        bool       `json:"cache_hit" gorm:"default:false"`
+	Status          JobStatus  `json:"status" gorm:"default:'queued'"`
+	ResultPath      string     `json:"result_path"`
+	ErrorMessage    string     `json:"error_message"`
+	ResultKey       string     `json:"-"`
+	BatchID         *string    `json:"batch_id,omitempty" gorm:"type:varchar(36);index"`
+	Priority        string     `json:"priority" gorm:"default:'normal'"`
+	Attempts        int        `json:"attempts" gorm:"default:0"`
+	WorkerID        string     `json:"-" gorm:"type:varchar(64);default:''"`
+	DeadLetteredAt  *time.Time `json:"dead_lettered_at,omitempty" gorm:"index"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
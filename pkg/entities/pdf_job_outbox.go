@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+// PdfJobOutbox is a job publication waiting to be relayed to RabbitMQ. It is
+// written in the transaction that queues the job and deleted once the broker
+// confirmed the message, so a job is never queued without being published.
+// A relay claims rows (ClaimedBy, ClaimedAt) before publishing them. A row
+// with a Delay schedules a retry through the delay queue of its lane; a
+// DeadLetter row moves the job to the dead-letter queue with its Reason.
+type PdfJobOutbox struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	JobID      string        `json:"job_id" gorm:"type:varchar(36);not null;index"`
+	Priority   string        `json:"priority" gorm:"default:'normal'"`
+	Delay      time.Duration `json:"delay" gorm:"default:0"`
+	DeadLetter bool          `json:"dead_letter" gorm:"default:false"`
+	Reason     string        `json:"reason" gorm:"type:text"`
+	Attempts   int           `json:"attempts" gorm:"default:0"`
+	LastError  string        `json:"last_error"`
+	ClaimedBy  string        `json:"claimed_by" gorm:"type:varchar(36);default:''"`
+	ClaimedAt  *time.Time    `json:"claimed_at" gorm:"index"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+// SigningCertificate is an uploaded certificate chain and its private key,
+// used to sign generated PDFs. A certificate with a NamespaceID signs the
+// templates of that namespace; one without is the default of its user.
+// The private key is stored encrypted (see pkg/signing) and never serialized.
+type SigningCertificate struct {
+	ID             uint      `json:"id" gorm:"primarykey"`
+	UserID         uint      `json:"user_id" gorm:"not null;index"`
+	NamespaceID    *uint     `json:"namespace_id" gorm:"index"`
+	Name           string    `json:"name"`
+	Subject        string    `json:"subject"`
+	Issuer         string    `json:"issuer"`
+	SerialNumber   string    `json:"serial_number"`
+	Fingerprint    string    `json:"fingerprint" gorm:"size:64"`
+	NotBefore      time.Time `json:"not_before"`
+	NotAfter       time.Time `json:"not_after"`
+	CertificatePEM string    `json:"certificate_pem" gorm:"type:text"`
+	EncryptedKey   []byte    `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
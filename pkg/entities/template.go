@@ -20,12 +20,16 @@ type Template struct {
 	Content       string         `json:"content"`
 	Framework     FrameworkType  `json:"framework"`
 	Variables     datatypes.JSON `json:"variables" gorm:"type:json"`
+	// VariablesSchema is an optional JSON Schema every generation payload must satisfy.
+	VariablesSchema datatypes.JSON `json:"variables_schema" gorm:"type:json"`
 	Fonts         MultiString    `json:"fonts"`
 	Logs          []Log          `gorm:"constraint:OnDelete:SET NULL;foreignKey:TemplateID"`
 	NamespaceID   uint
 	Namespace     Namespace   `json:"-" gorm:"foreignKey:NamespaceID"`
 	Description   string      `json:"description"`
 	CoverImageURL string      `json:"cover_image_url"`
+	// ThumbnailURL is a PNG of the draft's first page, refreshed on each save.
+	ThumbnailURL  string      `json:"thumbnail_url"`
 	Price         int         `json:"price"`
 	IsMarketplace bool        `json:"is_marketplace" gorm:"default:false"`
 	IsPublished   bool        `json:"is_published" gorm:"default:false"`
@@ -34,6 +38,36 @@ type Template struct {
 	UsesCount          int         `json:"uses_count" gorm:"default:0"`
 	PdfBackgroundColor string      `json:"pdf_background_color" gorm:"default:''"`
 	PdfContentPadding  string      `json:"pdf_content_padding" gorm:"default:''"`
+	// Page setup defaults, overridden per request (see utils.NewPageLayout).
+	PdfFormat          string      `json:"pdf_format" gorm:"default:''"`
+	PdfOrientation     string      `json:"pdf_orientation" gorm:"default:''"`
+	PdfMargin          string      `json:"pdf_margin" gorm:"default:''"`
+	// Repeating header/footer (Handlebars, same data plus {{pageNumber}} and {{totalPages}}).
+	PdfHeader          string      `json:"pdf_header" gorm:"type:text"`
+	PdfFooter          string      `json:"pdf_footer" gorm:"type:text"`
+	PdfHeaderHeight    string      `json:"pdf_header_height" gorm:"default:''"`
+	PdfFooterHeight    string      `json:"pdf_footer_height" gorm:"default:''"`
+	// PdfPermissions are the default restrictions of generated PDFs
+	// (no_print, no_copy, no_modify); passwords are only given per request.
+	PdfPermissions     MultiString `json:"pdf_permissions"`
+	// Document information (Handlebars, rendered with the request data): the
+	// title defaults to the template name, PdfFilename names the file in
+	// storage paths and Content-Disposition.
+	PdfTitle           string      `json:"pdf_title" gorm:"default:''"`
+	PdfAuthor          string      `json:"pdf_author" gorm:"default:''"`
+	PdfSubject         string      `json:"pdf_subject" gorm:"default:''"`
+	PdfKeywords        string      `json:"pdf_keywords" gorm:"default:''"`
+	PdfFilename        string      `json:"pdf_filename" gorm:"default:''"`
+	// Versioning: LatestVersion is the last saved snapshot, PublishedVersion the
+	// one generate-pdf renders by default (0 = none published, render the draft).
+	LatestVersion      int         `json:"latest_version" gorm:"default:0"`
+	PublishedVersion   int         `json:"published_version" gorm:"default:0"`
+	// Translations are the {{t}} message bundles by locale; DefaultLocale is
+	// used when a request names no locale (see utils.LocaleFallbacks).
+	Translations       datatypes.JSON `json:"translations" gorm:"type:json"`
+	DefaultLocale      string         `json:"default_locale" gorm:"default:''"`
+	// Partials are the namespace partials the template calls, loaded for rendering.
+	Partials           map[string]string `json:"-" gorm:"-"`
 }
 
 func (template *Template) BeforeCreate(tx *gorm.DB) (err error) {
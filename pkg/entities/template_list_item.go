@@ -1,21 +1,20 @@
 package entities
 
 import (
-	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
-// TemplateListItem is a row for dashboard template cards (includes content for MiniPreview).
+// TemplateListItem is a row for dashboard template cards. The body is not
+// shipped: cards show ThumbnailURL, or the preview endpoint.
 type TemplateListItem struct {
 	gorm.Model
 	UUID               string      `json:"uuid"`
 	Name               string      `json:"name"`
-	Content            string      `json:"content"`
-	Variables          datatypes.JSON `json:"variables"`
 	Framework          FrameworkType `json:"framework"`
 	Fonts              MultiString `json:"fonts"`
 	NamespaceID        uint        `json:"NamespaceID"`
 	Description        string      `json:"description"`
+	ThumbnailURL       string      `json:"thumbnail_url"`
 	Price              int         `json:"price"`
 	IsMarketplace      bool        `json:"is_marketplace"`
 	IsPublished        bool        `json:"is_published"`
@@ -23,4 +22,9 @@ type TemplateListItem struct {
 	UsesCount          int         `json:"uses_count"`
 	PdfBackgroundColor string      `json:"pdf_background_color"`
 	PdfContentPadding  string      `json:"pdf_content_padding"`
+	PdfFormat          string      `json:"pdf_format"`
+	PdfOrientation     string      `json:"pdf_orientation"`
+	PdfMargin          string      `json:"pdf_margin"`
+	LatestVersion      int         `json:"latest_version"`
+	PublishedVersion   int         `json:"published_version"`
 }
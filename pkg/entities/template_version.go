@@ -0,0 +1,38 @@
+package entities
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// TemplateVersion is an immutable snapshot of everything that changes the
+// rendered PDF of a template, appended on each save. Numbers start at 1.
+type TemplateVersion struct {
+	ID                 uint           `json:"id" gorm:"primarykey"`
+	TemplateID         uint           `json:"template_id" gorm:"not null;uniqueIndex:idx_template_versions_number"`
+	Version            int            `json:"version" gorm:"not null;uniqueIndex:idx_template_versions_number"`
+	Content            string         `json:"content"`
+	Framework          FrameworkType  `json:"framework"`
+	Variables          datatypes.JSON `json:"variables" gorm:"type:json"`
+	VariablesSchema    datatypes.JSON `json:"variables_schema" gorm:"type:json"`
+	Fonts              MultiString    `json:"fonts"`
+	PdfBackgroundColor string         `json:"pdf_background_color"`
+	PdfContentPadding  string         `json:"pdf_content_padding"`
+	PdfFormat          string         `json:"pdf_format"`
+	PdfOrientation     string         `json:"pdf_orientation"`
+	PdfMargin          string         `json:"pdf_margin"`
+	PdfHeader          string         `json:"pdf_header" gorm:"type:text"`
+	PdfFooter          string         `json:"pdf_footer" gorm:"type:text"`
+	PdfHeaderHeight    string         `json:"pdf_header_height"`
+	PdfFooterHeight    string         `json:"pdf_footer_height"`
+	PdfPermissions     MultiString    `json:"pdf_permissions"`
+	PdfTitle           string         `json:"pdf_title"`
+	PdfAuthor          string         `json:"pdf_author"`
+	PdfSubject         string         `json:"pdf_subject"`
+	PdfKeywords        string         `json:"pdf_keywords"`
+	PdfFilename        string         `json:"pdf_filename"`
+	Translations       datatypes.JSON `json:"translations" gorm:"type:json"`
+	DefaultLocale      string         `json:"default_locale"`
+	CreatedAt          time.Time      `json:"created_at"`
+}
@@ -15,6 +15,7 @@ type Service interface {
 	GetKeyByValue(keyValue string) (*entities.Key, error)
 	ValidateKey(keyValue string) (bool, error)
 	IncreaseUsageCount(ID uint) error
+	SetPriority(ID uint, priority string) (*entities.Key, error)
 }
 
 type service struct {
@@ -91,6 +92,20 @@ func (s *service) GetKeyByValue(keyValue string) (*entities.Key, error) {
 	return key, nil
 }
 
+// SetPriority sets the queue priority of the async jobs of the key with the
+// given ID; empty is normal.
+func (s *service) SetPriority(ID uint, priority string) (*entities.Key, error) {
+	key, err := s.repository.Get(ID)
+	if err != nil {
+		return nil, err
+	}
+	key.Priority = priority
+	if err := s.repository.Update(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 // ValidateKey validates if a key is valid.
 func (s *service) ValidateKey(keyValue string) (bool, error) {
 	key, err := s.repository.GetKeyByValue(keyValue)
@@ -0,0 +1,55 @@
+package logs
+
+import (
+	"encoding/json"
+	"strings"
+
+	"gorm.io/datatypes"
+)
+
+// redactedValue replaces secrets in stored request bodies.
+const redactedValue = "[REDACTED]"
+
+// isSecretField reports whether a request body key holds a secret that must
+// never be written to a log: PDF user/owner passwords, certificate passwords.
+func isSecretField(key string) bool {
+	return strings.HasSuffix(strings.ToLower(key), "password")
+}
+
+// RedactSecrets returns body with the value of every secret field replaced,
+// at any depth. Bodies that are not JSON objects or arrays are returned as is.
+func RedactSecrets(body datatypes.JSON) datatypes.JSON {
+	var v interface{}
+	if len(body) == 0 || json.Unmarshal(body, &v) != nil {
+		return body
+	}
+	if !redact(v) {
+		return body
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redact replaces secret fields of v in place and reports whether it found any.
+func redact(v interface{}) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if isSecretField(k) {
+				v[k] = redactedValue
+				found = true
+				continue
+			}
+			found = redact(child) || found
+		}
+	case []interface{}:
+		for _, child := range v {
+			found = redact(child) || found
+		}
+	}
+	return found
+}
@@ -0,0 +1,23 @@
+package logs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	body := []byte(`{"parts":[{"data":{"name":"Ada"}}],"protection":{"user_password":"s3cret","owner_password":"0wner","permissions":["no_print"]}}`)
+	got := string(RedactSecrets(body))
+	if strings.Contains(got, "s3cret") || strings.Contains(got, "0wner") {
+		t.Fatalf("passwords not redacted: %s", got)
+	}
+	if !strings.Contains(got, `"user_password":"[REDACTED]"`) || !strings.Contains(got, `"name":"Ada"`) || !strings.Contains(got, "no_print") {
+		t.Fatalf("unexpected body: %s", got)
+	}
+
+	for _, body := range []string{`{"name":"Ada"}`, `not json`, ``} {
+		if got := string(RedactSecrets([]byte(body))); got != body {
+			t.Errorf("RedactSecrets(%q) = %q", body, got)
+		}
+	}
+}
@@ -22,7 +22,9 @@ func NewService(repo Repository) Service {
 	return &service{repo: *NewRepository(database.DB)}
 }
 
+// CreateLog stores log with the secrets of its request body redacted.
 func (s *service) CreateLog(log *entities.Log) error {
+	log.RequestBody = RedactSecrets(log.RequestBody)
 	return s.repo.CreateLog(log)
 }
 
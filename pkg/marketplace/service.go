@@ -179,12 +179,15 @@ func (s *service) CopyToNamespace(templateID, namespaceID, userID uint) (*entiti
 	}
 
 	copy := &entities.Template{
-		Name:        source.Name,
-		Content:     source.Content,
-		Framework:   source.Framework,
-		Variables:   source.Variables,
-		Fonts:       source.Fonts,
-		NamespaceID: namespaceID,
+		Name:            source.Name,
+		Content:         source.Content,
+		Framework:       source.Framework,
+		Variables:       source.Variables,
+		VariablesSchema: source.VariablesSchema,
+		Translations:    source.Translations,
+		DefaultLocale:   source.DefaultLocale,
+		Fonts:           source.Fonts,
+		NamespaceID:     namespaceID,
 	}
 	if err := s.repo.Create(copy); err != nil {
 		return nil, err
@@ -0,0 +1,210 @@
+package namespace
+
+import (
+	"designmypdf/pkg/entities"
+	"designmypdf/utils"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aymerick/raymond"
+)
+
+var (
+	ErrInvalidPartialName = errors.New("partial name must be 1 to 64 letters, digits, '_' or '-'")
+	ErrInvalidPartial     = errors.New("invalid partial")
+	ErrPartialExists      = errors.New("a partial with this name already exists in the namespace")
+	ErrPartialInUse       = errors.New("partial is used by templates of the namespace")
+)
+
+// ListPartials returns the partials of a namespace sorted by name.
+func (s *service) ListPartials(namespaceID uint) ([]entities.Partial, error) {
+	if _, err := s.repository.Get(namespaceID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListPartials(namespaceID)
+}
+
+// GetPartial returns a partial of a namespace.
+func (s *service) GetPartial(namespaceID, ID uint) (*entities.Partial, error) {
+	return s.repository.GetPartial(namespaceID, ID)
+}
+
+// CreatePartial adds a partial to a namespace. It also returns the IDs of the
+// templates calling it, whose rendering changes.
+func (s *service) CreatePartial(namespaceID uint, name, content string) (*entities.Partial, []uint, error) {
+	if _, err := s.repository.Get(namespaceID); err != nil {
+		return nil, nil, err
+	}
+	sources, err := s.repository.PartialSources(namespaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, exists := sources[name]; exists {
+		return nil, nil, ErrPartialExists
+	}
+	if err := validatePartial(sources, name, content); err != nil {
+		return nil, nil, err
+	}
+
+	partial := &entities.Partial{NamespaceID: namespaceID, Name: name, Content: content}
+	if err := s.repository.CreatePartial(partial); err != nil {
+		return nil, nil, err
+	}
+	sources[name] = content
+	dependents, err := s.dependentTemplates(namespaceID, sources, name)
+	return partial, dependents, err
+}
+
+// UpdatePartial renames and/or rewrites a partial. A partial still called by
+// a template, draft or version, cannot be renamed. It also returns the IDs of
+// the templates calling it under its old or new name.
+func (s *service) UpdatePartial(namespaceID, ID uint, name, content string) (*entities.Partial, []uint, error) {
+	partial, err := s.repository.GetPartial(namespaceID, ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	sources, err := s.repository.PartialSources(namespaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	oldName := partial.Name
+	if name != oldName {
+		callers, err := s.callingTemplates(namespaceID, sources, oldName)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(callers) > 0 {
+			return nil, nil, fmt.Errorf("%w: %v", ErrPartialInUse, callers)
+		}
+	}
+	delete(sources, oldName)
+	if _, exists := sources[name]; exists {
+		return nil, nil, ErrPartialExists
+	}
+	if err := validatePartial(sources, name, content); err != nil {
+		return nil, nil, err
+	}
+
+	partial.Name = name
+	partial.Content = content
+	if err := s.repository.UpdatePartial(partial); err != nil {
+		return nil, nil, err
+	}
+	sources[name] = content
+	dependents, err := s.dependentTemplates(namespaceID, sources, oldName, name)
+	return partial, dependents, err
+}
+
+// DeletePartial removes a partial no template of the namespace calls, in its
+// draft or in any of its versions.
+func (s *service) DeletePartial(namespaceID, ID uint) (*entities.Partial, error) {
+	partial, err := s.repository.GetPartial(namespaceID, ID)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := s.repository.PartialSources(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	callers, err := s.callingTemplates(namespaceID, sources, partial.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(callers) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrPartialInUse, callers)
+	}
+	if err := s.repository.DeletePartial(partial); err != nil {
+		return nil, err
+	}
+	return partial, nil
+}
+
+// validatePartial checks the name, the syntax and that saving the partial
+// next to others creates no {{> }} cycle.
+func validatePartial(others map[string]string, name, content string) error {
+	if !utils.PartialNamePattern.MatchString(name) {
+		return ErrInvalidPartialName
+	}
+	if _, err := raymond.Parse(content); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPartial, err)
+	}
+	candidate := make(map[string]string, len(others)+1)
+	for n, src := range others {
+		candidate[n] = src
+	}
+	candidate[name] = content
+	if err := utils.CheckPartialCycles(candidate); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPartial, err)
+	}
+	return nil
+}
+
+// dependentTemplates returns the IDs of the templates of the namespace whose
+// draft calls one of names, directly or through other partials.
+func (s *service) dependentTemplates(namespaceID uint, sources map[string]string, names ...string) ([]uint, error) {
+	templates, err := s.repository.templateSources(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := wantedNames(names)
+	var ids []uint
+	for _, t := range templates {
+		if callsAny(sources, wanted, t.Content, t.PdfHeader, t.PdfFooter) {
+			ids = append(ids, t.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// callingTemplates returns the IDs of the templates of the namespace whose
+// draft or any saved version calls one of names. Versions render the current
+// partials (see template.ResolveByUUID), so removing a partial one of them
+// calls breaks that version.
+func (s *service) callingTemplates(namespaceID uint, sources map[string]string, names ...string) ([]uint, error) {
+	ids, err := s.dependentTemplates(namespaceID, sources, names...)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.repository.versionSources(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	wanted := wantedNames(names)
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	for _, v := range versions {
+		if !seen[v.TemplateID] && callsAny(sources, wanted, v.Content, v.PdfHeader, v.PdfFooter) {
+			seen[v.TemplateID] = true
+			ids = append(ids, v.TemplateID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func wantedNames(names []string) map[string]bool {
+	wanted := make(map[string]bool, len(names))
+	for _, n := range names {
+		wanted[n] = true
+	}
+	return wanted
+}
+
+// callsAny reports whether calls, or the partials they reach, reference one of names.
+func callsAny(partials map[string]string, names map[string]bool, calls ...string) bool {
+	for _, src := range utils.ReachablePartials(partials, calls...) {
+		calls = append(calls, src)
+	}
+	for _, src := range calls {
+		for _, ref := range utils.PartialReferences(src) {
+			if names[ref] {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package namespace
+
+import (
+	"designmypdf/pkg/entities"
+	"errors"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestService returns a service on a fresh in-memory SQLite database
+// holding one namespace with a "letterhead" partial.
+func newTestService(t *testing.T) (*service, *gorm.DB, *entities.Partial) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		// go-sqlite3 needs cgo.
+		t.Skipf("SQLite unavailable: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&entities.Namespace{}, &entities.Partial{}, &entities.Template{}, &entities.TemplateVersion{}); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	if err := db.Create(&entities.Namespace{Model: gorm.Model{ID: 1}, Name: "ns"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	partial := &entities.Partial{NamespaceID: 1, Name: "letterhead", Content: "<header></header>"}
+	if err := db.Create(partial).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &service{repository: *NewRepository(db)}, db, partial
+}
+
+// createTemplate saves a template whose draft is draft and whose published
+// version 1 is published.
+func createTemplate(t *testing.T, db *gorm.DB, draft, published string) *entities.Template {
+	t.Helper()
+	tpl := &entities.Template{Name: "invoice", UUID: "tpl-uuid", NamespaceID: 1, Content: draft, LatestVersion: 1, PublishedVersion: 1}
+	if err := db.Create(tpl).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&entities.TemplateVersion{TemplateID: tpl.ID, Version: 1, Content: published}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return tpl
+}
+
+func TestDeletePartial_UsedByPublishedVersionOnly(t *testing.T) {
+	s, db, partial := newTestService(t)
+	tpl := createTemplate(t, db, "<p>draft without partial</p>", "{{> letterhead}}<p>published</p>")
+
+	if _, err := s.DeletePartial(1, partial.ID); !errors.Is(err, ErrPartialInUse) {
+		t.Fatalf("DeletePartial() err = %v, want ErrPartialInUse", err)
+	}
+	if _, _, err := s.UpdatePartial(1, partial.ID, "banner", partial.Content); !errors.Is(err, ErrPartialInUse) {
+		t.Fatalf("rename err = %v, want ErrPartialInUse", err)
+	}
+	// Thumbnails render the draft, which does not call the partial.
+	if _, dependents, err := s.UpdatePartial(1, partial.ID, "letterhead", "<header>new</header>"); err != nil || len(dependents) != 0 {
+		t.Fatalf("content update = %v, %v; want no draft dependents", dependents, err)
+	}
+
+	if err := db.Where("template_id = ?", tpl.ID).Delete(&entities.TemplateVersion{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DeletePartial(1, partial.ID); err != nil {
+		t.Errorf("DeletePartial() without callers = %v", err)
+	}
+}
+
+func TestUpdatePartial_RenameInUse(t *testing.T) {
+	s, db, partial := newTestService(t)
+	tpl := createTemplate(t, db, "{{> letterhead}}", "<p>v1</p>")
+
+	if _, _, err := s.UpdatePartial(1, partial.ID, "banner", partial.Content); !errors.Is(err, ErrPartialInUse) {
+		t.Fatalf("rename err = %v, want ErrPartialInUse", err)
+	}
+	got, dependents, err := s.UpdatePartial(1, partial.ID, "letterhead", "<header>v2</header>")
+	if err != nil || got.Content != "<header>v2</header>" || len(dependents) != 1 || dependents[0] != tpl.ID {
+		t.Fatalf("content update = %+v, %v, %v", got, dependents, err)
+	}
+
+	if err := db.Model(tpl).Update("content", "<p>no partial</p>").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, _, err := s.UpdatePartial(1, partial.ID, "banner", partial.Content); err != nil || got.Name != "banner" {
+		t.Errorf("rename without callers = %+v, %v", got, err)
+	}
+}
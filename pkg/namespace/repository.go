@@ -57,3 +57,64 @@ func (r *Repository) GetAllUserNamespaces(userID uint) (*[]entities.NamespaceLis
 	}
 	return &namespaces, nil
 }
+
+func (r *Repository) ListPartials(namespaceID uint) ([]entities.Partial, error) {
+	var partials []entities.Partial
+	if err := r.db.Where("namespace_id = ?", namespaceID).Order("name").Find(&partials).Error; err != nil {
+		return nil, err
+	}
+	return partials, nil
+}
+
+func (r *Repository) GetPartial(namespaceID, id uint) (*entities.Partial, error) {
+	var partial entities.Partial
+	if err := r.db.Where("namespace_id = ? AND id = ?", namespaceID, id).First(&partial).Error; err != nil {
+		return nil, err
+	}
+	return &partial, nil
+}
+
+func (r *Repository) CreatePartial(partial *entities.Partial) error {
+	return r.db.Create(partial).Error
+}
+
+func (r *Repository) UpdatePartial(partial *entities.Partial) error {
+	return r.db.Save(partial).Error
+}
+
+func (r *Repository) DeletePartial(partial *entities.Partial) error {
+	return r.db.Delete(partial).Error
+}
+
+// PartialSources returns the partials of a namespace by name.
+func (r *Repository) PartialSources(namespaceID uint) (map[string]string, error) {
+	partials, err := r.ListPartials(namespaceID)
+	if err != nil {
+		return nil, err
+	}
+	sources := make(map[string]string, len(partials))
+	for _, p := range partials {
+		sources[p.Name] = p.Content
+	}
+	return sources, nil
+}
+
+// versionSources returns the Handlebars sources of every saved version of the
+// templates of a namespace: any of them may be published or pinned with ?version=.
+func (r *Repository) versionSources(namespaceID uint) ([]entities.TemplateVersion, error) {
+	var versions []entities.TemplateVersion
+	err := r.db.Select("template_versions.template_id", "template_versions.content", "template_versions.pdf_header", "template_versions.pdf_footer").
+		Joins("JOIN templates ON templates.id = template_versions.template_id").
+		Where("templates.namespace_id = ? AND templates.deleted_at IS NULL", namespaceID).
+		Find(&versions).Error
+	return versions, err
+}
+
+// templateSources returns the Handlebars sources of the templates of a namespace.
+func (r *Repository) templateSources(namespaceID uint) ([]entities.Template, error) {
+	var templates []entities.Template
+	err := r.db.Select("id", "content", "pdf_header", "pdf_footer").
+		Where("namespace_id = ?", namespaceID).
+		Find(&templates).Error
+	return templates, err
+}
@@ -11,6 +11,12 @@ type Service interface {
 	Delete(ID uint) (*entities.Namespace, error)
 	GetUserNamespaces(userID uint) (*[]entities.NamespaceListItem, error)
 	Update(ID uint, name string) (*entities.Namespace, error)
+	Get(ID uint) (*entities.Namespace, error)
+	ListPartials(namespaceID uint) ([]entities.Partial, error)
+	GetPartial(namespaceID, ID uint) (*entities.Partial, error)
+	CreatePartial(namespaceID uint, name, content string) (*entities.Partial, []uint, error)
+	UpdatePartial(namespaceID, ID uint, name, content string) (*entities.Partial, []uint, error)
+	DeletePartial(namespaceID, ID uint) (*entities.Partial, error)
 }
 
 type service struct {
@@ -69,3 +75,8 @@ func (s *service) Update(ID uint, name string) (*entities.Namespace, error) {
 	}
 	return ns, nil
 }
+
+// Get retrieves the namespace with the given ID.
+func (s *service) Get(ID uint) (*entities.Namespace, error) {
+	return s.repository.Get(ID)
+}
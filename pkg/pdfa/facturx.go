@@ -0,0 +1,60 @@
+package pdfa
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FacturXFileName is the name Factur-X and ZUGFeRD readers look the invoice up by.
+const FacturXFileName = "factur-x.xml"
+
+// facturXLevels maps the guideline identifier of a Factur-X invoice to its
+// conformance level, most specific first.
+var facturXLevels = []struct{ guideline, level string }{
+	{"urn:factur-x.eu:1p0:minimum", "MINIMUM"},
+	{"urn:factur-x.eu:1p0:basicwl", "BASIC WL"},
+	{"urn:factur-x.eu:1p0:basic", "BASIC"},
+	{"urn:factur-x.eu:1p0:extended", "EXTENDED"},
+	{"urn:cen.eu:en16931:2017", "EN 16931"},
+}
+
+// facturXInvoice checks that data is a well-formed UN/CEFACT Cross Industry
+// Invoice and returns its Factur-X conformance level.
+func facturXInvoice(data []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	root := ""
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: Factur-X invoice is not well-formed XML: %v", ErrInvalidOptions, err)
+		}
+		if start, ok := tok.(xml.StartElement); ok && root == "" {
+			root = start.Name.Local
+		}
+	}
+	if root != "CrossIndustryInvoice" {
+		return "", fmt.Errorf("%w: Factur-X invoice must be a CrossIndustryInvoice document", ErrInvalidOptions)
+	}
+	for _, l := range facturXLevels {
+		if strings.Contains(string(data), l.guideline) {
+			return l.level, nil
+		}
+	}
+	return "EN 16931", nil
+}
+
+// facturXRelationship is Data for the levels that are not a complete
+// invoice, Alternative otherwise (Factur-X 1.0 specification, 7.1).
+func facturXRelationship(level string) string {
+	if level == "MINIMUM" || level == "BASIC WL" {
+		return RelationshipData
+	}
+	return RelationshipAlternative
+}
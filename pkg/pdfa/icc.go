@@ -0,0 +1,127 @@
+package pdfa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+// outputCondition names the output intent profile.
+const outputCondition = "sRGB IEC61966-2.1"
+
+var (
+	srgbOnce    sync.Once
+	srgbProfile []byte
+)
+
+// sRGBProfile returns an ICC v2 display profile of sRGB: the D50-adapted
+// primaries and the sRGB transfer curve sampled on 1024 points.
+func sRGBProfile() []byte {
+	srgbOnce.Do(func() { srgbProfile = buildSRGBProfile() })
+	return srgbProfile
+}
+
+func buildSRGBProfile() []byte {
+	trc := curveTag()
+	blocks := [][]byte{
+		descTag(outputCondition),
+		textTag("No copyright, use freely"),
+		xyzTag(0.9642, 1.0, 0.8249),
+		xyzTag(0.4360747, 0.2225045, 0.0139322),
+		xyzTag(0.3850649, 0.7168786, 0.0971045),
+		xyzTag(0.1430804, 0.0606169, 0.7141733),
+		trc,
+	}
+	// The three curves share one copy of the data.
+	tags := []struct {
+		sig   string
+		block int
+	}{
+		{"desc", 0}, {"cprt", 1}, {"wtpt", 2},
+		{"rXYZ", 3}, {"gXYZ", 4}, {"bXYZ", 5},
+		{"rTRC", 6}, {"gTRC", 6}, {"bTRC", 6},
+	}
+
+	var body bytes.Buffer
+	offsets := make([]int, len(blocks))
+	for i, b := range blocks {
+		offsets[i] = 128 + 4 + 12*len(tags) + body.Len()
+		body.Write(b)
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+	table := new(bytes.Buffer)
+	binary.Write(table, binary.BigEndian, uint32(len(tags)))
+	for _, t := range tags {
+		table.WriteString(t.sig)
+		binary.Write(table, binary.BigEndian, uint32(offsets[t.block]))
+		binary.Write(table, binary.BigEndian, uint32(len(blocks[t.block])))
+	}
+
+	size := 128 + table.Len() + body.Len()
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(size))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2000) // creation date: 2000-01-01
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	copy(header[68:], xyzNumbers(0.9642, 1.0, 0.8249)) // PCS illuminant
+
+	out := make([]byte, 0, size)
+	out = append(out, header...)
+	out = append(out, table.Bytes()...)
+	return append(out, body.Bytes()...)
+}
+
+func s15Fixed16(v float64) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(int32(math.Round(v*65536))))
+	return b
+}
+
+func xyzNumbers(x, y, z float64) []byte {
+	return append(append(s15Fixed16(x), s15Fixed16(y)...), s15Fixed16(z)...)
+}
+
+func xyzTag(x, y, z float64) []byte {
+	return append([]byte("XYZ \x00\x00\x00\x00"), xyzNumbers(x, y, z)...)
+}
+
+func textTag(s string) []byte {
+	return append([]byte("text\x00\x00\x00\x00"), append([]byte(s), 0)...)
+}
+
+// descTag is a textDescriptionType with an ASCII description only.
+func descTag(s string) []byte {
+	var b bytes.Buffer
+	b.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&b, binary.BigEndian, uint32(len(s)+1))
+	b.WriteString(s)
+	b.WriteByte(0)
+	b.Write(make([]byte, 4+4+2+1+67)) // no Unicode nor ScriptCode description
+	return b.Bytes()
+}
+
+// curveTag samples the sRGB transfer function.
+func curveTag() []byte {
+	const points = 1024
+	var b bytes.Buffer
+	b.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&b, binary.BigEndian, uint32(points))
+	for i := 0; i < points; i++ {
+		v := float64(i) / (points - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.Write(&b, binary.BigEndian, uint16(math.Round(v*65535)))
+	}
+	return b.Bytes()
+}
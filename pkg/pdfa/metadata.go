@@ -0,0 +1,133 @@
+package pdfa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// errEncrypted is returned for encrypted input: its objects cannot be
+// updated without the key.
+var errEncrypted = errors.New("the document is encrypted")
+
+// Metadata is the document information written both to the Info dictionary
+// and to the XMP packet; PDF/A requires the two to agree.
+type Metadata struct {
+	Title   string
+	Author  string
+	Subject string
+	// Keywords is a comma-separated list.
+	Keywords string
+	// Creator is the application that created the content (empty is
+	// designmypdf).
+	Creator string
+	// Date is the creation date; zero is now.
+	Date time.Time
+}
+
+func (m Metadata) withDefaults() Metadata {
+	if m.Creator == "" {
+		m.Creator = producer
+	}
+	if m.Date.IsZero() {
+		m.Date = time.Now()
+	}
+	m.Date = m.Date.UTC().Truncate(time.Second)
+	return m
+}
+
+// keywords splits Keywords into its trimmed, non-empty entries.
+func (m Metadata) keywords() []string {
+	var list []string
+	for _, k := range strings.Split(m.Keywords, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			list = append(list, k)
+		}
+	}
+	return list
+}
+
+// SetMetadata writes meta to the Info dictionary and the XMP metadata of pdf.
+// Like Convert, it rewrites the document with a classic cross-reference table
+// and appends the metadata as an incremental update.
+func SetMetadata(pdf []byte, meta Metadata) ([]byte, error) {
+	meta = meta.withDefaults()
+	doc, err := openDocument(pdf)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := doc.ctx.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF catalog: %w", err)
+	}
+	metadata := doc.u.addStream(types.Dict{"Type": types.Name("Metadata"), "Subtype": types.Name("XML")},
+		xmpPacket(0, meta, ""))
+	catalog.Update("Metadata", metadata)
+	doc.u.put(*doc.ctx.Root, catalog)
+	return doc.write(meta), nil
+}
+
+var startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+
+// document is a normalized PDF and the incremental update appended to it.
+type document struct {
+	ctx      *model.Context
+	u        *update
+	prevXRef int
+}
+
+// openDocument normalizes pdf and starts its incremental update.
+func openDocument(pdf []byte) (*document, error) {
+	base, err := normalize(pdf)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := api.ReadContext(bytes.NewReader(base), model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if ctx.Encrypt != nil {
+		return nil, errEncrypted
+	}
+	if ctx.Root == nil || ctx.Size == nil || len(ctx.ID) == 0 {
+		return nil, errors.New("failed to read PDF: incomplete trailer")
+	}
+	m := startXRefPattern.FindAllSubmatch(base, -1)
+	if len(m) == 0 {
+		return nil, errors.New("failed to read PDF: missing startxref")
+	}
+	prevXRef, _ := strconv.Atoi(string(m[len(m)-1][1]))
+	return &document{ctx: ctx, u: newUpdate(base, *ctx.Size), prevXRef: prevXRef}, nil
+}
+
+// write appends a new Info dictionary holding meta and returns the updated
+// document.
+func (d *document) write(meta Metadata) []byte {
+	info := types.Dict{
+		"Producer":     types.StringLiteral(producer),
+		"Creator":      textString(meta.Creator),
+		"CreationDate": types.StringLiteral(types.DateString(meta.Date)),
+		"ModDate":      types.StringLiteral(types.DateString(meta.Date)),
+	}
+	for key, value := range map[string]string{
+		"Title":    meta.Title,
+		"Author":   meta.Author,
+		"Subject":  meta.Subject,
+		"Keywords": strings.Join(meta.keywords(), ", "),
+	} {
+		if value != "" {
+			info[key] = textString(value)
+		}
+	}
+	infoRef := d.u.add(info)
+	return d.u.write(fmt.Sprintf("<</Size %d /Root %s /Info %s /Prev %d /ID %s>>",
+		d.u.size, d.ctx.Root.PDFString(), infoRef.PDFString(), d.prevXRef, d.ctx.ID.PDFString()))
+}
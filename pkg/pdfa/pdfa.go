@@ -0,0 +1,338 @@
+// Package pdfa turns rendered PDFs into PDF/A-2b or PDF/A-3b archival
+// documents: XMP metadata, an sRGB output intent and, for PDF/A-3, embedded
+// files such as the source data or a Factur-X invoice. SetMetadata writes the
+// same document information to PDFs that are not archived.
+package pdfa
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+var (
+	// ErrInvalidOptions is returned for options that can never be applied.
+	ErrInvalidOptions = errors.New("invalid PDF/A options")
+	// ErrNotConformant is returned when the document itself cannot be made
+	// conformant, e.g. a font is not embedded or the file is encrypted.
+	ErrNotConformant = errors.New("PDF cannot be made PDF/A conformant")
+)
+
+// Supported conformance levels.
+const (
+	Conformance2B = "pdfa-2b"
+	Conformance3B = "pdfa-3b"
+)
+
+// Relationships of an embedded file to the document (/AFRelationship).
+const (
+	RelationshipSource      = "Source"
+	RelationshipData        = "Data"
+	RelationshipAlternative = "Alternative"
+)
+
+// producer is written to the Info dictionary and the XMP metadata.
+const producer = "designmypdf"
+
+// Attachment is a file embedded in a PDF/A-3 document.
+type Attachment struct {
+	Name         string
+	MimeType     string
+	Description  string
+	Relationship string
+	Data         []byte
+}
+
+// Options describe the conversion applied by Convert.
+type Options struct {
+	// Conformance is Conformance2B or Conformance3B.
+	Conformance string
+	// Metadata is the document information of the archived document.
+	Metadata
+	// Attachments are embedded files (PDF/A-3 only).
+	Attachments []Attachment
+	// FacturX is a Factur-X/ZUGFeRD invoice (CrossIndustryInvoice XML)
+	// embedded as factur-x.xml with its XMP properties (PDF/A-3 only).
+	FacturX []byte
+}
+
+// ParseConformance normalizes a conformance level such as "pdfa-3b",
+// "PDF/A-3b" or "3b"; empty stays empty.
+func ParseConformance(s string) (string, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.NewReplacer("pdf/a", "", "pdfa", "", "-", "", "_", "", " ", "").Replace(v)
+	switch v {
+	case "":
+		return "", nil
+	case "2b":
+		return Conformance2B, nil
+	case "3b":
+		return Conformance3B, nil
+	}
+	return "", fmt.Errorf("%w: unsupported conformance %q (want %s or %s)", ErrInvalidOptions, s, Conformance2B, Conformance3B)
+}
+
+// Validate reports options that can never be applied.
+func (o Options) Validate() error {
+	switch o.Conformance {
+	case Conformance2B:
+		if len(o.Attachments) > 0 || len(o.FacturX) > 0 {
+			return fmt.Errorf("%w: embedded files require %s", ErrInvalidOptions, Conformance3B)
+		}
+	case Conformance3B:
+	default:
+		return fmt.Errorf("%w: unsupported conformance %q (want %s or %s)", ErrInvalidOptions, o.Conformance, Conformance2B, Conformance3B)
+	}
+	for _, a := range o.Attachments {
+		if a.Name == "" || a.MimeType == "" {
+			return fmt.Errorf("%w: attachments need a name and a MIME type", ErrInvalidOptions)
+		}
+		switch a.Relationship {
+		case RelationshipSource, RelationshipData, RelationshipAlternative:
+		default:
+			return fmt.Errorf("%w: unsupported attachment relationship %q", ErrInvalidOptions, a.Relationship)
+		}
+	}
+	return nil
+}
+
+// Convert makes pdf a PDF/A document of the requested conformance. Fonts must
+// already be embedded, which Chrome always does. The document is rewritten
+// with a classic cross-reference table, then the metadata, output intent,
+// annotation flags and embedded files are appended as an incremental update,
+// so a later signature can be added the same way.
+func Convert(pdf []byte, opts Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	part := 2
+	if opts.Conformance == Conformance3B {
+		part = 3
+	}
+	attachments := append([]Attachment{}, opts.Attachments...)
+	facturXLevel := ""
+	if len(opts.FacturX) > 0 {
+		level, err := facturXInvoice(opts.FacturX)
+		if err != nil {
+			return nil, err
+		}
+		facturXLevel = level
+		attachments = append(attachments, Attachment{
+			Name:         FacturXFileName,
+			MimeType:     "text/xml",
+			Description:  "Factur-X invoice",
+			Relationship: facturXRelationship(level),
+			Data:         opts.FacturX,
+		})
+	}
+	meta := opts.Metadata.withDefaults()
+
+	doc, err := openDocument(pdf)
+	if errors.Is(err, errEncrypted) {
+		return nil, fmt.Errorf("%w: %v", ErrNotConformant, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ctx, u := doc.ctx, doc.u
+	if err := ctx.EnsurePageCount(); err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if err := checkFonts(ctx); err != nil {
+		return nil, err
+	}
+	if err := fixAnnotations(ctx, u); err != nil {
+		return nil, err
+	}
+
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF catalog: %w", err)
+	}
+	metadata := u.addStream(types.Dict{"Type": types.Name("Metadata"), "Subtype": types.Name("XML")},
+		xmpPacket(part, meta, facturXLevel))
+	profile := u.addStream(types.Dict{"N": types.Integer(3)}, sRGBProfile())
+	catalog.Update("Metadata", metadata)
+	catalog.Update("OutputIntents", types.Array{types.Dict{
+		"Type":                      types.Name("OutputIntent"),
+		"S":                         types.Name("GTS_PDFA1"),
+		"OutputConditionIdentifier": types.StringLiteral(outputCondition),
+		"Info":                      types.StringLiteral(outputCondition),
+		"DestOutputProfile":         profile,
+	}})
+	if len(attachments) > 0 {
+		if err := embedFiles(ctx, u, catalog, attachments, meta.Date); err != nil {
+			return nil, err
+		}
+	}
+	u.put(*ctx.Root, catalog)
+	return doc.write(meta), nil
+}
+
+// checkFonts reports fonts whose program is not embedded: PDF/A forbids
+// them and they cannot be embedded after rendering.
+func checkFonts(ctx *model.Context) error {
+	for nr, entry := range ctx.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		d, ok := entry.Object.(types.Dict)
+		if !ok || d.Type() == nil || *d.Type() != "FontDescriptor" {
+			continue
+		}
+		if d["FontFile"] == nil && d["FontFile2"] == nil && d["FontFile3"] == nil {
+			name := "object " + strconv.Itoa(nr)
+			if n := d.NameEntry("FontName"); n != nil {
+				name = *n
+			}
+			return fmt.Errorf("%w: font %s is not embedded", ErrNotConformant, name)
+		}
+	}
+	return nil
+}
+
+// fixAnnotations sets the Print flag and clears the hiding flags of every
+// annotation, as PDF/A requires.
+func fixAnnotations(ctx *model.Context, u *update) error {
+	const (
+		invisible = 1 << 0
+		hidden    = 1 << 1
+		print     = 1 << 2
+		noView    = 1 << 5
+		toggle    = 1 << 8
+	)
+	fix := func(d types.Dict) bool {
+		if st := d.Subtype(); st != nil && *st == "Popup" {
+			return false
+		}
+		flags := 0
+		if f := d.IntEntry("F"); f != nil {
+			flags = *f
+		}
+		want := (flags | print) &^ (invisible | hidden | noView | toggle)
+		if d["F"] != nil && want == flags {
+			return false
+		}
+		d.Update("F", types.Integer(want))
+		return true
+	}
+
+	for nr := 1; nr <= ctx.PageCount; nr++ {
+		pageDict, pageRef, _, err := ctx.PageDict(nr, false)
+		if err != nil || pageDict == nil || pageRef == nil {
+			return fmt.Errorf("failed to read page %d: %v", nr, err)
+		}
+		annots, err := ctx.DereferenceArray(pageDict["Annots"])
+		if err != nil {
+			return fmt.Errorf("failed to read annotations of page %d: %w", nr, err)
+		}
+		pageChanged := false
+		for _, a := range annots {
+			switch v := a.(type) {
+			case types.IndirectRef:
+				d, err := ctx.DereferenceDict(v)
+				if err != nil {
+					return fmt.Errorf("failed to read annotation %s: %w", v, err)
+				}
+				if d != nil && fix(d) {
+					u.put(v, d)
+				}
+			case types.Dict:
+				pageChanged = fix(v) || pageChanged
+			}
+		}
+		if pageChanged {
+			pageDict.Update("Annots", annots)
+			u.put(*pageRef, pageDict)
+		}
+	}
+	return nil
+}
+
+// embedFiles adds the attachments as associated files of the document: an
+// embedded file stream and a file specification each, listed both in the
+// EmbeddedFiles name tree and in the catalog /AF array.
+func embedFiles(ctx *model.Context, u *update, catalog types.Dict, attachments []Attachment, date time.Time) error {
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Name < attachments[j].Name })
+	names := types.Array{}
+	associated := types.Array{}
+	for _, a := range attachments {
+		file := u.addStream(types.Dict{
+			"Type":    types.Name("EmbeddedFile"),
+			"Subtype": types.Name(a.MimeType),
+			"Params": types.Dict{
+				"Size":    types.Integer(len(a.Data)),
+				"ModDate": types.StringLiteral(types.DateString(date)),
+			},
+		}, a.Data)
+		spec := types.Dict{
+			"Type":           types.Name("Filespec"),
+			"F":              textString(a.Name),
+			"UF":             textString(a.Name),
+			"AFRelationship": types.Name(a.Relationship),
+			"EF":             types.Dict{"F": file, "UF": file},
+		}
+		if a.Description != "" {
+			spec["Desc"] = textString(a.Description)
+		}
+		ref := u.add(spec)
+		names = append(names, textString(a.Name), ref)
+		associated = append(associated, ref)
+	}
+
+	nameTree, err := ctx.DereferenceDict(catalog["Names"])
+	if err != nil {
+		return fmt.Errorf("failed to read name dictionary: %w", err)
+	}
+	if nameTree == nil {
+		nameTree = types.Dict{}
+	}
+	nameTree.Update("EmbeddedFiles", types.Dict{"Names": names})
+	if ref, ok := catalog["Names"].(types.IndirectRef); ok {
+		u.put(ref, nameTree)
+	} else {
+		catalog.Update("Names", nameTree)
+	}
+	catalog.Update("AF", associated)
+	return nil
+}
+
+// textString encodes s as a PDF text string: a literal string for ASCII,
+// UTF-16BE otherwise.
+func textString(s string) types.Object {
+	for _, r := range s {
+		if r < 32 || r > 126 {
+			return types.NewHexLiteral([]byte(types.EncodeUTF16String(s)))
+		}
+	}
+	escaped, err := types.Escape(s)
+	if err != nil {
+		return types.NewHexLiteral([]byte(s))
+	}
+	return types.StringLiteral(*escaped)
+}
+
+// normalize rewrites pdf without object and cross-reference streams, so the
+// incremental update can use a classic cross-reference table.
+func normalize(pdf []byte) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.WriteObjectStream = false
+	conf.WriteXRefStream = false
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		return nil, fmt.Errorf("failed to rewrite PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,182 @@
+package pdfa
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// testPDF is a one-page PDF with a link annotation and the given font
+// descriptor, with a classic cross-reference table.
+func testPDF(fontDescriptor string) []byte {
+	objects := []string{
+		"<</Type /Catalog /Pages 2 0 R>>",
+		"<</Type /Pages /Kids [3 0 R] /Count 1>>",
+		"<</Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources <</Font <</F1 5 0 R>>>> /Contents 4 0 R /Annots [<</Type /Annot /Subtype /Link /Rect [0 0 10 10] /Border [0 0 0]>>]>>",
+		"<</Length 35>>\nstream\nBT /F1 12 Tf 72 720 Td (Hi) Tj ET\nendstream",
+		"<</Type /Font /Subtype /TrueType /BaseFont /Arial /FirstChar 32 /LastChar 32 /Widths [278] /FontDescriptor 6 0 R>>",
+		fontDescriptor,
+		"<</Length 4 /Length1 4>>\nstream\nfont\nendstream",
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<</Size %d /Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+const embeddedFont = "<</Type /FontDescriptor /FontName /Arial /Flags 32 /FontBBox [0 0 1000 1000] /ItalicAngle 0 /Ascent 900 /Descent -200 /CapHeight 700 /StemV 80 /FontFile2 7 0 R>>"
+
+const facturX = `<?xml version="1.0" encoding="UTF-8"?>
+<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100" xmlns:ram="urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100">
+<rsm:ExchangedDocumentContext><ram:GuidelineSpecifiedDocumentContextParameter><ram:ID>urn:factur-x.eu:1p0:minimum</ram:ID></ram:GuidelineSpecifiedDocumentContextParameter></rsm:ExchangedDocumentContext>
+</rsm:CrossIndustryInvoice>`
+
+func TestConvert(t *testing.T) {
+	date := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	out, err := Convert(testPDF(embeddedFont), Options{
+		Conformance: Conformance3B,
+		Metadata:    Metadata{Title: "Facture n°42", Date: date},
+		Attachments: []Attachment{{Name: "payload.json", MimeType: "application/json", Relationship: RelationshipSource, Data: []byte(`{"total":42}`)}},
+		FacturX:     []byte(facturX),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err := api.ReadContext(bytes.NewReader(out), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.ValidateContext(ctx); err != nil {
+		t.Fatalf("converted PDF is invalid: %v", err)
+	}
+	catalog, _ := ctx.Catalog()
+	for _, key := range []string{"Metadata", "OutputIntents", "AF", "Names"} {
+		if catalog[key] == nil {
+			t.Errorf("catalog has no /%s", key)
+		}
+	}
+	if af, _ := ctx.DereferenceArray(catalog["AF"]); len(af) != 2 {
+		t.Errorf("AF = %v, want 2 associated files", af)
+	}
+
+	for _, want := range []string{
+		"<pdfaid:part>3</pdfaid:part>",
+		"<pdfaid:conformance>B</pdfaid:conformance>",
+		"<xmp:CreateDate>2026-03-01T10:30:00Z</xmp:CreateDate>",
+		"Facture n°42",
+		"<fx:ConformanceLevel>MINIMUM</fx:ConformanceLevel>",
+		"/AFRelationship/Data",
+		"/AFRelationship/Source",
+		"/Subtype/application#2fjson",
+		"/CreationDate(D:20260301103000+00'00')",
+		"/S/GTS_PDFA1",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	page, _, _, _ := ctx.PageDict(1, false)
+	annots, _ := ctx.DereferenceArray(page["Annots"])
+	if f := annots[0].(types.Dict).IntEntry("F"); f == nil || *f&4 == 0 {
+		t.Errorf("link annotation flags = %v, want Print", f)
+	}
+}
+
+func TestConvertRejects(t *testing.T) {
+	missingFont := strings.Replace(embeddedFont, " /FontFile2 7 0 R", "", 1)
+	if _, err := Convert(testPDF(missingFont), Options{Conformance: Conformance2B}); !errors.Is(err, ErrNotConformant) {
+		t.Errorf("non-embedded font: err = %v", err)
+	}
+	for _, opts := range []Options{
+		{Conformance: "pdfa-1b"},
+		{Conformance: Conformance2B, FacturX: []byte(facturX)},
+		{Conformance: Conformance3B, FacturX: []byte("<Invoice/>")},
+	} {
+		if _, err := Convert(testPDF(embeddedFont), opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("Convert(%s) err = %v", opts.Conformance, err)
+		}
+	}
+}
+
+func TestSetMetadata(t *testing.T) {
+	out, err := SetMetadata(testPDF(embeddedFont), Metadata{
+		Title:    "Invoice 42",
+		Author:   "ACME & Co",
+		Subject:  "Monthly invoice",
+		Keywords: "invoice, , acme",
+		Date:     time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := api.ReadContext(bytes.NewReader(out), model.NewDefaultConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := api.ValidateContext(ctx); err != nil {
+		t.Fatalf("updated PDF is invalid: %v", err)
+	}
+	for _, want := range []string{
+		"/Author(ACME & Co)",
+		"/Keywords(invoice, acme)",
+		"/Subject(Monthly invoice)",
+		"/Title(Invoice 42)",
+		"/Producer(designmypdf)",
+		"<dc:creator><rdf:Seq><rdf:li>ACME &amp; Co</rdf:li></rdf:Seq></dc:creator>",
+		"<dc:subject><rdf:Bag><rdf:li>invoice</rdf:li><rdf:li>acme</rdf:li></rdf:Bag></dc:subject>",
+		"<pdf:Keywords>invoice, acme</pdf:Keywords>",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+	if bytes.Contains(out, []byte("pdfaid")) {
+		t.Error("plain metadata claims PDF/A conformance")
+	}
+}
+
+func TestParseConformance(t *testing.T) {
+	for in, want := range map[string]string{"pdfa-2b": Conformance2B, "PDF/A-3b": Conformance3B, "3B": Conformance3B, "": ""} {
+		if got, err := ParseConformance(in); err != nil || got != want {
+			t.Errorf("ParseConformance(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseConformance("pdfa-1a"); err == nil {
+		t.Error("pdfa-1a should be rejected")
+	}
+}
+
+func TestSRGBProfile(t *testing.T) {
+	p := sRGBProfile()
+	if int(binary.BigEndian.Uint32(p)) != len(p) || string(p[36:40]) != "acsp" || string(p[12:16]) != "mntr" {
+		t.Fatalf("invalid ICC header: % x", p[:40])
+	}
+	count := int(binary.BigEndian.Uint32(p[128:]))
+	for i := 0; i < count; i++ {
+		entry := p[132+12*i:]
+		offset, size := binary.BigEndian.Uint32(entry[4:]), binary.BigEndian.Uint32(entry[8:])
+		if offset%4 != 0 || int(offset+size) > len(p) {
+			t.Errorf("tag %s is out of bounds", entry[:4])
+		}
+	}
+}
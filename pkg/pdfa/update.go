@@ -0,0 +1,92 @@
+package pdfa
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// updateObject is an object written by an incremental update; stream is
+// nil for plain dictionaries.
+type updateObject struct {
+	ref    types.IndirectRef
+	dict   types.Dict
+	stream []byte
+}
+
+// update collects the objects of an incremental update of base, a PDF with
+// a classic cross-reference table, and size objects.
+type update struct {
+	base    []byte
+	size    int
+	objects []updateObject
+}
+
+func newUpdate(base []byte, size int) *update {
+	return &update{base: base, size: size}
+}
+
+// add appends a new dictionary object and returns its reference.
+func (u *update) add(d types.Dict) types.IndirectRef {
+	ref := *types.NewIndirectRef(u.size, 0)
+	u.size++
+	u.objects = append(u.objects, updateObject{ref: ref, dict: d})
+	return ref
+}
+
+// addStream appends a new unfiltered stream object and returns its reference.
+func (u *update) addStream(d types.Dict, data []byte) types.IndirectRef {
+	ref := u.add(d)
+	u.objects[len(u.objects)-1].stream = data
+	return ref
+}
+
+// put replaces the existing object ref with d.
+func (u *update) put(ref types.IndirectRef, d types.Dict) {
+	for i, o := range u.objects {
+		if o.ref.ObjectNumber == ref.ObjectNumber {
+			u.objects[i].dict = d
+			return
+		}
+	}
+	u.objects = append(u.objects, updateObject{ref: ref, dict: d})
+}
+
+// write returns base followed by the update objects, their cross-reference
+// section and trailer.
+func (u *update) write(trailer string) []byte {
+	var out bytes.Buffer
+	out.Write(u.base)
+	if !bytes.HasSuffix(u.base, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	offsets := map[int]int{}
+	gens := map[int]int{}
+	for _, o := range u.objects {
+		nr, gen := o.ref.ObjectNumber.Value(), o.ref.GenerationNumber.Value()
+		offsets[nr], gens[nr] = out.Len(), gen
+		if o.stream != nil {
+			o.dict.Update("Length", types.Integer(len(o.stream)))
+			fmt.Fprintf(&out, "%d %d obj\n%s\nstream\n", nr, gen, o.dict.PDFString())
+			out.Write(o.stream)
+			out.WriteString("\nendstream\nendobj\n")
+			continue
+		}
+		fmt.Fprintf(&out, "%d %d obj\n%s\nendobj\n", nr, gen, o.dict.PDFString())
+	}
+
+	xrefOffset := out.Len()
+	out.WriteString("xref\n")
+	numbers := make([]int, 0, len(offsets))
+	for nr := range offsets {
+		numbers = append(numbers, nr)
+	}
+	sort.Ints(numbers)
+	for _, nr := range numbers {
+		fmt.Fprintf(&out, "%d 1\n%010d %05d n \n", nr, offsets[nr], gens[nr])
+	}
+	fmt.Fprintf(&out, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer, xrefOffset)
+	return out.Bytes()
+}
@@ -0,0 +1,119 @@
+package pdfa
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// facturXNamespace is the XMP namespace of the Factur-X (ZUGFeRD 2) properties.
+const facturXNamespace = "urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#"
+
+// xmpPacket returns the XMP metadata of a PDF/A document of the given part
+// (2 or 3), conformance level B, with the Factur-X properties and their
+// extension schema when facturXLevel is set. Part 0 is a document that
+// claims no PDF/A conformance.
+func xmpPacket(part int, meta Metadata, facturXLevel string) []byte {
+	var b bytes.Buffer
+	b.WriteString("<?xpacket begin=\"\uFEFF\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	b.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">` + "\n")
+	b.WriteString(`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` + "\n")
+
+	if part > 0 {
+		fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+<pdfaid:part>%d</pdfaid:part>
+<pdfaid:conformance>B</pdfaid:conformance>
+</rdf:Description>
+`, part)
+	}
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	b.WriteString("<dc:format>application/pdf</dc:format>\n")
+	if meta.Title != "" {
+		fmt.Fprintf(&b, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", xmlText(meta.Title))
+	}
+	if meta.Author != "" {
+		fmt.Fprintf(&b, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", xmlText(meta.Author))
+	}
+	if meta.Subject != "" {
+		fmt.Fprintf(&b, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", xmlText(meta.Subject))
+	}
+	keywords := meta.keywords()
+	if len(keywords) > 0 {
+		b.WriteString("<dc:subject><rdf:Bag>")
+		for _, k := range keywords {
+			fmt.Fprintf(&b, "<rdf:li>%s</rdf:li>", xmlText(k))
+		}
+		b.WriteString("</rdf:Bag></dc:subject>\n")
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	date := meta.Date.Format(time.RFC3339)
+	fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+<xmp:CreatorTool>%s</xmp:CreatorTool>
+<xmp:CreateDate>%s</xmp:CreateDate>
+<xmp:ModifyDate>%s</xmp:ModifyDate>
+<xmp:MetadataDate>%s</xmp:MetadataDate>
+</rdf:Description>
+`, xmlText(meta.Creator), date, date, date)
+
+	fmt.Fprintf(&b, `<rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
+<pdf:Producer>%s</pdf:Producer>
+`, producer)
+	if len(keywords) > 0 {
+		fmt.Fprintf(&b, "<pdf:Keywords>%s</pdf:Keywords>\n", xmlText(strings.Join(keywords, ", ")))
+	}
+	b.WriteString("</rdf:Description>\n")
+
+	if facturXLevel != "" {
+		writeFacturX(&b, facturXLevel)
+	}
+
+	b.WriteString("</rdf:RDF>\n</x:xmpmeta>\n")
+	// Padding lets editors update the packet in place.
+	b.WriteString(strings.Repeat(strings.Repeat(" ", 99)+"\n", 20))
+	b.WriteString(`<?xpacket end="w"?>`)
+	return b.Bytes()
+}
+
+// writeFacturX writes the Factur-X properties and the PDF/A extension schema
+// that declares them.
+func writeFacturX(b *bytes.Buffer, level string) {
+	fmt.Fprintf(b, `<rdf:Description rdf:about="" xmlns:fx="%s">
+<fx:DocumentType>INVOICE</fx:DocumentType>
+<fx:DocumentFileName>%s</fx:DocumentFileName>
+<fx:Version>1.0</fx:Version>
+<fx:ConformanceLevel>%s</fx:ConformanceLevel>
+</rdf:Description>
+`, facturXNamespace, FacturXFileName, xmlText(level))
+
+	b.WriteString(`<rdf:Description rdf:about="" xmlns:pdfaExtension="http://www.aiim.org/pdfa/ns/extension/" xmlns:pdfaSchema="http://www.aiim.org/pdfa/ns/schema#" xmlns:pdfaProperty="http://www.aiim.org/pdfa/ns/property#">
+<pdfaExtension:schemas><rdf:Bag><rdf:li rdf:parseType="Resource">
+<pdfaSchema:schema>Factur-X PDFA Extension Schema</pdfaSchema:schema>
+`)
+	fmt.Fprintf(b, "<pdfaSchema:namespaceURI>%s</pdfaSchema:namespaceURI>\n", facturXNamespace)
+	b.WriteString("<pdfaSchema:prefix>fx</pdfaSchema:prefix>\n<pdfaSchema:property><rdf:Seq>\n")
+	for _, p := range [][2]string{
+		{"DocumentFileName", "The name of the embedded XML document"},
+		{"DocumentType", "The type of the hybrid document in capital letters, e.g. INVOICE or ORDER"},
+		{"Version", "The actual version of the standard applying to the embedded XML document"},
+		{"ConformanceLevel", "The conformance level of the embedded XML document"},
+	} {
+		fmt.Fprintf(b, `<rdf:li rdf:parseType="Resource">
+<pdfaProperty:name>%s</pdfaProperty:name>
+<pdfaProperty:valueType>Text</pdfaProperty:valueType>
+<pdfaProperty:category>external</pdfaProperty:category>
+<pdfaProperty:description>%s</pdfaProperty:description>
+</rdf:li>
+`, p[0], p[1])
+	}
+	b.WriteString("</rdf:Seq></pdfaSchema:property>\n</rdf:li></rdf:Bag></pdfaExtension:schemas>\n</rdf:Description>\n")
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/pdfa"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Files embedded in a PDF/A-3 document (GenerateOptions.Attach).
+const (
+	// AttachPayload embeds the generation data as payload.json.
+	AttachPayload = "payload"
+	// AttachFacturX embeds the Factur-X/ZUGFeRD invoice XML given in the
+	// FacturXField payload field as factur-x.xml.
+	AttachFacturX = "factur-x"
+	FacturXField  = "factur_x"
+)
+
+// ParseAttachments reads a comma-separated attachment list such as
+// "payload,factur-x".
+func ParseAttachments(value string) []string {
+	var attach []string
+	for _, a := range strings.Split(value, ",") {
+		if a = strings.ToLower(strings.TrimSpace(a)); a != "" {
+			attach = append(attach, a)
+		}
+	}
+	return attach
+}
+
+// validateArchive reports a conformance level or attachments that can never
+// be applied, and options PDF/A forbids.
+func validateArchive(conformance string, attach []string, protect *ProtectOptions, sign *SignOptions) error {
+	if conformance == "" {
+		if len(attach) > 0 {
+			return fmt.Errorf("%w: attach requires conformance=%s", pdfa.ErrInvalidOptions, pdfa.Conformance3B)
+		}
+		return nil
+	}
+	if normalized, err := pdfa.ParseConformance(conformance); err != nil {
+		return err
+	} else if normalized != conformance {
+		return fmt.Errorf("%w: unsupported conformance %q", pdfa.ErrInvalidOptions, conformance)
+	}
+	for _, a := range attach {
+		if a != AttachPayload && a != AttachFacturX {
+			return fmt.Errorf("%w: unknown attachment %q (want %s or %s)", pdfa.ErrInvalidOptions, a, AttachPayload, AttachFacturX)
+		}
+	}
+	if len(attach) > 0 && conformance != pdfa.Conformance3B {
+		return fmt.Errorf("%w: embedded files require %s", pdfa.ErrInvalidOptions, pdfa.Conformance3B)
+	}
+	if protect.active() {
+		return errArchivedProtection
+	}
+	if sign != nil && sign.Visible {
+		// The signature appearance uses the non-embedded Helvetica font.
+		return fmt.Errorf("%w: visible signatures are not PDF/A conformant", pdfa.ErrInvalidOptions)
+	}
+	return nil
+}
+
+// archiveOptions returns the PDF/A conversion of a document described by meta
+// and generated from payload, or nil when no conformance is requested.
+// facturX is the invoice XML of AttachFacturX.
+func archiveOptions(conformance string, attach []string, meta pdfa.Metadata, payload interface{}, facturX interface{}) (*pdfa.Options, error) {
+	if conformance == "" {
+		return nil, nil
+	}
+	opts := &pdfa.Options{Conformance: conformance, Metadata: meta}
+	for _, a := range attach {
+		switch a {
+		case AttachPayload:
+			data, err := json.Marshal(payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode payload attachment: %w", err)
+			}
+			opts.Attachments = append(opts.Attachments, pdfa.Attachment{
+				Name:         "payload.json",
+				MimeType:     "application/json",
+				Description:  "Data the document was generated from",
+				Relationship: pdfa.RelationshipSource,
+				Data:         data,
+			})
+		case AttachFacturX:
+			xml, ok := facturX.(string)
+			if !ok || strings.TrimSpace(xml) == "" {
+				return nil, fmt.Errorf("%w: attach=%s needs the invoice XML in the %q payload field", pdfa.ErrInvalidOptions, AttachFacturX, FacturXField)
+			}
+			opts.FacturX = []byte(xml)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,42 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/pdfa"
+	"errors"
+	"testing"
+)
+
+func TestValidateArchive(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts GenerateOptions
+	}{
+		{"attach without conformance", GenerateOptions{Attach: []string{AttachPayload}}},
+		{"attach on PDF/A-2", GenerateOptions{Conformance: pdfa.Conformance2B, Attach: []string{AttachPayload}}},
+		{"unknown attachment", GenerateOptions{Conformance: pdfa.Conformance3B, Attach: []string{"invoice.pdf"}}},
+		{"encrypted", GenerateOptions{Conformance: pdfa.Conformance2B, Protect: &ProtectOptions{UserPassword: "x"}}},
+		{"visible signature", GenerateOptions{Conformance: pdfa.Conformance2B, Sign: &SignOptions{Visible: true}}},
+	} {
+		if err := tt.opts.Validate(); err == nil {
+			t.Errorf("%s: Validate() should fail", tt.name)
+		}
+	}
+	ok := GenerateOptions{Conformance: pdfa.Conformance3B, Attach: []string{AttachPayload, AttachFacturX}, Sign: &SignOptions{}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
+
+func TestArchiveOptions(t *testing.T) {
+	data := map[string]interface{}{"total": 42}
+	if _, err := archiveOptions(pdfa.Conformance3B, []string{AttachFacturX}, pdfa.Metadata{Title: "Invoice"}, data, data[FacturXField]); !errors.Is(err, pdfa.ErrInvalidOptions) {
+		t.Errorf("missing Factur-X XML: err = %v", err)
+	}
+	opts, err := archiveOptions(pdfa.Conformance3B, []string{AttachPayload}, pdfa.Metadata{Title: "Invoice"}, data, nil)
+	if err != nil || len(opts.Attachments) != 1 || string(opts.Attachments[0].Data) != `{"total":42}` || opts.Title != "Invoice" {
+		t.Errorf("archiveOptions() = %+v, %v", opts, err)
+	}
+	if opts, err := archiveOptions("", nil, pdfa.Metadata{Title: "Invoice"}, data, nil); opts != nil || err != nil {
+		t.Errorf("no conformance: archiveOptions() = %+v, %v", opts, err)
+	}
+}
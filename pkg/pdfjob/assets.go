@@ -0,0 +1,61 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/pkg/assets"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// serveLocalAssets pauses every request of the tab and answers CDN URLs
+// (Tailwind, Bootstrap, highlight.js, Google Fonts) from the asset store.
+// Misses go to the network, or are blocked when ASSETS_OFFLINE=true.
+// Must run before navigation.
+func serveLocalAssets(tabCtx context.Context) chromedp.Action {
+	store := assets.Default()
+
+	chromedp.ListenTarget(tabCtx, func(ev interface{}) {
+		e, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+		go func() {
+			c := chromedp.FromContext(tabCtx)
+			if c == nil || c.Target == nil {
+				return
+			}
+			ctx := cdp.WithExecutor(tabCtx, c.Target)
+
+			var err error
+			if body, contentType, found := store.Lookup(e.Request.URL); found {
+				err = fetch.FulfillRequest(e.RequestID, 200).
+					WithResponseHeaders([]*fetch.HeaderEntry{
+						{Name: "Content-Type", Value: contentType},
+						{Name: "Access-Control-Allow-Origin", Value: "*"},
+						{Name: "Cache-Control", Value: "public, max-age=31536000"},
+					}).
+					WithBody(base64.StdEncoding.EncodeToString(body)).
+					Do(ctx)
+			} else if store.Offline() && isNetworkURL(e.Request.URL) {
+				err = fetch.FailRequest(e.RequestID, network.ErrorReasonBlockedByClient).Do(ctx)
+			} else {
+				err = fetch.ContinueRequest(e.RequestID).Do(ctx)
+			}
+			if err != nil && ctx.Err() == nil {
+				fmt.Printf("warning: failed to answer asset request %s: %v\n", e.Request.URL, err)
+			}
+		}()
+	})
+
+	return fetch.Enable()
+}
+
+func isNetworkURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "http://") || strings.HasPrefix(rawURL, "https://")
+}
@@ -0,0 +1,236 @@
+package pdfjob
+
+import (
+	"archive/zip"
+	"context"
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/storage"
+	"designmypdf/pkg/webhook"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxBatchSize caps the number of payloads accepted in a single batch request.
+const MaxBatchSize = 5000
+
+// BatchProgress is the aggregated view of a batch returned by the status endpoint.
+type BatchProgress struct {
+	Batch    *entities.PdfGenerationBatch `json:"batch"`
+	Counts   map[entities.JobStatus]int64 `json:"counts"`
+	Progress float64                      `json:"progress"`
+	Jobs     []entities.PdfGenerationJob  `json:"jobs"`
+}
+
+// EnqueueBatch persists a batch with one child job per payload; RunOutbox
+// publishes every child to the RabbitMQ lane of priority. When zip is true the worker assembles a single ZIP
+// of all successful results once the last child finishes.
+func (s *Service) EnqueueBatch(keyID uint, templateUUID string, payloads []json.RawMessage, opts GenerateOptions, zip bool, priority string) (*entities.PdfGenerationBatch, error) {
+	if len(payloads) == 0 {
+		return nil, errors.New("batch must contain at least one payload")
+	}
+	if len(payloads) > MaxBatchSize {
+		return nil, fmt.Errorf("batch too large: %d payloads (max %d)", len(payloads), MaxBatchSize)
+	}
+
+	batch := &entities.PdfGenerationBatch{
+		ID:           uuid.New().String(),
+		KeyID:        keyID,
+		TemplateUUID: templateUUID,
+		Format:       opts.Format,
+		Status:       entities.JobStatusQueued,
+		TotalCount:   len(payloads),
+		Zip:          zip,
+	}
+
+	jobOptions := marshalJobOptions(opts)
+	passwords, err := sealPasswords(opts.Protect)
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]entities.PdfGenerationJob, len(payloads))
+	for i, payload := range payloads {
+		jobs[i] = entities.PdfGenerationJob{
+			ID:              uuid.New().String(),
+			KeyID:           keyID,
+			TemplateUUID:    templateUUID,
+			Payload:         []byte(payload),
+			Format:          opts.Format,
+			Output:          opts.JobOutput(),
+			Options:         jobOptions,
+			NoCache:         opts.NoCache,
+			SealedPasswords: passwords,
+			TemplateVersion: opts.TemplateVersion,
+			Priority:        priority,
+			Status:          entities.JobStatusQueued,
+			BatchID:         &batch.ID,
+		}
+	}
+
+	if err := s.repo.CreateBatch(batch, jobs); err != nil {
+		return nil, fmt.Errorf("failed to create batch: %w", err)
+	}
+	s.wakeOutbox()
+
+	return batch, nil
+}
+
+// GetBatchProgress returns a batch with per-status counts and its child jobs.
+func (s *Service) GetBatchProgress(batchID string) (*BatchProgress, error) {
+	batch, err := s.repo.GetBatchByID(batchID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.repo.CountBatchJobsByStatus(batchID)
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := s.repo.ListBatchJobs(batchID)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress float64
+	if batch.TotalCount > 0 {
+		done := counts[entities.JobStatusCompleted] + counts[entities.JobStatusFailed]
+		progress = float64(done) / float64(batch.TotalCount)
+	}
+
+	return &BatchProgress{Batch: batch, Counts: counts, Progress: progress, Jobs: jobs}, nil
+}
+
+// trackBatchProgress is called after a batch child reaches a terminal state.
+// The caller that accounts for the last child finalizes the batch.
+func (s *Service) trackBatchProgress(job *entities.PdfGenerationJob, succeeded bool) {
+	if job.BatchID == nil {
+		return
+	}
+	batchID := *job.BatchID
+
+	if err := s.repo.IncrementBatchCounter(batchID, succeeded); err != nil {
+		fmt.Printf("warning: failed to update batch %s progress: %v\n", batchID, err)
+		return
+	}
+
+	batch, err := s.repo.GetBatchByID(batchID)
+	if err != nil {
+		fmt.Printf("warning: failed to load batch %s: %v\n", batchID, err)
+		return
+	}
+	if batch.CompletedCount+batch.FailedCount < batch.TotalCount {
+		return
+	}
+
+	status := entities.JobStatusCompleted
+	if batch.CompletedCount == 0 {
+		status = entities.JobStatusFailed
+	}
+	claimed, err := s.repo.ClaimBatchCompletion(batchID, status)
+	if err != nil {
+		fmt.Printf("warning: failed to complete batch %s: %v\n", batchID, err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	s.finalizeBatch(batch, status, job.Key.UserID)
+}
+
+func (s *Service) finalizeBatch(batch *entities.PdfGenerationBatch, status entities.JobStatus, userID uint) {
+	extra := map[string]interface{}{
+		"batch_id":        batch.ID,
+		"template_uuid":   batch.TemplateUUID,
+		"status":          status,
+		"total_count":     batch.TotalCount,
+		"completed_count": batch.CompletedCount,
+		"failed_count":    batch.FailedCount,
+	}
+
+	if batch.Zip && batch.CompletedCount > 0 {
+		zipURL, err := s.buildBatchZip(batch.ID)
+		if err != nil {
+			fmt.Printf("warning: failed to build ZIP for batch %s: %v\n", batch.ID, err)
+			extra["zip_error"] = err.Error()
+		} else {
+			if err := s.repo.SetBatchZipPath(batch.ID, zipURL); err != nil {
+				fmt.Printf("warning: failed to save ZIP path for batch %s: %v\n", batch.ID, err)
+			}
+			extra["zip_path"] = zipURL
+		}
+	}
+
+	publisher := webhook.NewPublisher()
+	publisher.Publish(webhook.EventPdfBatchCompleted, batch.ID, userID, batch.KeyID, extra)
+}
+
+// buildBatchZip streams every successful child PDF into one archive and uploads it.
+func (s *Service) buildBatchZip(batchID string) (string, error) {
+	jobs, err := s.repo.ListBatchJobs(batchID)
+	if err != nil {
+		return "", err
+	}
+	store, err := getStorageInstance()
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	tmp, err := os.CreateTemp("", "batch-*.zip")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	zw := zip.NewWriter(tmp)
+	for i, job := range jobs {
+		if job.Status != entities.JobStatusCompleted || job.ResultKey == "" {
+			continue
+		}
+		name := fmt.Sprintf("%05d_%s%s", i+1, job.ID, path.Ext(job.ResultKey))
+		// Named documents are stored as templates/<uuid>/<file name>.
+		if dir, file := path.Split(job.ResultKey); strings.Count(dir, "/") > 1 {
+			name = fmt.Sprintf("%05d_%s", i+1, file)
+		}
+		if err := copyObjectToZip(ctx, zw, store, job.ResultKey, name); err != nil {
+			return "", fmt.Errorf("job %s: %w", job.ID, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return store.Put(ctx, fmt.Sprintf("batches/%s.zip", batchID), tmp, size, "application/zip")
+}
+
+func copyObjectToZip(ctx context.Context, zw *zip.Writer, store storage.ObjectStore, objectKey, name string) error {
+	rc, err := store.Get(ctx, objectKey)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, rc)
+	return err
+}
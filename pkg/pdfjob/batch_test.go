@@ -0,0 +1,127 @@
+package pdfjob
+
+import (
+	"designmypdf/config/database"
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/webhook"
+	"testing"
+	"time"
+)
+
+func createBatch(t *testing.T, id string, total int, children ...string) {
+	t.Helper()
+	if err := database.DB.Create(&entities.PdfGenerationBatch{ID: id, TemplateUUID: "t", TotalCount: total}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, child := range children {
+		createJobs(t, entities.PdfGenerationJob{ID: child, BatchID: &id, Status: entities.JobStatusRunning})
+	}
+}
+
+func loadBatch(t *testing.T, id string) entities.PdfGenerationBatch {
+	t.Helper()
+	var batch entities.PdfGenerationBatch
+	if err := database.DB.First(&batch, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return batch
+}
+
+func completionEvents(t *testing.T, batchID string) int64 {
+	t.Helper()
+	var n int64
+	database.DB.Model(&entities.WebhookEvent{}).
+		Where("event_name = ? AND job_id = ?", webhook.EventPdfBatchCompleted, batchID).
+		Count(&n)
+	return n
+}
+
+func TestTrackBatchProgress(t *testing.T) {
+	useTestDB(t)
+	createBatch(t, "b", 3, "j1", "j2", "j3")
+	s := &Service{repo: Repository{}}
+	batchID := "b"
+	child := &entities.PdfGenerationJob{BatchID: &batchID}
+
+	s.trackBatchProgress(child, true)
+	if b := loadBatch(t, "b"); b.Status != entities.JobStatusRunning || b.CompletedCount != 1 || b.CompletedAt != nil {
+		t.Fatalf("after first child: status=%s completed=%d completed_at=%v", b.Status, b.CompletedCount, b.CompletedAt)
+	}
+
+	s.trackBatchProgress(child, false)
+	s.trackBatchProgress(child, true)
+	b := loadBatch(t, "b")
+	if b.Status != entities.JobStatusCompleted || b.CompletedCount != 2 || b.FailedCount != 1 || b.CompletedAt == nil {
+		t.Fatalf("after last child: status=%s completed=%d failed=%d completed_at=%v", b.Status, b.CompletedCount, b.FailedCount, b.CompletedAt)
+	}
+	if n := completionEvents(t, "b"); n != 1 {
+		t.Errorf("completion events = %d, want 1", n)
+	}
+
+	// A late duplicate must not finalize the batch twice.
+	s.trackBatchProgress(child, true)
+	if n := completionEvents(t, "b"); n != 1 {
+		t.Errorf("completion events after duplicate = %d, want 1", n)
+	}
+}
+
+func TestTrackBatchProgress_AllFailed(t *testing.T) {
+	useTestDB(t)
+	createBatch(t, "b", 2)
+	s := &Service{repo: Repository{}}
+	batchID := "b"
+	child := &entities.PdfGenerationJob{BatchID: &batchID}
+
+	s.trackBatchProgress(child, false)
+	s.trackBatchProgress(child, false)
+	if b := loadBatch(t, "b"); b.Status != entities.JobStatusFailed || b.FailedCount != 2 {
+		t.Errorf("status=%s failed=%d, want failed/2", b.Status, b.FailedCount)
+	}
+}
+
+func TestResetForReplayReopensBatch(t *testing.T) {
+	useTestDB(t)
+	createBatch(t, "b", 2)
+	now := time.Now()
+	batchID := "b"
+	createJobs(t,
+		entities.PdfGenerationJob{ID: "ok", BatchID: &batchID, Status: entities.JobStatusCompleted},
+		entities.PdfGenerationJob{ID: "dead", BatchID: &batchID, Status: entities.JobStatusFailed, Attempts: 5, DeadLetteredAt: &now},
+	)
+	s := &Service{repo: Repository{}}
+	child := &entities.PdfGenerationJob{BatchID: &batchID}
+	s.trackBatchProgress(child, true)
+	s.trackBatchProgress(child, false)
+	if b := loadBatch(t, "b"); b.Status != entities.JobStatusCompleted || b.CompletedAt == nil {
+		t.Fatalf("batch not finalized before replay: status=%s completed_at=%v", b.Status, b.CompletedAt)
+	}
+
+	if reset, err := s.repo.ResetForReplay("dead"); err != nil || !reset {
+		t.Fatalf("ResetForReplay() = %v, %v", reset, err)
+	}
+	b := loadBatch(t, "b")
+	if b.Status != entities.JobStatusRunning || b.FailedCount != 0 || b.CompletedCount != 1 || b.CompletedAt != nil {
+		t.Fatalf("after replay: status=%s completed=%d failed=%d completed_at=%v", b.Status, b.CompletedCount, b.FailedCount, b.CompletedAt)
+	}
+
+	// The replayed child succeeding completes the batch again.
+	s.trackBatchProgress(child, true)
+	if b := loadBatch(t, "b"); b.Status != entities.JobStatusCompleted || b.CompletedCount != 2 || b.FailedCount != 0 || b.CompletedAt == nil {
+		t.Errorf("after replayed child: status=%s completed=%d failed=%d completed_at=%v", b.Status, b.CompletedCount, b.FailedCount, b.CompletedAt)
+	}
+}
+
+func TestResetForReplayKeepsFailedCountNonNegative(t *testing.T) {
+	useTestDB(t)
+	createBatch(t, "b", 1)
+	now := time.Now()
+	batchID := "b"
+	createJobs(t, entities.PdfGenerationJob{ID: "dead", BatchID: &batchID, Status: entities.JobStatusFailed, DeadLetteredAt: &now})
+
+	if _, err := (Repository{}).ResetForReplay("dead"); err != nil {
+		t.Fatal(err)
+	}
+	if b := loadBatch(t, "b"); b.FailedCount != 0 {
+		t.Errorf("failed_count = %d, want 0", b.FailedCount)
+	}
+}
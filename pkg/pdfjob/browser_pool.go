@@ -2,17 +2,99 @@ package pdfjob
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/chromedp/cdproto/browser"
 	"github.com/chromedp/chromedp"
 )
 
-// BrowserPool holds a persistent Chrome exec allocator so every PDF reuses
-// the same process instead of spawning a new one per request.
+const (
+	defaultBrowserPoolSize     = 1
+	defaultBrowserMaxTabs      = 8
+	defaultBrowserQueueTimeout = 30 * time.Second
+	defaultBrowserRecycleAfter = 500
+	defaultBrowserHealthPeriod = 30 * time.Second
+	browserHealthCheckTimeout  = 5 * time.Second
+)
+
+// ErrBrowserPoolBusy is returned by Acquire when no tab slot frees up before
+// the queue timeout.
+var ErrBrowserPoolBusy = errors.New("browser pool busy: no tab available before queue timeout")
+
+var errBrowserPoolClosed = errors.New("browser pool closed")
+
+// BrowserPool spreads tabs over one or more long-lived Chrome processes.
+//
+// Concurrency is capped by BROWSER_MAX_TABS across all processes; callers
+// beyond that wait up to BROWSER_QUEUE_TIMEOUT. A process is restarted when a
+// health check fails and recycled after BROWSER_RECYCLE_AFTER renders to cap
+// memory growth. Processes are started lazily on first use.
 type BrowserPool struct {
-	allocCtx    context.Context
-	allocCancel context.CancelFunc
+	allocOpts    []chromedp.ExecAllocatorOption
+	queueTimeout time.Duration
+	recycleAfter int
+
+	slots chan struct{}
+
+	mu      sync.Mutex
+	procs   []*browserProcess
+	changed chan struct{}
+	closed  bool
+	stop    chan struct{}
+
+	waiting       int64
+	totalRenders  int64
+	queueTimeouts int64
+	recycles      int64
+	crashes       int64
+}
+
+// browserProcess is one Chrome process of the pool. Fields are guarded by BrowserPool.mu.
+type browserProcess struct {
+	id            int
+	allocCancel   context.CancelFunc
+	browserCtx    context.Context
+	browserCancel context.CancelFunc
+	startedAt     time.Time
+	starting      bool
+	draining      bool
+	active        int
+	renders       int
+	restarts      int
+	lastError     string
+}
+
+// BrowserPoolStats is the snapshot returned by Stats.
+type BrowserPoolStats struct {
+	Browsers      int                   `json:"browsers"`
+	MaxTabs       int                   `json:"max_tabs"`
+	ActiveTabs    int                   `json:"active_tabs"`
+	Waiting       int64                 `json:"waiting"`
+	QueueTimeout  string                `json:"queue_timeout"`
+	RecycleAfter  int                   `json:"recycle_after"`
+	TotalRenders  int64                 `json:"total_renders"`
+	QueueTimeouts int64                 `json:"queue_timeouts"`
+	Recycles      int64                 `json:"recycles"`
+	Crashes       int64                 `json:"crashes"`
+	Processes     []BrowserProcessStats `json:"processes"`
+}
+
+// BrowserProcessStats describes one Chrome process of the pool.
+type BrowserProcessStats struct {
+	ID         int        `json:"id"`
+	Running    bool       `json:"running"`
+	Draining   bool       `json:"draining"`
+	ActiveTabs int        `json:"active_tabs"`
+	Renders    int        `json:"renders"`
+	Restarts   int        `json:"restarts"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
 }
 
 var (
@@ -28,6 +110,8 @@ func GetBrowserPool() *BrowserPool {
 	return globalPool
 }
 
+// newBrowserPool reads BROWSER_POOL_SIZE, BROWSER_MAX_TABS, BROWSER_QUEUE_TIMEOUT,
+// BROWSER_RECYCLE_AFTER (0 disables recycling) and BROWSER_HEALTH_INTERVAL.
 func newBrowserPool() *BrowserPool {
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.NoSandbox,
@@ -37,17 +121,369 @@ func newBrowserPool() *BrowserPool {
 	if p := os.Getenv("CHROME_PATH"); p != "" {
 		opts = append(opts, chromedp.ExecPath(p))
 	}
+
+	size := envInt("BROWSER_POOL_SIZE", defaultBrowserPoolSize, 1)
+	maxTabs := envInt("BROWSER_MAX_TABS", defaultBrowserMaxTabs, 1)
+
+	p := &BrowserPool{
+		allocOpts:    opts,
+		queueTimeout: envDuration("BROWSER_QUEUE_TIMEOUT", defaultBrowserQueueTimeout),
+		recycleAfter: envInt("BROWSER_RECYCLE_AFTER", defaultBrowserRecycleAfter, 0),
+		slots:        make(chan struct{}, maxTabs),
+		changed:      make(chan struct{}),
+		stop:         make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		p.procs = append(p.procs, &browserProcess{id: i})
+	}
+
+	go p.healthLoop(envDuration("BROWSER_HEALTH_INTERVAL", defaultBrowserHealthPeriod))
+	return p
+}
+
+// Acquire waits for a free tab slot and opens a tab in the least loaded Chrome
+// process. Cancelling ctx closes the tab. The caller must call release exactly
+// once when the render is done.
+func (p *BrowserPool) Acquire(ctx context.Context) (tabCtx context.Context, release func(), err error) {
+	waitCtx, cancelWait := context.WithTimeout(ctx, p.queueTimeout)
+	defer cancelWait()
+
+	atomic.AddInt64(&p.waiting, 1)
+	select {
+	case p.slots <- struct{}{}:
+		atomic.AddInt64(&p.waiting, -1)
+	case <-waitCtx.Done():
+		atomic.AddInt64(&p.waiting, -1)
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		atomic.AddInt64(&p.queueTimeouts, 1)
+		return nil, nil, ErrBrowserPoolBusy
+	}
+
+	proc, err := p.pick(waitCtx)
+	if err != nil {
+		<-p.slots
+		if errors.Is(err, ErrBrowserPoolBusy) {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			atomic.AddInt64(&p.queueTimeouts, 1)
+		}
+		return nil, nil, err
+	}
+
+	tabCtx, cancelTab, err := p.openTab(proc)
+	if err != nil {
+		<-p.slots
+		return nil, nil, err
+	}
+	stopAfter := context.AfterFunc(ctx, cancelTab)
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			stopAfter()
+			cancelTab()
+			p.release(proc)
+			<-p.slots
+		})
+	}
+	return tabCtx, release, nil
+}
+
+// openTab opens a tab on the process reserved by pick. When the process was
+// shut down meanwhile, the reservation is given back.
+func (p *BrowserPool) openTab(proc *browserProcess) (context.Context, context.CancelFunc, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if proc.browserCtx == nil {
+		proc.active--
+		p.notifyLocked()
+		return nil, nil, errBrowserPoolClosed
+	}
+	tabCtx, cancelTab := chromedp.NewContext(proc.browserCtx)
+	return tabCtx, cancelTab, nil
+}
+
+// pick reserves a tab on the least loaded process, starting it if needed.
+func (p *BrowserPool) pick(ctx context.Context) (*browserProcess, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errBrowserPoolClosed
+		}
+
+		proc := p.leastLoadedLocked()
+		if proc != nil && proc.running() {
+			proc.active++
+			p.mu.Unlock()
+			return proc, nil
+		}
+
+		if proc != nil && proc.active > 0 {
+			// Browser died under live tabs: let them finish before restarting.
+			atomic.AddInt64(&p.crashes, 1)
+			proc.lastError = "browser context closed"
+			proc.draining = true
+			p.mu.Unlock()
+			continue
+		}
+
+		if proc != nil {
+			// A process that died idle still holds its allocator and
+			// Chrome: free them before starting a new one.
+			p.shutdownLocked(proc)
+			proc.starting = true
+			p.mu.Unlock()
+
+			allocCancel, browserCtx, browserCancel, err := startBrowser(p.allocOpts)
+
+			p.mu.Lock()
+			proc.starting = false
+			if err != nil {
+				proc.lastError = err.Error()
+				p.notifyLocked()
+				p.mu.Unlock()
+				return nil, fmt.Errorf("failed to start browser: %w", err)
+			}
+			if p.closed {
+				p.mu.Unlock()
+				browserCancel()
+				allocCancel()
+				return nil, errBrowserPoolClosed
+			}
+			if !proc.startedAt.IsZero() {
+				proc.restarts++
+			}
+			proc.allocCancel, proc.browserCtx, proc.browserCancel = allocCancel, browserCtx, browserCancel
+			proc.startedAt = time.Now()
+			proc.renders = 0
+			proc.lastError = ""
+			p.notifyLocked()
+			p.mu.Unlock()
+			continue
+		}
+
+		// Every process is draining or starting: wait for one to come back.
+		changed := p.changed
+		p.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ErrBrowserPoolBusy
+		}
+	}
+}
+
+// leastLoadedLocked returns the process with the fewest active tabs, preferring
+// running ones on ties. Draining and starting processes are skipped.
+func (p *BrowserPool) leastLoadedLocked() *browserProcess {
+	var best *browserProcess
+	for _, proc := range p.procs {
+		if proc.draining || proc.starting {
+			continue
+		}
+		if best == nil ||
+			proc.active < best.active ||
+			(proc.active == best.active && proc.running() && !best.running()) {
+			best = proc
+		}
+	}
+	return best
+}
+
+func (p *BrowserPool) release(proc *browserProcess) {
+	atomic.AddInt64(&p.totalRenders, 1)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proc.active--
+	proc.renders++
+
+	if proc.browserCtx != nil && proc.browserCtx.Err() != nil && !proc.draining {
+		atomic.AddInt64(&p.crashes, 1)
+		proc.lastError = "browser context closed"
+		proc.draining = true
+	}
+	if p.recycleAfter > 0 && proc.renders >= p.recycleAfter && !proc.draining {
+		atomic.AddInt64(&p.recycles, 1)
+		proc.draining = true
+	}
+	if proc.draining && proc.active == 0 {
+		p.shutdownLocked(proc)
+	}
+	p.notifyLocked()
+}
+
+// shutdownLocked closes a process so the next pick starts a fresh one.
+func (p *BrowserPool) shutdownLocked(proc *browserProcess) {
+	browserCancel, allocCancel := proc.browserCancel, proc.allocCancel
+	proc.browserCtx, proc.browserCancel, proc.allocCancel = nil, nil, nil
+	proc.draining = false
+	if browserCancel != nil {
+		go func() {
+			browserCancel()
+			allocCancel()
+		}()
+	}
+}
+
+// notifyLocked wakes every caller waiting in pick.
+func (p *BrowserPool) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+func (p *BrowserPool) healthLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.checkHealth()
+		}
+	}
+}
+
+// checkHealth pings every running process; a process that does not answer is
+// drained and restarted on next use.
+func (p *BrowserPool) checkHealth() {
+	p.mu.Lock()
+	type target struct {
+		proc *browserProcess
+		ctx  context.Context
+	}
+	var targets []target
+	for _, proc := range p.procs {
+		if proc.running() && !proc.draining {
+			targets = append(targets, target{proc, proc.browserCtx})
+		}
+	}
+	p.mu.Unlock()
+
+	for _, t := range targets {
+		err := pingBrowser(t.ctx)
+		if err == nil {
+			continue
+		}
+		fmt.Printf("warning: browser %d failed health check: %v\n", t.proc.id, err)
+
+		p.mu.Lock()
+		if t.proc.browserCtx == t.ctx && !t.proc.draining {
+			atomic.AddInt64(&p.crashes, 1)
+			t.proc.lastError = err.Error()
+			t.proc.draining = true
+			if t.proc.active == 0 {
+				p.shutdownLocked(t.proc)
+			}
+			p.notifyLocked()
+		}
+		p.mu.Unlock()
+	}
+}
+
+func pingBrowser(browserCtx context.Context) error {
+	if err := browserCtx.Err(); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(browserCtx, browserHealthCheckTimeout)
+	defer cancel()
+	return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		_, _, _, _, _, err := browser.GetVersion().Do(ctx)
+		return err
+	}))
+}
+
+// startBrowser launches a Chrome process and waits until it accepts commands.
+func startBrowser(opts []chromedp.ExecAllocatorOption) (context.CancelFunc, context.Context, context.CancelFunc, error) {
 	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
-	return &BrowserPool{allocCtx: allocCtx, allocCancel: allocCancel}
+	browserCtx, browserCancel := chromedp.NewContext(allocCtx)
+	if err := chromedp.Run(browserCtx); err != nil {
+		browserCancel()
+		allocCancel()
+		return nil, nil, nil, err
+	}
+	return allocCancel, browserCtx, browserCancel, nil
 }
 
-// NewTab opens a new Chrome tab in the existing process.
-// Caller must defer the returned cancel to close the tab.
-func (p *BrowserPool) NewTab() (context.Context, context.CancelFunc) {
-	return chromedp.NewContext(p.allocCtx)
+func (proc *browserProcess) running() bool {
+	return proc.browserCtx != nil && proc.browserCtx.Err() == nil
 }
 
-// Close shuts down the Chrome process. Call on application exit.
+// Stats returns a snapshot of pool usage.
+func (p *BrowserPool) Stats() BrowserPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := BrowserPoolStats{
+		Browsers:      len(p.procs),
+		MaxTabs:       cap(p.slots),
+		Waiting:       atomic.LoadInt64(&p.waiting),
+		QueueTimeout:  p.queueTimeout.String(),
+		RecycleAfter:  p.recycleAfter,
+		TotalRenders:  atomic.LoadInt64(&p.totalRenders),
+		QueueTimeouts: atomic.LoadInt64(&p.queueTimeouts),
+		Recycles:      atomic.LoadInt64(&p.recycles),
+		Crashes:       atomic.LoadInt64(&p.crashes),
+	}
+	for _, proc := range p.procs {
+		ps := BrowserProcessStats{
+			ID:         proc.id,
+			Running:    proc.running(),
+			Draining:   proc.draining,
+			ActiveTabs: proc.active,
+			Renders:    proc.renders,
+			Restarts:   proc.restarts,
+			LastError:  proc.lastError,
+		}
+		if !proc.startedAt.IsZero() {
+			startedAt := proc.startedAt
+			ps.StartedAt = &startedAt
+		}
+		stats.ActiveTabs += proc.active
+		stats.Processes = append(stats.Processes, ps)
+	}
+	return stats
+}
+
+// Close shuts down every Chrome process. Call on application exit.
 func (p *BrowserPool) Close() {
-	p.allocCancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.stop)
+	for _, proc := range p.procs {
+		if proc.browserCancel != nil {
+			proc.browserCancel()
+			proc.allocCancel()
+		}
+		proc.browserCtx, proc.browserCancel, proc.allocCancel = nil, nil, nil
+	}
+	p.notifyLocked()
+}
+
+func envInt(name string, def, min int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= min {
+			return n
+		}
+	}
+	return def
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
 }
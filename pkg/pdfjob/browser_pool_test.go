@@ -0,0 +1,105 @@
+package pdfjob
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestBrowserPoolAcquire_QueueTimeout(t *testing.T) {
+	p := &BrowserPool{
+		queueTimeout: 20 * time.Millisecond,
+		slots:        make(chan struct{}, 1),
+		changed:      make(chan struct{}),
+		stop:         make(chan struct{}),
+		procs:        []*browserProcess{{id: 0}},
+	}
+	p.slots <- struct{}{} // the only tab is busy
+
+	_, _, err := p.Acquire(context.Background())
+	if !errors.Is(err, ErrBrowserPoolBusy) {
+		t.Fatalf("Acquire() error = %v, want ErrBrowserPoolBusy", err)
+	}
+	if got := p.Stats().QueueTimeouts; got != 1 {
+		t.Errorf("QueueTimeouts = %d, want 1", got)
+	}
+}
+
+func TestBrowserPoolAcquire_CallerCancelled(t *testing.T) {
+	p := &BrowserPool{
+		queueTimeout: time.Second,
+		slots:        make(chan struct{}, 1),
+		changed:      make(chan struct{}),
+		stop:         make(chan struct{}),
+		procs:        []*browserProcess{{id: 0}},
+	}
+	p.slots <- struct{}{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := p.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Acquire() error = %v, want context.Canceled", err)
+	}
+	if got := p.Stats().QueueTimeouts; got != 0 {
+		t.Errorf("QueueTimeouts = %d, want 0", got)
+	}
+}
+
+func TestBrowserPoolPick_ShutsDownDeadProcess(t *testing.T) {
+	deadCtx, cancelDead := context.WithCancel(context.Background())
+	cancelDead()
+	cancelled := make(chan string, 2)
+	p := &BrowserPool{
+		allocOpts: []chromedp.ExecAllocatorOption{chromedp.ExecPath("/nonexistent/chrome")},
+		changed:   make(chan struct{}),
+		stop:      make(chan struct{}),
+		procs: []*browserProcess{{
+			id:            0,
+			browserCtx:    deadCtx,
+			browserCancel: func() { cancelled <- "browser" },
+			allocCancel:   func() { cancelled <- "allocator" },
+			startedAt:     time.Now(),
+		}},
+	}
+
+	if _, err := p.pick(context.Background()); err == nil {
+		t.Fatal("pick() started a browser from a nonexistent path")
+	}
+	for _, want := range []string{"browser", "allocator"} {
+		select {
+		case got := <-cancelled:
+			if got != want {
+				t.Errorf("cancelled %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s context of the dead process not cancelled", want)
+		}
+	}
+}
+
+func TestBrowserPoolOpenTab_ProcessGone(t *testing.T) {
+	p := &BrowserPool{
+		changed: make(chan struct{}),
+		procs:   []*browserProcess{{id: 0, active: 1}},
+	}
+	if _, _, err := p.openTab(p.procs[0]); !errors.Is(err, errBrowserPoolClosed) {
+		t.Fatalf("openTab() error = %v, want errBrowserPoolClosed", err)
+	}
+	if got := p.Stats().ActiveTabs; got != 0 {
+		t.Errorf("ActiveTabs = %d, want 0: the reservation leaked", got)
+	}
+}
+
+func TestBrowserPoolLeastLoaded_SkipsDraining(t *testing.T) {
+	p := &BrowserPool{procs: []*browserProcess{
+		{id: 0, active: 0, draining: true},
+		{id: 1, active: 3},
+		{id: 2, active: 1},
+	}}
+	if got := p.leastLoadedLocked(); got == nil || got.id != 2 {
+		t.Fatalf("leastLoadedLocked() = %+v, want process 2", got)
+	}
+}
@@ -0,0 +1,147 @@
+package pdfjob
+
+import (
+	"crypto/sha256"
+	"designmypdf/config/database"
+	"designmypdf/pkg/entities"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPdfCacheMaxEntries = 1000
+	defaultPdfCacheTTL        = 24 * time.Hour
+)
+
+// pdfCacheConfig reads PDF_CACHE_MAX_ENTRIES and PDF_CACHE_TTL (Go duration, e.g. "12h").
+// PDF_CACHE_MAX_ENTRIES=0 disables the cache.
+func pdfCacheConfig() (maxEntries int, ttl time.Duration) {
+	return envInt("PDF_CACHE_MAX_ENTRIES", defaultPdfCacheMaxEntries, 0),
+		envDuration("PDF_CACHE_TTL", defaultPdfCacheTTL)
+}
+
+// cacheKeyInput lists everything that changes the rendered bytes.
+type cacheKeyInput struct {
+	Content        string                 `json:"content"`
+	Framework      entities.FrameworkType `json:"framework"`
+	Fonts          []string               `json:"fonts"`
+	BackgroundHex  string                 `json:"bg"`
+	ContentPadding string                 `json:"padding"`
+	Header         string                 `json:"header,omitempty"`
+	Footer         string                 `json:"footer,omitempty"`
+	HeaderHeight   string                 `json:"header_height,omitempty"`
+	FooterHeight   string                 `json:"footer_height,omitempty"`
+	Partials       map[string]string      `json:"partials,omitempty"`
+	Translations   json.RawMessage        `json:"translations,omitempty"`
+	Name           string                 `json:"name,omitempty"`
+	Metadata       []string               `json:"metadata,omitempty"`
+	Data           map[string]interface{} `json:"data"`
+	Options        GenerateOptions        `json:"options"`
+}
+
+// generateHash returns the content address of a render.
+func generateHash(templateEntity *entities.Template, data map[string]interface{}, opts GenerateOptions) string {
+	b, _ := json.Marshal(cacheKeyInput{
+		Content:        templateEntity.Content,
+		Framework:      templateEntity.Framework,
+		Fonts:          templateEntity.Fonts,
+		BackgroundHex:  templateEntity.PdfBackgroundColor,
+		ContentPadding: templateEntity.PdfContentPadding,
+		Header:         templateEntity.PdfHeader,
+		Footer:         templateEntity.PdfFooter,
+		HeaderHeight:   templateEntity.PdfHeaderHeight,
+		FooterHeight:   templateEntity.PdfFooterHeight,
+		Partials:       templateEntity.Partials,
+		Translations:   json.RawMessage(templateEntity.Translations),
+		Name:           templateEntity.Name,
+		Metadata:       documentMetadataFields(templateEntity),
+		Data:           data,
+		Options:        opts,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheLookup returns a live entry for hash and records the hit.
+func cacheLookup(hash string) (*entities.PdfCacheEntry, bool) {
+	maxEntries, _ := pdfCacheConfig()
+	if maxEntries == 0 || database.DB == nil {
+		return nil, false
+	}
+
+	var entry entities.PdfCacheEntry
+	if err := database.DB.Where("hash = ? AND expires_at > ?", hash, time.Now()).First(&entry).Error; err != nil {
+		return nil, false
+	}
+
+	if err := database.DB.Model(&entities.PdfCacheEntry{}).
+		Where("hash = ?", hash).
+		Updates(map[string]interface{}{
+			"last_hit_at": time.Now(),
+			"hit_count":   gorm.Expr("hit_count + 1"),
+		}).Error; err != nil {
+		fmt.Printf("warning: failed to record cache hit: %v\n", err)
+	}
+	return &entry, true
+}
+
+// cacheStore records a freshly uploaded render and evicts least recently hit
+// entries beyond PDF_CACHE_MAX_ENTRIES. Evicted objects stay in storage since
+// earlier responses may still point at them.
+func cacheStore(hash, objectKey, url string, size int64) {
+	maxEntries, ttl := pdfCacheConfig()
+	if maxEntries == 0 || database.DB == nil {
+		return
+	}
+
+	now := time.Now()
+	entry := entities.PdfCacheEntry{
+		Hash:      hash,
+		ObjectKey: objectKey,
+		URL:       url,
+		Size:      size,
+		CreatedAt: now,
+		LastHitAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"object_key", "url", "size", "created_at", "last_hit_at", "expires_at"}),
+	}).Create(&entry).Error; err != nil {
+		fmt.Printf("warning: failed to store cache entry: %v\n", err)
+		return
+	}
+
+	evictPdfCache(maxEntries)
+}
+
+func evictPdfCache(maxEntries int) {
+	if err := database.DB.Where("expires_at <= ?", time.Now()).Delete(&entities.PdfCacheEntry{}).Error; err != nil {
+		fmt.Printf("warning: failed to prune expired cache entries: %v\n", err)
+	}
+
+	var count int64
+	if err := database.DB.Model(&entities.PdfCacheEntry{}).Count(&count).Error; err != nil || count <= int64(maxEntries) {
+		return
+	}
+
+	var hashes []string
+	if err := database.DB.Model(&entities.PdfCacheEntry{}).
+		Order("last_hit_at ASC").
+		Limit(int(count)-maxEntries).
+		Pluck("hash", &hashes).Error; err != nil {
+		fmt.Printf("warning: failed to select cache entries to evict: %v\n", err)
+		return
+	}
+	if len(hashes) == 0 {
+		return
+	}
+	if err := database.DB.Where("hash IN ?", hashes).Delete(&entities.PdfCacheEntry{}).Error; err != nil {
+		fmt.Printf("warning: failed to evict cache entries: %v\n", err)
+	}
+}
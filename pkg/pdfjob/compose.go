@@ -0,0 +1,260 @@
+package pdfjob
+
+import (
+	"bytes"
+	"context"
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/key"
+	"designmypdf/pkg/pdfa"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// MaxComposeParts caps the number of templates merged into one document.
+const MaxComposeParts = 20
+
+// ComposePart is one template/data pair of a composed document.
+// Format overrides the document format for this part only (e.g. A3 annex),
+// Locale the document locale.
+type ComposePart struct {
+	Template *entities.Template
+	Data     map[string]interface{}
+	Format   string
+	Locale   string
+	Title    string
+}
+
+// ComposeOptions controls document-level settings of ComposePdfForKey.
+// Empty page setup values fall back to each part's template defaults.
+type ComposeOptions struct {
+	Format          string
+	Orientation     string
+	Margin          string
+	Locale          string
+	TableOfContents bool
+	TocTitle        string
+	// Sign signs the composed document (never the parts) with a certificate
+	// of the first part's namespace or the user default.
+	Sign *SignOptions
+	// Protect encrypts the composed document; unset permissions fall back to
+	// the first part's template default.
+	Protect *ProtectOptions
+	// Conformance and Attach convert the composed document to PDF/A like
+	// GenerateOptions; the payload attachment lists the data of every part.
+	Conformance string
+	Attach      []string
+}
+
+// tocTemplate lists every part with its first page number; rendered like any other template.
+const tocTemplate = `<div style="font-family:sans-serif">
+<h1 style="font-size:1.75rem;font-weight:700;margin-bottom:1.5rem">{{title}}</h1>
+<table style="width:100%;border-collapse:collapse">
+{{#each entries}}<tr>
+<td style="padding:.5rem 0;border-bottom:1px dotted #999">{{title}}</td>
+<td style="padding:.5rem 0;border-bottom:1px dotted #999;text-align:right;width:4rem">{{page}}</td>
+</tr>{{/each}}
+</table>
+</div>`
+
+// ComposePdfForKey renders every part through the browser pool and concatenates
+// them into one PDF, with an optional generated table of contents and one
+// outline entry per part. The key usage count is charged once per document.
+func ComposePdfForKey(ctx context.Context, keyEntity *entities.Key, parts []ComposePart, opts ComposeOptions) ([]byte, error) {
+	if len(parts) == 0 {
+		return nil, errors.New("at least one part is required")
+	}
+	if len(parts) > MaxComposeParts {
+		return nil, fmt.Errorf("too many parts: %d (max %d)", len(parts), MaxComposeParts)
+	}
+	payload := make([]map[string]interface{}, len(parts))
+	var facturX interface{}
+	for i, part := range parts {
+		payload[i] = part.Data
+		if facturX == nil {
+			facturX = part.Data[FacturXField]
+		}
+	}
+	meta, err := composeMetadata(parts[0], opts)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := archiveOptions(opts.Conformance, opts.Attach, meta, payload, facturX)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([][]byte, len(parts))
+	pageCounts := make([]int, len(parts))
+	for i, part := range parts {
+		format := part.Format
+		if format == "" {
+			format = opts.Format
+		}
+		locale := part.Locale
+		if locale == "" {
+			locale = opts.Locale
+		}
+		buf, err := renderPdf(ctx, part.Template, part.Data, GenerateOptions{
+			Format:      format,
+			Orientation: opts.Orientation,
+			Margin:      opts.Margin,
+			Locale:      locale,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("part %d (%s): %w", i+1, part.Template.UUID, err)
+		}
+		n, err := api.PageCount(bytes.NewReader(buf), model.NewDefaultConfiguration())
+		if err != nil {
+			return nil, fmt.Errorf("part %d (%s): count pages: %w", i+1, part.Template.UUID, err)
+		}
+		rendered[i] = buf
+		pageCounts[i] = n
+	}
+
+	var docs [][]byte
+	tocPages := 0
+	if opts.TableOfContents {
+		tocSetup := GenerateOptions{Format: opts.Format, Orientation: opts.Orientation, Margin: opts.Margin}.
+			withTemplateDefaults(parts[0].Template)
+		tocBuf, n, err := renderTableOfContents(ctx, parts, pageCounts, opts.TocTitle, tocSetup)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, tocBuf)
+		tocPages = n
+	}
+	docs = append(docs, rendered...)
+
+	bookmarks := make([]pdfcpu.Bookmark, len(parts))
+	page := tocPages + 1
+	for i, part := range parts {
+		bookmarks[i] = pdfcpu.Bookmark{Title: partTitle(part), PageFrom: page}
+		page += pageCounts[i]
+	}
+
+	merged, err := mergePdfs(docs)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := api.AddBookmarks(bytes.NewReader(merged), &out, bookmarks, true, model.NewDefaultConfiguration()); err != nil {
+		return nil, fmt.Errorf("failed to add outline: %w", err)
+	}
+	finish := finishing{
+		Archive: archive,
+		Protect: withTemplatePermissions(opts.Protect, parts[0].Template.PdfPermissions),
+		Sign:    opts.Sign,
+	}
+	if hasMetadata(parts[0].Template) {
+		finish.Metadata = &meta
+	}
+	signed, err := finishPdf(keyEntity, parts[0].Template.NamespaceID, out.Bytes(), finish)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := key.NewService(key.Repository{})
+	if err := svc.IncreaseUsageCount(keyEntity.ID); err != nil {
+		fmt.Printf("warning: failed to increase usage count: %v\n", err)
+	}
+
+	return signed, nil
+}
+
+// renderTableOfContents renders the TOC, re-rendering once if its own length
+// shifts the page numbers it prints.
+func renderTableOfContents(ctx context.Context, parts []ComposePart, pageCounts []int, title string, setup GenerateOptions) ([]byte, int, error) {
+	if title == "" {
+		title = "Table of contents"
+	}
+	tocEntity := &entities.Template{UUID: "toc", Content: tocTemplate, Framework: entities.Tailwind}
+
+	tocPages := 1
+	for attempt := 0; attempt < 2; attempt++ {
+		entries := make([]map[string]interface{}, len(parts))
+		page := tocPages + 1
+		for i, part := range parts {
+			entries[i] = map[string]interface{}{"title": partTitle(part), "page": page}
+			page += pageCounts[i]
+		}
+
+		buf, err := renderPdf(ctx, tocEntity, map[string]interface{}{"title": title, "entries": entries}, setup)
+		if err != nil {
+			return nil, 0, fmt.Errorf("table of contents: %w", err)
+		}
+		n, err := api.PageCount(bytes.NewReader(buf), model.NewDefaultConfiguration())
+		if err != nil {
+			return nil, 0, fmt.Errorf("table of contents: count pages: %w", err)
+		}
+		if n == tocPages {
+			return buf, n, nil
+		}
+		tocPages = n
+	}
+	return nil, 0, errors.New("table of contents: page count did not stabilize")
+}
+
+func mergePdfs(docs [][]byte) ([]byte, error) {
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+	readers := make([]io.ReadSeeker, len(docs))
+	for i, d := range docs {
+		readers[i] = bytes.NewReader(d)
+	}
+	var out bytes.Buffer
+	if err := api.MergeRaw(readers, &out, false, model.NewDefaultConfiguration()); err != nil {
+		return nil, fmt.Errorf("failed to merge PDFs: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// composeMetadata renders the document information of a composed document
+// from its first part; an untitled template falls back to the part title.
+func composeMetadata(first ComposePart, opts ComposeOptions) (pdfa.Metadata, error) {
+	locale := first.Locale
+	if locale == "" {
+		locale = opts.Locale
+	}
+	renderOpts, err := renderOptions(first.Template, GenerateOptions{Locale: locale}.withTemplateDefaults(first.Template))
+	if err != nil {
+		return pdfa.Metadata{}, err
+	}
+	meta, err := renderMetadata(first.Template, first.Data, renderOpts)
+	if err != nil {
+		return pdfa.Metadata{}, err
+	}
+	if strings.TrimSpace(first.Template.PdfTitle) == "" {
+		meta.Title = partTitle(first)
+	}
+	return meta, nil
+}
+
+func partTitle(part ComposePart) string {
+	if part.Title != "" {
+		return part.Title
+	}
+	return part.Template.Name
+}
+
+// StorePdf uploads already rendered PDF bytes under filename (see
+// DocumentFilename; empty for a generated name) and returns their URL.
+func StorePdf(ctx context.Context, pdfBuf []byte, filename string) (string, error) {
+	store, err := getStorageInstance()
+	if err != nil {
+		return "", transient(fmt.Errorf("failed to initialize storage: %w", err))
+	}
+	storagePath := storagePath(filename, OutputPDF)
+	url, err := store.Put(ctx, storagePath, bytes.NewReader(pdfBuf), int64(len(pdfBuf)), "application/pdf")
+	if err != nil {
+		return "", transient(fmt.Errorf("failed to upload PDF: %w", err))
+	}
+	return url, nil
+}
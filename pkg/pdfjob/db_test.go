@@ -0,0 +1,42 @@
+package pdfjob
+
+import (
+	"designmypdf/config/database"
+	"designmypdf/pkg/entities"
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// useTestDB points database.DB to a fresh in-memory SQLite database with the
+// job tables and webhook events, for the duration of the test.
+func useTestDB(t *testing.T) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		// go-sqlite3 needs cgo.
+		t.Skipf("SQLite unavailable: %v", err)
+	}
+	err = db.AutoMigrate(
+		&entities.Key{},
+		&entities.PdfGenerationJob{},
+		&entities.PdfGenerationBatch{},
+		&entities.PdfJobOutbox{},
+		&entities.WebhookEvent{},
+	)
+	if err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	prev := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = prev
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
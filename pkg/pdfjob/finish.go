@@ -0,0 +1,66 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/pdfa"
+	"designmypdf/pkg/signing"
+	"fmt"
+)
+
+var (
+	// errSignedProtection rejects a signature combined with encryption; signed
+	// requests lift template restrictions with ?permissions=all.
+	errSignedProtection = fmt.Errorf("%w: signed PDFs cannot be encrypted or restricted", ErrInvalidProtection)
+	// errArchivedProtection rejects encryption of a PDF/A document, which the
+	// standard forbids.
+	errArchivedProtection = fmt.Errorf("%w: PDF/A documents cannot be encrypted or restricted", ErrInvalidProtection)
+)
+
+// finishing lists the document-level steps applied to a rendered PDF.
+// Metadata is written on its own only when the PDF is not archived: the
+// PDF/A conversion writes the metadata of Archive.
+type finishing struct {
+	Metadata *pdfa.Metadata
+	Archive  *pdfa.Options
+	Protect  *ProtectOptions
+	Sign     *SignOptions
+}
+
+// finishPdf applies the document-level steps that follow rendering: document
+// metadata or PDF/A conversion first, then encryption or signature, never
+// both (any change made to the bytes after signing invalidates the signature).
+func finishPdf(keyEntity *entities.Key, namespaceID uint, pdf []byte, f finishing) ([]byte, error) {
+	if f.Metadata != nil && f.Archive == nil {
+		withMetadata, err := pdfa.SetMetadata(pdf, *f.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write PDF metadata: %w", err)
+		}
+		pdf = withMetadata
+	}
+	if f.Archive != nil {
+		if f.Protect.active() {
+			return nil, errArchivedProtection
+		}
+		archived, err := pdfa.Convert(pdf, *f.Archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert PDF to %s: %w", f.Archive.Conformance, err)
+		}
+		pdf = archived
+	}
+	if f.Sign == nil {
+		return protectPdf(pdf, f.Protect)
+	}
+	if f.Protect.active() {
+		return nil, errSignedProtection
+	}
+	svc := signing.NewService(signing.Repository{})
+	id, err := svc.Identity(keyEntity.UserID, namespaceID, f.Sign.CertificateID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signing certificate: %w", err)
+	}
+	signed, err := signing.Sign(pdf, id, f.Sign.signingOptions())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign PDF: %w", err)
+	}
+	return signed, nil
+}
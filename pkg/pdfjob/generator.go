@@ -1,17 +1,17 @@
 package pdfjob
 
 import (
+	"bytes"
 	"context"
-	"crypto/md5"
+	"designmypdf/pkg/assets"
 	"designmypdf/pkg/entities"
 	"designmypdf/pkg/key"
 	"designmypdf/pkg/storage"
 	"designmypdf/utils"
-	"encoding/hex"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"net/url"
-	"os"
 	"regexp"
 	"runtime"
 	"strings"
@@ -20,93 +20,356 @@ import (
 
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
-	"github.com/google/uuid"
 )
 
-type pdfCache struct {
-	cache    map[string]string
-	mu       sync.RWMutex
-	maxItems int
+// GenerateOptions carries the per-request rendering settings. Every field that
+// changes the output must be JSON-visible so it takes part in the cache key.
+type GenerateOptions struct {
+	// Format is a named paper size (A4, Letter, B5...) or "W x H" in mm/cm/in.
+	Format string `json:"format"`
+	// Orientation is portrait or landscape.
+	Orientation string `json:"orientation,omitempty"`
+	// Margin is a CSS-like shorthand of 1 to 4 lengths, e.g. "10mm" or "0.5in 1in".
+	Margin string `json:"margin,omitempty"`
+	// Locale picks the translations of {{t}} and the default locale of the
+	// formatting helpers; empty uses the template default locale.
+	Locale string `json:"locale,omitempty"`
+	// Sign applies a PAdES signature once the PDF is rendered. Signed PDFs
+	// bypass the result cache: every signature carries its own signing time.
+	Sign *SignOptions `json:"sign,omitempty"`
+	// Protect encrypts the PDF and restricts its permissions; unset
+	// permissions fall back to the template default. PDFs with a password
+	// bypass the result cache.
+	Protect *ProtectOptions `json:"protect,omitempty"`
+	// Conformance converts the PDF to PDF/A (pdfa.Conformance2B or
+	// pdfa.Conformance3B); Attach lists the files embedded in a PDF/A-3
+	// document (AttachPayload, AttachFacturX).
+	Conformance string   `json:"conformance,omitempty"`
+	Attach      []string `json:"attach,omitempty"`
+	// Filename overrides the file name rendered from the template (see
+	// DocumentFilename); it is part of the storage path.
+	Filename string `json:"filename,omitempty"`
+	// Output renders an image (OutputPNG, OutputJPEG, OutputWebP) with the
+	// Image settings instead of a PDF; empty is a PDF.
+	Output string        `json:"output,omitempty"`
+	Image  *ImageOptions `json:"image,omitempty"`
+	// NoCache skips the shared result cache for this request (lookup and store).
+	NoCache bool `json:"-"`
+	// TemplateVersion pins the template version a queued job renders
+	// (see template.ResolveByUUID); the rendered content is already in the cache key.
+	TemplateVersion int `json:"-"`
 }
 
-var (
-	pdfCacheInstance = &pdfCache{
-		cache:    make(map[string]string),
-		maxItems: 100,
+// Validate reports malformed page setup values before anything is rendered or queued.
+func (o GenerateOptions) Validate() error {
+	if strings.TrimSpace(o.Locale) != "" {
+		if _, err := utils.NormalizeLocale(o.Locale); err != nil {
+			return err
+		}
 	}
-	storageInstance *storage.BackblazeStorage
-	storageMu       sync.Mutex
-)
+	if o.Sign != nil {
+		if err := o.Sign.Validate(); err != nil {
+			return err
+		}
+	}
+	if o.Protect != nil {
+		if err := o.Protect.Validate(); err != nil {
+			return err
+		}
+		if o.Sign != nil && o.Protect.active() {
+			return errSignedProtection
+		}
+	}
+	if err := validateArchive(o.Conformance, o.Attach, o.Protect, o.Sign); err != nil {
+		return err
+	}
+	if err := o.validateOutput(); err != nil {
+		return err
+	}
+	_, err := utils.NewPageLayout(o.Format, o.Orientation, o.Margin)
+	return err
+}
 
-func generateHash(templateContent string, data map[string]interface{}, format string, bgColor string, contentPadding string) string {
-	dataBytes, _ := json.Marshal(data)
-	hash := md5.Sum([]byte(templateContent + string(dataBytes) + format + bgColor + contentPadding))
-	return hex.EncodeToString(hash[:])
+// withTemplateDefaults fills unset page setup values and locale from the template.
+func (o GenerateOptions) withTemplateDefaults(t *entities.Template) GenerateOptions {
+	if strings.TrimSpace(o.Format) == "" {
+		o.Format = t.PdfFormat
+	}
+	if strings.TrimSpace(o.Format) == "" {
+		o.Format = "A4"
+	}
+	o.Format = strings.ToUpper(strings.TrimSpace(o.Format))
+	if strings.TrimSpace(o.Orientation) == "" {
+		o.Orientation = t.PdfOrientation
+	}
+	if strings.TrimSpace(o.Margin) == "" {
+		o.Margin = t.PdfMargin
+	}
+	if strings.TrimSpace(o.Locale) == "" {
+		o.Locale = t.DefaultLocale
+	}
+	if locale, err := utils.NormalizeLocale(o.Locale); err == nil {
+		o.Locale = locale
+	}
+	o.Protect = withTemplatePermissions(o.Protect, t.PdfPermissions)
+	return o
 }
 
+// finishing returns the post-render steps of a template render with data.
+func (o GenerateOptions) finishing(t *entities.Template, data map[string]interface{}) (finishing, error) {
+	renderOpts, err := renderOptions(t, o)
+	if err != nil {
+		return finishing{}, err
+	}
+	meta, err := renderMetadata(t, data, renderOpts)
+	if err != nil {
+		return finishing{}, err
+	}
+	archive, err := archiveOptions(o.Conformance, o.Attach, meta, data, data[FacturXField])
+	if err != nil {
+		return finishing{}, err
+	}
+	f := finishing{Archive: archive, Protect: o.Protect, Sign: o.Sign}
+	if hasMetadata(t) {
+		f.Metadata = &meta
+	}
+	return f, nil
+}
+
+// GenerateResult describes a PDF stored by GeneratePdfForKey.
+type GenerateResult struct {
+	URL       string
+	ObjectKey string
+	CacheHit  bool
+}
+
+var (
+	storageInstance storage.ObjectStore
+	storageMu       sync.Mutex
+)
+
 var hexColorRe = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
 
 func isValidHex(color string) bool {
 	return hexColorRe.MatchString(color)
 }
 
-func getStorageInstance() (*storage.BackblazeStorage, error) {
+// SetStorage overrides the object store used for generated PDFs (tests, or a
+// store already built by the caller). Pass nil to fall back to STORAGE_BACKEND.
+func SetStorage(store storage.ObjectStore) {
+	storageMu.Lock()
+	defer storageMu.Unlock()
+	storageInstance = store
+}
+
+func getStorageInstance() (storage.ObjectStore, error) {
 	storageMu.Lock()
 	defer storageMu.Unlock()
 	if storageInstance != nil {
 		return storageInstance, nil
 	}
-	keyID, appKey, bucketName, ok := storage.B2ConfigFromEnv()
-	if !ok {
-		return nil, fmt.Errorf("backblaze B2 env missing: set BACKBLAZE_KEY_ID, BACKBLAZE_APP_KEY, BACKBLAZE_BUCKET_NAME")
-	}
-	b2, err := storage.NewBackblazeStorage(keyID, appKey, bucketName)
+	store, err := storage.NewFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	storageInstance = b2
+	storageInstance = store
 	return storageInstance, nil
 }
 
-// GeneratePdfForKey renders a PDF for the given key/template/data and returns
-// the Backblaze URL. It is safe for concurrent use and works for both the
-// synchronous HTTP handler and the async worker.
+// GeneratePdfForKey renders a PDF for the given key/template/data, uploads it
+// and returns where it was stored. Identical renders are served from the shared
+// result cache unless opts.NoCache is set. It is safe for concurrent use and
+// works for both the synchronous HTTP handler and the async worker.
 func GeneratePdfForKey(
 	ctx context.Context,
 	keyEntity *entities.Key,
 	templateEntity *entities.Template,
 	data map[string]interface{},
-	format string,
-) (string, error) {
-	contentHash := generateHash(templateEntity.Content, data, format, templateEntity.PdfBackgroundColor, templateEntity.PdfContentPadding)
-
-	pdfCacheInstance.mu.RLock()
-	cachedURL, found := pdfCacheInstance.cache[contentHash]
-	pdfCacheInstance.mu.RUnlock()
-	if found {
-		fmt.Printf("PDF found in cache: %s\n", cachedURL)
-		go func() {
-			svc := key.NewService(key.Repository{})
-			if err := svc.IncreaseUsageCount(keyEntity.ID); err != nil {
-				fmt.Printf("warning: failed to increase usage count: %v\n", err)
-			}
-		}()
-		return cachedURL, nil
+	opts GenerateOptions,
+) (*GenerateResult, error) {
+	opts = opts.withTemplateDefaults(templateEntity)
+	contentHash := generateHash(templateEntity, data, opts)
+	cacheable := !opts.NoCache && opts.Sign == nil && !opts.Protect.hasPasswords()
+	finish, err := opts.finishing(templateEntity, data)
+	if err != nil {
+		return nil, err
+	}
+	filename, err := DocumentFilename(templateEntity, data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheable {
+		if cached, found := cacheLookup(contentHash); found {
+			fmt.Printf("PDF found in cache: %s\n", cached.URL)
+			go func() {
+				svc := key.NewService(key.Repository{})
+				if err := svc.IncreaseUsageCount(keyEntity.ID); err != nil {
+					fmt.Printf("warning: failed to increase usage count: %v\n", err)
+				}
+			}()
+			return &GenerateResult{URL: cached.URL, ObjectKey: cached.ObjectKey, CacheHit: true}, nil
+		}
+	}
+
+	pdfBuf, err := renderOutput(ctx, keyEntity, templateEntity, data, opts, finish)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := getStorageInstance()
+	if err != nil {
+		return nil, transient(fmt.Errorf("failed to initialize storage: %w", err))
+	}
+
+	storagePath := storagePath(filename, opts.Output)
+
+	var wg sync.WaitGroup
+	var uploadedURL string
+	var uploadErr, countErr error
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		uploadedURL, uploadErr = store.Put(ctx, storagePath, bytes.NewReader(pdfBuf), int64(len(pdfBuf)), OutputContentType(opts.Output))
+	}()
+	go func() {
+		defer wg.Done()
+		svc := key.NewService(key.Repository{})
+		countErr = svc.IncreaseUsageCount(keyEntity.ID)
+	}()
+	wg.Wait()
+
+	if uploadErr != nil {
+		return nil, transient(fmt.Errorf("failed to upload PDF: %w", uploadErr))
+	}
+	if countErr != nil {
+		fmt.Printf("warning: failed to increase usage count: %v\n", countErr)
+	}
+
+	if cacheable {
+		cacheStore(contentHash, storagePath, uploadedURL, int64(len(pdfBuf)))
+	}
+
+	return &GenerateResult{URL: uploadedURL, ObjectKey: storagePath}, nil
+}
+
+// RenderPdfForKey renders a PDF, or the image of opts.Output, and returns its
+// bytes without touching storage or the URL cache. The key usage count is
+// charged exactly like GeneratePdfForKey.
+func RenderPdfForKey(
+	ctx context.Context,
+	keyEntity *entities.Key,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+) ([]byte, error) {
+	opts = opts.withTemplateDefaults(templateEntity)
+	finish, err := opts.finishing(templateEntity, data)
+	if err != nil {
+		return nil, err
+	}
+	pdfBuf, err := renderOutput(ctx, keyEntity, templateEntity, data, opts, finish)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := key.NewService(key.Repository{})
+	if err := svc.IncreaseUsageCount(keyEntity.ID); err != nil {
+		fmt.Printf("warning: failed to increase usage count: %v\n", err)
+	}
+	return pdfBuf, nil
+}
+
+// renderOutput renders the document of opts.Output: an image, or a PDF with
+// its finishing steps.
+func renderOutput(
+	ctx context.Context,
+	keyEntity *entities.Key,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+	finish finishing,
+) ([]byte, error) {
+	if opts.isImage() {
+		return renderImage(ctx, templateEntity, data, opts)
 	}
+	pdfBuf, err := renderPdf(ctx, templateEntity, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return finishPdf(keyEntity, templateEntity.NamespaceID, pdfBuf, finish)
+}
 
-	renderedHTML, err := utils.RenderTemplate(templateEntity.Content, data)
+// renderOptions returns the Handlebars environment of a template render: its
+// partials, and the locale of opts with the translations it resolves to.
+func renderOptions(templateEntity *entities.Template, opts GenerateOptions) (utils.RenderOptions, error) {
+	bundles, err := utils.ParseTranslations(templateEntity.Translations)
 	if err != nil {
-		return "", fmt.Errorf("failed to render template: %w", err)
+		return utils.RenderOptions{}, fmt.Errorf("invalid template translations: %w", err)
 	}
+	return utils.RenderOptions{
+		Partials: templateEntity.Partials,
+		Locale:   opts.Locale,
+		Messages: bundles.Messages(opts.Locale, templateEntity.DefaultLocale),
+	}, nil
+}
+
+// document is a template rendered into the full HTML page that Chrome prints,
+// with the page setup it must be printed with.
+type document struct {
+	HTML            string
+	Layout          utils.PageLayout
+	HeaderFooter    *headerFooter
+	ContentAreaH    int
+	WaitForTailwind bool
+}
 
-	// Tailwind v4 Play CDN: browser script detects arbitrary classes at runtime.
-	// Bootstrap uses a plain CSS CDN link (no JS DOM scan needed).
+// buildDocument renders the template with data and wraps it in the HTML page
+// shared by PDF and preview rendering.
+func buildDocument(
+	ctx context.Context,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+) (*document, error) {
+	opts = opts.withTemplateDefaults(templateEntity)
+	renderOpts, err := renderOptions(templateEntity, opts)
+	if err != nil {
+		return nil, err
+	}
+	renderedHTML, err := utils.RenderTemplateWith(templateEntity.Content, data, renderOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	// Chrome writes the page title to the PDF Info dictionary.
+	title, err := renderMetadataField("pdf_title", templateEntity.PdfTitle, data, renderOpts)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = templateEntity.Name
+	}
+
+	// Tailwind is compiled server-side when the CLI is available; otherwise the
+	// v4 browser build detects classes at runtime. CDN URLs are answered from the
+	// local asset store by serveLocalAssets.
 	var frameworkTag string
+	waitForTailwind := false
 	switch templateEntity.Framework {
 	case entities.Bootstrap:
 		frameworkTag = `<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/bootstrap@5/dist/css/bootstrap.min.css">`
 	default:
-		frameworkTag = `<script src="https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4"></script>`
+		css, err := assets.TailwindCSS(ctx, renderedHTML)
+		if err == nil {
+			frameworkTag = "<style>" + css + "</style>"
+		} else {
+			if !errors.Is(err, assets.ErrTailwindUnavailable) {
+				fmt.Printf("warning: server-side Tailwind failed, using browser build: %v\n", err)
+			}
+			frameworkTag = `<script src="https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4"></script>`
+			waitForTailwind = true
+		}
 	}
 
 	fontImports := utils.ImportFontCreation(templateEntity.Fonts)
@@ -117,13 +380,23 @@ func GeneratePdfForKey(
 		bgStyle = fmt.Sprintf("body{background-color:%s!important}", templateEntity.PdfBackgroundColor)
 	}
 
-	formatNorm := strings.ToUpper(strings.TrimSpace(format))
-	if formatNorm == "" {
-		formatNorm = "A4"
+	layout, err := utils.NewPageLayout(opts.Format, opts.Orientation, opts.Margin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page setup: %w", err)
+	}
+
+	var hf *headerFooter
+	if hasHeaderFooter(templateEntity) {
+		if layout, err = reserveHeaderFooter(templateEntity, layout); err != nil {
+			return nil, fmt.Errorf("invalid page setup: %w", err)
+		}
+		if hf, err = renderHeaderFooter(ctx, templateEntity, data, layout, renderOpts); err != nil {
+			return nil, err
+		}
 	}
 
 	pad := utils.EffectivePdfContentPadding(templateEntity.PdfContentPadding)
-	contentWidth := utils.ContentAreaWidthPx(formatNorm)
+	contentWidth := layout.ContentAreaWidthPx()
 	padStyle := fmt.Sprintf(
 		".content{box-sizing:border-box;padding:%s;width:%dpx;min-height:auto;height:auto}",
 		pad,
@@ -133,7 +406,7 @@ func GeneratePdfForKey(
 	fullHTML := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
-    <title>Preview</title>
+    <title>%s</title>
     <meta charset="UTF-8">
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     %s
@@ -146,6 +419,7 @@ func GeneratePdfForKey(
     <div class="content">%s</div>
 </body>
 </html>`,
+		html.EscapeString(title),
 		frameworkTag,
 		fontImports,
 		utils.CodeHighlightHeadTags(),
@@ -159,38 +433,30 @@ func GeneratePdfForKey(
 		renderedHTML,
 	)
 
-	if err := os.MkdirAll("./uploads/template", 0755); err != nil {
-		return "", fmt.Errorf("failed to create upload directory: %w", err)
-	}
-
-	id := uuid.New()
-	outputPath := fmt.Sprintf("./uploads/template/template_%s.pdf", id.String())
-
-	runtime.GC()
-
-	f, err := utils.GetFormat(formatNorm)
-	if err != nil {
-		return "", fmt.Errorf("invalid format %q: %w", formatNorm, err)
-	}
-
-	viewportW, viewportH := utils.PaperViewportCssPixels(formatNorm)
-	contentAreaH := utils.ContentAreaHeightPx(formatNorm, templateEntity.PdfContentPadding)
-	orphanThreshold := utils.OrphanThresholdPx(contentAreaH)
-	hintsJS := utils.ApplyPdfPageBreakHintsJS(contentAreaH, orphanThreshold, true)
-
-	pool := GetBrowserPool()
-	tabCtx, cancelTab := pool.NewTab()
-	defer cancelTab()
+	return &document{
+		HTML:            fullHTML,
+		Layout:          layout,
+		HeaderFooter:    hf,
+		ContentAreaH:    layout.ContentAreaHeightPx(templateEntity.PdfContentPadding),
+		WaitForTailwind: waitForTailwind,
+	}, nil
+}
 
-	// Apply a 20s deadline on the tab so a hung render never blocks forever.
-	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, 20*time.Second)
-	defer cancelTimeout()
+// loadDocument navigates the tab to doc and waits until it is laid out like
+// at print time: styles applied, code highlighted, page break hints placed.
+func loadDocument(tabCtx context.Context, doc *document) chromedp.Tasks {
+	viewportW, viewportH := doc.Layout.ViewportCssPixels()
+	orphanThreshold := utils.OrphanThresholdPx(doc.ContentAreaH)
+	hintsJS := utils.ApplyPdfPageBreakHintsJS(doc.ContentAreaH, orphanThreshold, true)
 
-	var pdfBuf []byte
-	if err := chromedp.Run(tabCtx,
-		chromedp.Navigate("data:text/html,"+url.PathEscape(fullHTML)),
+	return chromedp.Tasks{
+		serveLocalAssets(tabCtx),
+		chromedp.Navigate("data:text/html," + url.PathEscape(doc.HTML)),
 		chromedp.EmulateViewport(int64(viewportW), int64(viewportH)),
 		chromedp.ActionFunc(func(ctx context.Context) error {
+			if !doc.WaitForTailwind {
+				return nil
+			}
 			return chromedp.Evaluate(utils.WaitForTailwindJS(), nil).Do(ctx)
 		}),
 		chromedp.ActionFunc(func(ctx context.Context) error {
@@ -199,75 +465,62 @@ func GeneratePdfForKey(
 		chromedp.ActionFunc(func(ctx context.Context) error {
 			return chromedp.Evaluate(hintsJS, nil).Do(ctx)
 		}),
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			var runErr error
-			pdfBuf, _, runErr = page.PrintToPDF().
-				WithPrintBackground(true).
-				WithPaperWidth(f.Width).
-				WithPaperHeight(f.Height).
-				WithMarginTop(0.0).
-				WithMarginBottom(0.0).
-				WithMarginLeft(0.0).
-				WithMarginRight(0.0).
-				Do(ctx)
-			return runErr
-		}),
-	); err != nil {
-		return "", fmt.Errorf("failed to generate PDF: %w", err)
-	}
-
-	if err := utils.SavePDF(outputPath, pdfBuf); err != nil {
-		return "", fmt.Errorf("failed to save PDF: %w", err)
 	}
+}
 
-	b2Storage, err := getStorageInstance()
+// renderPdf builds the full HTML document for a template and prints it in a pooled Chrome tab.
+func renderPdf(
+	ctx context.Context,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+) ([]byte, error) {
+	doc, err := buildDocument(ctx, templateEntity, data, opts)
 	if err != nil {
-		os.Remove(outputPath)
-		return "", fmt.Errorf("failed to initialize storage: %w", err)
+		return nil, err
 	}
 
-	storagePath := fmt.Sprintf("templates/%s.pdf", id.String())
-
-	var wg sync.WaitGroup
-	var uploadedURL string
-	var uploadErr, countErr error
+	runtime.GC()
 
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		uploadedURL, uploadErr = b2Storage.UploadFile(ctx, outputPath, storagePath)
-	}()
-	go func() {
-		defer wg.Done()
-		svc := key.NewService(key.Repository{})
-		countErr = svc.IncreaseUsageCount(keyEntity.ID)
-	}()
-	wg.Wait()
+	paperW, paperH := doc.Layout.PaperInches()
+	marginTop, marginRight, marginBottom, marginLeft := doc.Layout.MarginInches()
 
-	if uploadErr != nil {
-		os.Remove(outputPath)
-		return "", fmt.Errorf("failed to upload PDF: %w", uploadErr)
-	}
-	if countErr != nil {
-		fmt.Printf("warning: failed to increase usage count: %v\n", countErr)
+	tabCtx, releaseTab, err := GetBrowserPool().Acquire(ctx)
+	if err != nil {
+		// Pool saturated or Chrome failing to start: not the document's fault.
+		return nil, transient(err)
 	}
+	defer releaseTab()
 
-	pdfCacheInstance.mu.Lock()
-	if len(pdfCacheInstance.cache) >= pdfCacheInstance.maxItems {
-		for k := range pdfCacheInstance.cache {
-			delete(pdfCacheInstance.cache, k)
-			break
-		}
-	}
-	pdfCacheInstance.cache[contentHash] = uploadedURL
-	pdfCacheInstance.mu.Unlock()
+	// Apply a 20s deadline on the tab so a hung render never blocks forever.
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, 20*time.Second)
+	defer cancelTimeout()
 
-	go func() {
-		time.Sleep(500 * time.Millisecond)
-		if err := os.Remove(outputPath); err != nil {
-			fmt.Printf("warning: failed to delete local PDF: %v\n", err)
-		}
-	}()
+	var pdfBuf []byte
+	if err := chromedp.Run(tabCtx,
+		loadDocument(tabCtx, doc),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			params := page.PrintToPDF().
+				WithPrintBackground(true).
+				WithPaperWidth(paperW).
+				WithPaperHeight(paperH).
+				WithMarginTop(marginTop).
+				WithMarginBottom(marginBottom).
+				WithMarginLeft(marginLeft).
+				WithMarginRight(marginRight)
+			if doc.HeaderFooter != nil {
+				params = params.
+					WithDisplayHeaderFooter(true).
+					WithHeaderTemplate(doc.HeaderFooter.Header).
+					WithFooterTemplate(doc.HeaderFooter.Footer)
+			}
+			var runErr error
+			pdfBuf, _, runErr = params.Do(ctx)
+			return runErr
+		}),
+	); err != nil {
+		return nil, chromeError(fmt.Errorf("failed to generate PDF: %w", err))
+	}
 
-	return uploadedURL, nil
+	return pdfBuf, nil
 }
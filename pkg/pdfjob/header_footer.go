@@ -0,0 +1,116 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/pkg/assets"
+	"designmypdf/pkg/entities"
+	"designmypdf/utils"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/aymerick/raymond"
+)
+
+// emptyHeaderFooter suppresses Chrome's default date/title/URL line when only
+// one of header or footer is defined.
+const emptyHeaderFooter = "<span></span>"
+
+// headerFooter holds the PrintToPDF templates of a render.
+type headerFooter struct {
+	Header string
+	Footer string
+}
+
+// pageTokens are filled by Chrome in header/footer templates.
+func pageTokens() map[string]interface{} {
+	return map[string]interface{}{
+		"pageNumber": raymond.SafeString(`<span class="pageNumber"></span>`),
+		"totalPages": raymond.SafeString(`<span class="totalPages"></span>`),
+		"date":       raymond.SafeString(`<span class="date"></span>`),
+	}
+}
+
+// hasHeaderFooter reports whether the template defines a header or a footer.
+func hasHeaderFooter(t *entities.Template) bool {
+	return strings.TrimSpace(t.PdfHeader) != "" || strings.TrimSpace(t.PdfFooter) != ""
+}
+
+// reserveHeaderFooter grows the layout margins to fit the template's header and footer.
+func reserveHeaderFooter(t *entities.Template, layout utils.PageLayout) (utils.PageLayout, error) {
+	var headerMm, footerMm float64
+	var err error
+	if strings.TrimSpace(t.PdfHeader) != "" {
+		if headerMm, err = headerFooterHeightMm(t.PdfHeaderHeight); err != nil {
+			return layout, fmt.Errorf("header height: %w", err)
+		}
+	}
+	if strings.TrimSpace(t.PdfFooter) != "" {
+		if footerMm, err = headerFooterHeightMm(t.PdfFooterHeight); err != nil {
+			return layout, fmt.Errorf("footer height: %w", err)
+		}
+	}
+	return layout.ReserveHeaderFooter(headerMm, footerMm)
+}
+
+func headerFooterHeightMm(stored string) (float64, error) {
+	if strings.TrimSpace(stored) == "" {
+		stored = utils.DefaultPdfHeaderFooterHeight
+	}
+	return utils.ParseLengthMm(stored)
+}
+
+// renderHeaderFooter renders the header and footer sections with the document
+// data plus {{pageNumber}}, {{totalPages}} and {{date}}. Chrome draws them in
+// an isolated document inside the page margins: no network, so only inline
+// styles, the compiled Tailwind CSS and locally installed fonts apply.
+func renderHeaderFooter(ctx context.Context, t *entities.Template, data map[string]interface{}, layout utils.PageLayout, renderOpts utils.RenderOptions) (*headerFooter, error) {
+	ctxData := make(map[string]interface{}, len(data)+3)
+	for k, v := range data {
+		ctxData[k] = v
+	}
+	for k, v := range pageTokens() {
+		ctxData[k] = v
+	}
+
+	var header, footer string
+	var err error
+	if strings.TrimSpace(t.PdfHeader) != "" {
+		if header, err = utils.RenderTemplateWith(t.PdfHeader, ctxData, renderOpts); err != nil {
+			return nil, fmt.Errorf("failed to render header: %w", err)
+		}
+	}
+	if strings.TrimSpace(t.PdfFooter) != "" {
+		if footer, err = utils.RenderTemplateWith(t.PdfFooter, ctxData, renderOpts); err != nil {
+			return nil, fmt.Errorf("failed to render footer: %w", err)
+		}
+	}
+
+	var css string
+	if t.Framework != entities.Bootstrap {
+		if compiled, err := assets.TailwindCSS(ctx, header+footer); err == nil {
+			css = compiled
+		}
+	}
+
+	fontFamily := "sans-serif"
+	if len(t.Fonts) > 0 {
+		fontFamily = fmt.Sprintf("'%s', sans-serif", t.Fonts[0])
+	}
+	// Chrome sizes header/footer text at 0 by default; pad to the page margins
+	// (at least 10mm so text never touches the paper edge).
+	style := fmt.Sprintf(
+		"<style>%s html{-webkit-print-color-adjust:exact;print-color-adjust:exact}"+
+			".dmp-hf{box-sizing:border-box;width:100%%;padding:0 %.2fmm 0 %.2fmm;font-size:10px;font-family:%s}</style>",
+		css, math.Max(layout.Margins.Right, 10), math.Max(layout.Margins.Left, 10), fontFamily,
+	)
+
+	hf := &headerFooter{Header: emptyHeaderFooter, Footer: emptyHeaderFooter}
+	if header != "" {
+		hf.Header = style + `<div class="dmp-hf">` + header + `</div>`
+	}
+	if footer != "" {
+		hf.Footer = style + `<div class="dmp-hf">` + footer + `</div>`
+	}
+	return hf, nil
+}
@@ -0,0 +1,48 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/pkg/entities"
+	"designmypdf/utils"
+	"strings"
+	"testing"
+)
+
+func TestRenderHeaderFooter_PageTokens(t *testing.T) {
+	t.Setenv("TAILWIND_BIN", "")
+	t.Setenv("PATH", "")
+
+	tpl := &entities.Template{
+		PdfFooter: `{{company}} — Page {{pageNumber}} of {{totalPages}}`,
+	}
+	layout, _ := utils.NewPageLayout("A4", "", "")
+	hf, err := renderHeaderFooter(context.Background(), tpl, map[string]interface{}{"company": "ACME"}, layout, utils.RenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hf.Header != emptyHeaderFooter {
+		t.Errorf("Header = %q, want the empty placeholder", hf.Header)
+	}
+	for _, want := range []string{"ACME", `<span class="pageNumber"></span>`, `<span class="totalPages"></span>`} {
+		if !strings.Contains(hf.Footer, want) {
+			t.Errorf("Footer %q does not contain %q", hf.Footer, want)
+		}
+	}
+}
+
+func TestReserveHeaderFooter_ShrinksContentArea(t *testing.T) {
+	layout, _ := utils.NewPageLayout("A4", "", "5mm")
+	tpl := &entities.Template{PdfHeader: "Report", PdfFooterHeight: "20mm"}
+
+	got, err := reserveHeaderFooter(tpl, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Header uses the 15mm default; no footer, so the bottom margin is unchanged.
+	if got.Margins.Top != 15 || got.Margins.Bottom != 5 {
+		t.Errorf("margins = %+v, want top 15 bottom 5", got.Margins)
+	}
+	if got.ContentAreaHeightPx("0") >= layout.ContentAreaHeightPx("0") {
+		t.Error("content area should shrink when a header is reserved")
+	}
+}
@@ -0,0 +1,237 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/pkg/entities"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Output formats of a generation (GenerateOptions.Output).
+const (
+	OutputPDF  = "pdf"
+	OutputPNG  = "png"
+	OutputJPEG = "jpeg"
+	OutputWebP = "webp"
+)
+
+// Image settings bounds. A scale of 1 is 96 DPI, the CSS pixel density.
+const (
+	MinImageScale       = 0.25
+	MaxImageScale       = 4
+	DefaultImageDPI     = 96
+	defaultImageQuality = 90
+	// maxImageSide is the largest bitmap side Chrome captures.
+	maxImageSide = 16384
+)
+
+var (
+	// ErrInvalidImageOptions is returned for an unknown output format or
+	// image settings that can never be applied.
+	ErrInvalidImageOptions = errors.New("invalid image options")
+	// ErrImageTooLarge is returned when the rendered document exceeds the
+	// largest image Chrome can capture at the requested scale.
+	ErrImageTooLarge = errors.New("image too large")
+)
+
+// ImageOptions are the settings of an image output. Without FullPage, the
+// image is page Page (1-based, default 1) of the print layout; headers and
+// footers are not drawn.
+type ImageOptions struct {
+	// FullPage captures the whole document as one image.
+	FullPage bool `json:"full_page,omitempty"`
+	Page     int  `json:"page,omitempty"`
+	// Scale is the device pixel ratio (DPI / 96); zero is 1.
+	Scale float64 `json:"scale,omitempty"`
+	// Quality is the JPEG or WebP compression quality (1-100); zero is 90.
+	Quality int `json:"quality,omitempty"`
+}
+
+// ParseOutput normalizes an output format such as "PNG" or "jpg"; empty is
+// a PDF and stays empty.
+func ParseOutput(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case "", OutputPDF:
+		return "", nil
+	case OutputPNG, OutputJPEG, OutputWebP:
+		return v, nil
+	case "jpg":
+		return OutputJPEG, nil
+	}
+	return "", fmt.Errorf("%w: unsupported output %q (want pdf, png, jpeg or webp)", ErrInvalidImageOptions, s)
+}
+
+// OutputContentType returns the MIME type of an output format.
+func OutputContentType(output string) string {
+	if output == "" || output == OutputPDF {
+		return "application/pdf"
+	}
+	return "image/" + output
+}
+
+// OutputExtension returns the file extension of an output format, without
+// the dot.
+func OutputExtension(output string) string {
+	if output == "" {
+		return OutputPDF
+	}
+	return output
+}
+
+// isImage reports whether o renders an image instead of a PDF.
+func (o GenerateOptions) isImage() bool {
+	return o.Output != "" && o.Output != OutputPDF
+}
+
+// JobOutput is the output format recorded on a job.
+func (o GenerateOptions) JobOutput() string {
+	return OutputExtension(o.Output)
+}
+
+// validateOutput reports an output format or image settings that can never
+// be applied, and PDF-only steps requested on an image.
+func (o GenerateOptions) validateOutput() error {
+	if normalized, err := ParseOutput(o.Output); err != nil {
+		return err
+	} else if normalized != o.Output && o.Output != OutputPDF {
+		return fmt.Errorf("%w: unsupported output %q", ErrInvalidImageOptions, o.Output)
+	}
+	if !o.isImage() {
+		if o.Image != nil {
+			return fmt.Errorf("%w: image settings require an image output", ErrInvalidImageOptions)
+		}
+		return nil
+	}
+	if o.Sign != nil || o.Protect.active() || o.Conformance != "" || len(o.Attach) > 0 {
+		return fmt.Errorf("%w: signature, protection and PDF/A only apply to PDF output", ErrInvalidImageOptions)
+	}
+	if o.Image == nil {
+		return nil
+	}
+	img := o.Image
+	if img.Page < 0 || (img.FullPage && img.Page > 1) {
+		return fmt.Errorf("%w: page must be a page number, and unset for a full-page image", ErrInvalidImageOptions)
+	}
+	if img.Scale != 0 && (img.Scale < MinImageScale || img.Scale > MaxImageScale || math.IsNaN(img.Scale)) {
+		return fmt.Errorf("%w: scale must be between %g and %g (%g to %g DPI)", ErrInvalidImageOptions,
+			MinImageScale, float64(MaxImageScale), MinImageScale*DefaultImageDPI, float64(MaxImageScale*DefaultImageDPI))
+	}
+	if img.Quality != 0 {
+		if o.Output == OutputPNG {
+			return fmt.Errorf("%w: quality only applies to jpeg and webp", ErrInvalidImageOptions)
+		}
+		if img.Quality < 1 || img.Quality > 100 {
+			return fmt.Errorf("%w: quality must be between 1 and 100", ErrInvalidImageOptions)
+		}
+	}
+	return nil
+}
+
+// screenshot is one capture of a loaded document.
+type screenshot struct {
+	Format page.CaptureScreenshotFormat
+	// Page is the 1-based page of the print layout; zero captures the whole
+	// document.
+	Page    int
+	Scale   float64
+	Quality int
+}
+
+// captureDocument loads doc in a pooled Chrome tab and captures it.
+func captureDocument(ctx context.Context, doc *document, shot screenshot) ([]byte, error) {
+	viewportW, viewportH := doc.Layout.ViewportCssPixels()
+
+	tabCtx, releaseTab, err := GetBrowserPool().Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseTab()
+
+	tabCtx, cancelTimeout := context.WithTimeout(tabCtx, 20*time.Second)
+	defer cancelTimeout()
+
+	var pages int
+	var height float64
+	var img []byte
+	if err := chromedp.Run(tabCtx,
+		loadDocument(tabCtx, doc),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if shot.Page == 0 {
+				return chromedp.Evaluate(`document.documentElement.scrollHeight`, &height).Do(ctx)
+			}
+			if err := chromedp.Evaluate(fmt.Sprintf("%s(%d)", screenPaginateJS, viewportH), &pages).Do(ctx); err != nil {
+				return err
+			}
+			if shot.Page > pages {
+				return fmt.Errorf("%w: %d (document has %d)", ErrPreviewPageOutOfRange, shot.Page, pages)
+			}
+			height = float64(viewportH)
+			return nil
+		}),
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			if math.Max(float64(viewportW), height)*shot.Scale > maxImageSide {
+				return fmt.Errorf("%w: %.0fx%.0f px exceeds %d px, lower the scale or capture single pages",
+					ErrImageTooLarge, float64(viewportW)*shot.Scale, height*shot.Scale, maxImageSide)
+			}
+			params := page.CaptureScreenshot().
+				WithFormat(shot.Format).
+				WithCaptureBeyondViewport(true).
+				WithClip(&page.Viewport{
+					X:      0,
+					Y:      float64(max(shot.Page-1, 0) * viewportH),
+					Width:  float64(viewportW),
+					Height: height,
+					Scale:  shot.Scale,
+				})
+			if shot.Quality > 0 {
+				params = params.WithQuality(int64(shot.Quality))
+			}
+			var err error
+			img, err = params.Do(ctx)
+			return err
+		}),
+	); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// renderImage renders the template with data as an image of opts.Output.
+func renderImage(
+	ctx context.Context,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+) ([]byte, error) {
+	doc, err := buildDocument(ctx, templateEntity, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	var img ImageOptions
+	if opts.Image != nil {
+		img = *opts.Image
+	}
+	shot := screenshot{Format: page.CaptureScreenshotFormat(opts.Output), Page: img.Page, Scale: img.Scale, Quality: img.Quality}
+	if img.FullPage {
+		shot.Page = 0
+	} else if shot.Page == 0 {
+		shot.Page = 1
+	}
+	if shot.Scale == 0 {
+		shot.Scale = 1
+	}
+	if shot.Quality == 0 && opts.Output != OutputPNG {
+		shot.Quality = defaultImageQuality
+	}
+	buf, err := captureDocument(ctx, doc, shot)
+	if err != nil && !errors.Is(err, ErrPreviewPageOutOfRange) && !errors.Is(err, ErrImageTooLarge) {
+		return nil, chromeError(fmt.Errorf("failed to capture image: %w", err))
+	}
+	return buf, err
+}
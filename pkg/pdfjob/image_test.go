@@ -0,0 +1,43 @@
+package pdfjob
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	for in, want := range map[string]string{"": "", "PDF": "", "png": OutputPNG, "JPG": OutputJPEG, " webp ": OutputWebP} {
+		if got, err := ParseOutput(in); err != nil || got != want {
+			t.Errorf("ParseOutput(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseOutput("gif"); !errors.Is(err, ErrInvalidImageOptions) {
+		t.Errorf("ParseOutput(gif): err = %v", err)
+	}
+}
+
+func TestValidateOutput(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts GenerateOptions
+	}{
+		{"image settings on a PDF", GenerateOptions{Image: &ImageOptions{Page: 2}}},
+		{"signed image", GenerateOptions{Output: OutputPNG, Sign: &SignOptions{}}},
+		{"PDF/A image", GenerateOptions{Output: OutputPNG, Conformance: "pdfa-2b"}},
+		{"full page with a page", GenerateOptions{Output: OutputPNG, Image: &ImageOptions{FullPage: true, Page: 2}}},
+		{"scale too high", GenerateOptions{Output: OutputJPEG, Image: &ImageOptions{Scale: 8}}},
+		{"PNG quality", GenerateOptions{Output: OutputPNG, Image: &ImageOptions{Quality: 80}}},
+		{"quality out of range", GenerateOptions{Output: OutputWebP, Image: &ImageOptions{Quality: 101}}},
+	} {
+		if err := tc.opts.validateOutput(); !errors.Is(err, ErrInvalidImageOptions) {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+	}
+	ok := GenerateOptions{Output: OutputJPEG, Image: &ImageOptions{Page: 3, Scale: 2, Quality: 75}}
+	if err := ok.validateOutput(); err != nil {
+		t.Errorf("validateOutput() = %v", err)
+	}
+	if got := (GenerateOptions{}).JobOutput(); got != OutputPDF {
+		t.Errorf("JobOutput() = %q, want pdf", got)
+	}
+}
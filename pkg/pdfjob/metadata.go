@@ -0,0 +1,161 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/pdfa"
+	"designmypdf/utils"
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aymerick/raymond"
+	"github.com/google/uuid"
+)
+
+// maxFilenameLength caps generated file names, in bytes, extension included.
+const maxFilenameLength = 200
+
+// ValidateMetadataField reports a template metadata field (title, author,
+// subject, keywords, file name) that is not a valid Handlebars template.
+func ValidateMetadataField(name, value string) error {
+	if _, err := raymond.Parse(value); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+// documentMetadataFields lists the metadata templates of t: title, author,
+// subject, keywords and file name.
+func documentMetadataFields(t *entities.Template) []string {
+	return []string{t.PdfTitle, t.PdfAuthor, t.PdfSubject, t.PdfKeywords, t.PdfFilename}
+}
+
+// hasMetadata reports whether t defines document information; without it
+// the PDF keeps the Info dictionary Chrome writes, titled by the template name.
+func hasMetadata(t *entities.Template) bool {
+	for _, f := range []string{t.PdfTitle, t.PdfAuthor, t.PdfSubject, t.PdfKeywords} {
+		if strings.TrimSpace(f) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderMetadata renders the metadata fields of t with data; the title
+// falls back to the template name.
+func renderMetadata(t *entities.Template, data interface{}, renderOpts utils.RenderOptions) (pdfa.Metadata, error) {
+	var meta pdfa.Metadata
+	for _, f := range []struct {
+		name, source string
+		dst          *string
+	}{
+		{"pdf_title", t.PdfTitle, &meta.Title},
+		{"pdf_author", t.PdfAuthor, &meta.Author},
+		{"pdf_subject", t.PdfSubject, &meta.Subject},
+		{"pdf_keywords", t.PdfKeywords, &meta.Keywords},
+	} {
+		value, err := renderMetadataField(f.name, f.source, data, renderOpts)
+		if err != nil {
+			return pdfa.Metadata{}, err
+		}
+		*f.dst = value
+	}
+	if meta.Title == "" {
+		meta.Title = t.Name
+	}
+	return meta, nil
+}
+
+// renderMetadataField renders one metadata template as plain text: HTML
+// escaping undone and whitespace collapsed.
+func renderMetadataField(name, source string, data interface{}, renderOpts utils.RenderOptions) (string, error) {
+	if strings.TrimSpace(source) == "" {
+		return "", nil
+	}
+	rendered, err := utils.RenderTemplateWith(source, data, renderOpts)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(rendered)), " "), nil
+}
+
+// DocumentFilename returns the file name of the document rendered from t
+// with data: opts.Filename, or the template file name rendered with data,
+// sanitized and with the extension of opts.Output. It is empty when neither
+// is set.
+func DocumentFilename(t *entities.Template, data map[string]interface{}, opts GenerateOptions) (string, error) {
+	name := opts.Filename
+	if strings.TrimSpace(name) == "" {
+		renderOpts, err := renderOptions(t, opts.withTemplateDefaults(t))
+		if err != nil {
+			return "", err
+		}
+		if name, err = renderMetadataField("pdf_filename", t.PdfFilename, data, renderOpts); err != nil {
+			return "", err
+		}
+	}
+	name = SanitizeFilename(name)
+	if name == "" {
+		return "", nil
+	}
+	return strings.TrimSuffix(name, ".pdf") + "." + OutputExtension(opts.Output), nil
+}
+
+// SanitizeFilename turns name into a safe file name: no directory, control
+// or reserved characters, at most maxFilenameLength bytes, ending in .pdf
+// (which replaces an image extension). It is empty when nothing usable is
+// left.
+func SanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsSpace(r):
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '-'
+		}
+		return r
+	}, name)
+	name = strings.Join(strings.Fields(name), " ")
+	switch strings.ToLower(path.Ext(name)) {
+	case ".pdf", ".png", ".jpg", ".jpeg", ".webp":
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+	if max := maxFilenameLength - len(".jpeg"); len(name) > max {
+		for max > 0 && !utf8.RuneStart(name[max]) {
+			max--
+		}
+		name = name[:max]
+	}
+	name = strings.Trim(name, " .")
+	if name == "" {
+		return ""
+	}
+	return name + ".pdf"
+}
+
+var dashRun = regexp.MustCompile(`-{2,}`)
+
+// storagePath returns the object key of a generated document of output:
+// templates/<uuid>.<extension>, or templates/<uuid>/<filename> with the file
+// name reduced to ASCII so it can be used in a URL as is.
+func storagePath(filename, output string) string {
+	id := uuid.New().String()
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)), r == '.', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, filename)
+	slug = strings.Trim(dashRun.ReplaceAllString(slug, "-"), "-")
+	if slug == "" || strings.HasPrefix(slug, ".") {
+		return fmt.Sprintf("templates/%s.%s", id, OutputExtension(output))
+	}
+	return fmt.Sprintf("templates/%s/%s", id, slug)
+}
@@ -0,0 +1,75 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/entities"
+	"designmypdf/utils"
+	"strings"
+	"testing"
+)
+
+func TestRenderMetadata(t *testing.T) {
+	tpl := &entities.Template{
+		Name:        "Invoice",
+		PdfTitle:    "Invoice {{number}}",
+		PdfAuthor:   "{{company}}",
+		PdfKeywords: "invoice, {{number}}",
+	}
+	data := map[string]interface{}{"number": "F-42", "company": "Smith & Sons"}
+	meta, err := renderMetadata(tpl, data, utils.RenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "Invoice F-42" || meta.Author != "Smith & Sons" || meta.Subject != "" || meta.Keywords != "invoice, F-42" {
+		t.Errorf("renderMetadata() = %+v", meta)
+	}
+	if meta, _ := renderMetadata(&entities.Template{Name: "Invoice"}, data, utils.RenderOptions{}); meta.Title != "Invoice" {
+		t.Errorf("untitled template: title = %q, want the template name", meta.Title)
+	}
+	if err := ValidateMetadataField("pdf_title", "Invoice {{number"); err == nil {
+		t.Error("ValidateMetadataField() accepted an unterminated expression")
+	}
+}
+
+func TestDocumentFilename(t *testing.T) {
+	tpl := &entities.Template{PdfFilename: "invoice-{{number}}"}
+	data := map[string]interface{}{"number": "2026/42"}
+	if name, err := DocumentFilename(tpl, data, GenerateOptions{}); err != nil || name != "invoice-2026-42.pdf" {
+		t.Errorf("DocumentFilename() = %q, %v", name, err)
+	}
+	if name, _ := DocumentFilename(tpl, data, GenerateOptions{Filename: "report.PDF"}); name != "report.pdf" {
+		t.Errorf("override: DocumentFilename() = %q", name)
+	}
+	if name, _ := DocumentFilename(tpl, data, GenerateOptions{Output: OutputWebP}); name != "invoice-2026-42.webp" {
+		t.Errorf("image: DocumentFilename() = %q", name)
+	}
+	if name, _ := DocumentFilename(&entities.Template{}, data, GenerateOptions{}); name != "" {
+		t.Errorf("no file name: DocumentFilename() = %q", name)
+	}
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	for in, want := range map[string]string{
+		"Facture n°42":           "Facture n°42.pdf",
+		"../../etc/passwd":       "-..-etc-passwd.pdf",
+		"card.PNG":               "card.pdf",
+		"a\x00b\tc  d.pdf":       "ab c d.pdf",
+		" .. ":                   "",
+		strings.Repeat("é", 150): strings.Repeat("é", 97) + ".pdf",
+	} {
+		if got := SanitizeFilename(in); got != want {
+			t.Errorf("SanitizeFilename(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStoragePath(t *testing.T) {
+	if p := storagePath("", OutputPDF); !strings.HasPrefix(p, "templates/") || !strings.HasSuffix(p, ".pdf") || strings.Count(p, "/") != 1 {
+		t.Errorf("storagePath(\"\") = %q", p)
+	}
+	if p := storagePath("", OutputJPEG); !strings.HasSuffix(p, ".jpeg") {
+		t.Errorf("storagePath(\"\", jpeg) = %q", p)
+	}
+	if p := storagePath("Facture n°42.pdf", OutputPDF); !strings.HasSuffix(p, "/Facture-n-42.pdf") || strings.Count(p, "/") != 2 {
+		t.Errorf("storagePath() = %q", p)
+	}
+}
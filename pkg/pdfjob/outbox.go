@@ -0,0 +1,123 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/pkg/entities"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultOutboxInterval   = 5 * time.Second
+	defaultQueuedStaleAfter = 15 * time.Minute
+	queuedSweepInterval     = time.Minute
+	outboxBatchSize         = 10
+	queuedSweepBatchSize    = 500
+)
+
+// RunOutbox relays the pending job publications of the outbox to RabbitMQ
+// until ctx is done: right after a job is queued by this service, and every
+// PDF_JOB_OUTBOX_INTERVAL for the rows other processes or failed publishes
+// left behind. Once a minute it also republishes the jobs whose message was
+// lost, still queued after PDF_JOB_QUEUED_STALE_AFTER or retrying after
+// PDF_JOB_RETRY_MAX_DELAY more, and requeues the jobs left running by a
+// worker that died (see RequeueInterruptedJobs). Several processes may run
+// it at once.
+func (s *Service) RunOutbox(ctx context.Context) {
+	interval := envDuration("PDF_JOB_OUTBOX_INTERVAL", defaultOutboxInterval)
+	staleAfter := envDuration("PDF_JOB_QUEUED_STALE_AFTER", defaultQueuedStaleAfter)
+
+	relay := time.NewTicker(interval)
+	defer relay.Stop()
+	sweep := time.NewTicker(queuedSweepInterval)
+	defer sweep.Stop()
+
+	for {
+		s.relayOutbox()
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.outboxWake:
+		case <-relay.C:
+		case <-sweep.C:
+			if n, err := s.republishStaleQueued(staleAfter); err != nil {
+				fmt.Printf("warning: failed to republish stale queued jobs: %v\n", err)
+			} else if n > 0 {
+				fmt.Printf("republished %d jobs queued for more than %s\n", n, staleAfter)
+			}
+			if n, err := s.RequeueInterruptedJobs(); err != nil {
+				fmt.Printf("warning: failed to requeue interrupted jobs: %v\n", err)
+			} else if n > 0 {
+				fmt.Printf("requeued %d interrupted jobs\n", n)
+			}
+		}
+	}
+}
+
+// wakeOutbox asks RunOutbox to relay the rows just written.
+func (s *Service) wakeOutbox() {
+	select {
+	case s.outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// relayOutbox publishes the pending outbox rows until none is left or the
+// broker fails.
+func (s *Service) relayOutbox() {
+	for {
+		n, err := s.repo.RelayOutbox(outboxBatchSize, s.publishOutboxRow)
+		if err != nil {
+			fmt.Printf("warning: failed to relay job outbox: %v\n", err)
+			return
+		}
+		if n < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// publishOutboxRow publishes the job of an outbox row to its lane, to the
+// delay queue of its lane when the row schedules a retry, or to the
+// dead-letter queue.
+func (s *Service) publishOutboxRow(row entities.PdfJobOutbox) error {
+	if row.DeadLetter {
+		return s.amqpClient.PublishDeadLetter(row.JobID, row.Priority, row.Reason)
+	}
+	if row.Delay > 0 {
+		return s.amqpClient.PublishRetry(row.JobID, row.Priority, row.Delay)
+	}
+	return s.amqpClient.Publish(row.JobID, row.Priority)
+}
+
+// republishStaleQueued adds an outbox row for the jobs that nothing is about
+// to publish: queued for more than olderThan, or retrying for more than the
+// longest backoff plus olderThan, whose delayed message was lost (broker
+// restart, purged delay queue). It returns the number of jobs republished.
+func (s *Service) republishStaleQueued(olderThan time.Duration) (int, error) {
+	now := time.Now()
+	queuedBefore := now.Add(-olderThan)
+	retryingBefore := now.Add(-retryPolicyFromEnv().MaxDelay - olderThan)
+	jobs, err := s.repo.ListStaleQueued(queuedBefore, retryingBefore, queuedSweepBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	republished := 0
+	for _, job := range jobs {
+		before := queuedBefore
+		if job.Status == entities.JobStatusRetrying {
+			before = retryingBefore
+		}
+		ok, err := s.repo.RepublishStale(job.ID, job.Status, before)
+		if err != nil {
+			return republished, err
+		}
+		if ok {
+			republished++
+		}
+	}
+	if republished > 0 {
+		s.wakeOutbox()
+	}
+	return republished, nil
+}
@@ -0,0 +1,138 @@
+package pdfjob
+
+import (
+	"designmypdf/config/database"
+	"designmypdf/pkg/entities"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRelayOutbox(t *testing.T) {
+	useTestDB(t)
+	repo := Repository{}
+	for _, id := range []string{"a", "b", "c"} {
+		job := &entities.PdfGenerationJob{ID: id, TemplateUUID: "t", Priority: PriorityHigh}
+		if err := repo.Create(job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var published []string
+	brokerDown := errors.New("broker down")
+	n, err := repo.RelayOutbox(10, func(row entities.PdfJobOutbox) error {
+		if row.JobID == "b" {
+			return brokerDown
+		}
+		if row.Priority != PriorityHigh {
+			t.Errorf("job %s published to lane %q", row.JobID, row.Priority)
+		}
+		published = append(published, row.JobID)
+		return nil
+	})
+	if n != 1 || !errors.Is(err, brokerDown) {
+		t.Fatalf("RelayOutbox() = %d, %v; want 1, broker down", n, err)
+	}
+
+	var rows []entities.PdfJobOutbox
+	database.DB.Order("id ASC").Find(&rows)
+	if len(rows) != 2 {
+		t.Fatalf("%d outbox rows left, want 2", len(rows))
+	}
+	for _, row := range rows {
+		if row.ClaimedAt != nil || row.ClaimedBy != "" {
+			t.Errorf("row of job %s still claimed", row.JobID)
+		}
+	}
+	if rows[0].JobID != "b" || rows[0].Attempts != 1 || rows[0].LastError != "broker down" {
+		t.Errorf("failed row = %+v", rows[0])
+	}
+
+	n, err = repo.RelayOutbox(10, func(row entities.PdfJobOutbox) error {
+		published = append(published, row.JobID)
+		return nil
+	})
+	if n != 2 || err != nil {
+		t.Fatalf("RelayOutbox() = %d, %v; want 2, nil", n, err)
+	}
+	if got := len(published); got != 3 {
+		t.Errorf("published %v, want a, b, c", published)
+	}
+}
+
+func TestRelayOutboxSkipsClaimedRows(t *testing.T) {
+	useTestDB(t)
+	repo := Repository{}
+	if err := repo.Create(&entities.PdfGenerationJob{ID: "claimed", TemplateUUID: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(&entities.PdfGenerationJob{ID: "abandoned", TemplateUUID: "t"}); err != nil {
+		t.Fatal(err)
+	}
+	recent, old := time.Now(), time.Now().Add(-2*outboxClaimTTL)
+	database.DB.Model(&entities.PdfJobOutbox{}).Where("job_id = ?", "claimed").
+		Updates(map[string]interface{}{"claimed_by": "other", "claimed_at": recent})
+	database.DB.Model(&entities.PdfJobOutbox{}).Where("job_id = ?", "abandoned").
+		Updates(map[string]interface{}{"claimed_by": "dead", "claimed_at": old})
+
+	var published []string
+	n, err := repo.RelayOutbox(10, func(row entities.PdfJobOutbox) error {
+		published = append(published, row.JobID)
+		return nil
+	})
+	if n != 1 || err != nil || len(published) != 1 || published[0] != "abandoned" {
+		t.Fatalf("RelayOutbox() = %d, %v, published %v; want only the abandoned row", n, err, published)
+	}
+}
+
+func TestListStaleQueuedIncludesLostRetries(t *testing.T) {
+	useTestDB(t)
+	repo := Repository{}
+	now := time.Now()
+	old, older := now.Add(-time.Hour), now.Add(-2*time.Hour)
+	for _, job := range []entities.PdfGenerationJob{
+		{ID: "queued-old", Status: entities.JobStatusQueued, UpdatedAt: old},
+		{ID: "queued-recent", Status: entities.JobStatusQueued, UpdatedAt: now},
+		{ID: "retrying-waiting", Status: entities.JobStatusRetrying, UpdatedAt: old},
+		{ID: "retrying-lost", Status: entities.JobStatusRetrying, UpdatedAt: older},
+		{ID: "running", Status: entities.JobStatusRunning, UpdatedAt: older},
+	} {
+		job.TemplateUUID = "t"
+		if err := database.DB.Create(&job).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queuedBefore, retryingBefore := now.Add(-30*time.Minute), now.Add(-90*time.Minute)
+	jobs, err := repo.ListStaleQueued(queuedBefore, retryingBefore, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]entities.JobStatus{}
+	for _, job := range jobs {
+		got[job.ID] = job.Status
+	}
+	want := map[string]entities.JobStatus{
+		"queued-old":    entities.JobStatusQueued,
+		"retrying-lost": entities.JobStatusRetrying,
+	}
+	if len(got) != len(want) || got["queued-old"] != want["queued-old"] || got["retrying-lost"] != want["retrying-lost"] {
+		t.Fatalf("ListStaleQueued() = %v, want %v", got, want)
+	}
+
+	ok, err := repo.RepublishStale("retrying-lost", entities.JobStatusRetrying, retryingBefore)
+	if err != nil || !ok {
+		t.Fatalf("RepublishStale() = %v, %v; want true", ok, err)
+	}
+	job, _ := repo.GetByID("retrying-lost")
+	if job.Status != entities.JobStatusQueued {
+		t.Errorf("status = %s, want queued", job.Status)
+	}
+	// Its outbox row is now pending: not listed again.
+	jobs, _ = repo.ListStaleQueued(queuedBefore, now, 10)
+	for _, job := range jobs {
+		if job.ID == "retrying-lost" {
+			t.Error("republished job listed again")
+		}
+	}
+}
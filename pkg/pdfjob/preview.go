@@ -0,0 +1,225 @@
+package pdfjob
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/template"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/page"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultThumbnailWidth = 400
+	MaxThumbnailWidth     = 1600
+)
+
+// ErrPreviewPageOutOfRange is returned when the requested page does not exist.
+var ErrPreviewPageOutOfRange = errors.New("page out of range")
+
+// screenPaginateJS turns the print page breaks into spacers so that page N of
+// the PDF is the Nth viewport-high band of the screen layout. Returns the page count.
+const screenPaginateJS = `(function(pageH) {
+  var content = document.querySelector('.content');
+  if (!content) return 1;
+  Array.from(content.querySelectorAll('*')).forEach(function(el) {
+    var cs = window.getComputedStyle(el);
+    if (cs.breakBefore !== 'page' && cs.pageBreakBefore !== 'always') return;
+    var top = el.getBoundingClientRect().top + window.scrollY;
+    var offset = top % pageH;
+    if (offset < 0.5 || pageH - offset < 0.5) return;
+    var spacer = document.createElement('div');
+    spacer.style.height = (pageH - offset) + 'px';
+    el.parentNode.insertBefore(spacer, el);
+  });
+  return Math.max(1, Math.ceil(document.documentElement.scrollHeight / pageH - 0.01));
+})`
+
+// SampleData returns the template variables as render data for previews.
+func SampleData(t *entities.Template) map[string]interface{} {
+	var data map[string]interface{}
+	if len(t.Variables) > 0 {
+		if err := json.Unmarshal(t.Variables, &data); err != nil {
+			data = nil
+		}
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data
+}
+
+// RenderPreviewHTML returns the HTML document the PDF pipeline prints for the
+// template and data, without starting Chrome.
+func RenderPreviewHTML(
+	ctx context.Context,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+) (string, error) {
+	doc, err := buildDocument(ctx, templateEntity, data, opts)
+	if err != nil {
+		return "", err
+	}
+	return doc.HTML, nil
+}
+
+// RenderPreviewPNG screenshots the printable area of page pageNum (1-based)
+// scaled to width pixels. Headers and footers are not drawn.
+func RenderPreviewPNG(
+	ctx context.Context,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+	pageNum, width int,
+) ([]byte, error) {
+	doc, err := buildDocument(ctx, templateEntity, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	viewportW, _ := doc.Layout.ViewportCssPixels()
+	png, err := captureDocument(ctx, doc, screenshot{
+		Format: page.CaptureScreenshotFormatPng,
+		Page:   pageNum,
+		Scale:  float64(width) / float64(viewportW),
+	})
+	if err != nil && !errors.Is(err, ErrPreviewPageOutOfRange) {
+		return nil, fmt.Errorf("failed to render preview: %w", err)
+	}
+	return png, err
+}
+
+// previewHash is the cache address of a PNG preview; like generateHash it
+// covers everything that changes the pixels.
+func previewHash(templateEntity *entities.Template, data map[string]interface{}, opts GenerateOptions, pageNum, width int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("png:%d:%d:%s", pageNum, width, generateHash(templateEntity, data, opts))))
+	return hex.EncodeToString(sum[:])
+}
+
+// PreviewPNG returns a PNG preview of a page, from the shared result cache
+// when the same preview was rendered before, and its storage URL. With
+// opts.NoCache the preview is rendered and returned without being stored.
+func PreviewPNG(
+	ctx context.Context,
+	templateEntity *entities.Template,
+	data map[string]interface{},
+	opts GenerateOptions,
+	pageNum, width int,
+) ([]byte, *GenerateResult, error) {
+	if opts.NoCache {
+		png, err := RenderPreviewPNG(ctx, templateEntity, data, opts, pageNum, width)
+		return png, nil, err
+	}
+
+	opts = opts.withTemplateDefaults(templateEntity)
+	hash := previewHash(templateEntity, data, opts, pageNum, width)
+
+	store, err := getStorageInstance()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	if cached, found := cacheLookup(hash); found {
+		if png, err := readObject(ctx, cached.ObjectKey); err == nil {
+			return png, &GenerateResult{URL: cached.URL, ObjectKey: cached.ObjectKey, CacheHit: true}, nil
+		}
+	}
+
+	png, err := RenderPreviewPNG(ctx, templateEntity, data, opts, pageNum, width)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storagePath := fmt.Sprintf("previews/%s.png", uuid.New().String())
+	url, err := store.Put(ctx, storagePath, bytes.NewReader(png), int64(len(png)), "image/png")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to upload preview: %w", err)
+	}
+	cacheStore(hash, storagePath, url, int64(len(png)))
+
+	return png, &GenerateResult{URL: url, ObjectKey: storagePath}, nil
+}
+
+func readObject(ctx context.Context, objectKey string) ([]byte, error) {
+	store, err := getStorageInstance()
+	if err != nil {
+		return nil, err
+	}
+	rc, err := store.Get(ctx, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+var thumbnailRefresh = struct {
+	sync.Mutex
+	// pending is set for templates whose refresh is running; true means it
+	// changed again meanwhile and must be refreshed once more.
+	pending map[uint]bool
+}{pending: map[uint]bool{}}
+
+// RefreshThumbnail regenerates in the background the page 1 thumbnail of a
+// template draft, rendered with its sample variables, and stores its URL on
+// the template. Calls for a template already refreshing are coalesced.
+func RefreshThumbnail(templateID uint) {
+	thumbnailRefresh.Lock()
+	if _, running := thumbnailRefresh.pending[templateID]; running {
+		thumbnailRefresh.pending[templateID] = true
+		thumbnailRefresh.Unlock()
+		return
+	}
+	thumbnailRefresh.pending[templateID] = false
+	thumbnailRefresh.Unlock()
+
+	go func() {
+		for {
+			if err := refreshThumbnail(templateID); err != nil {
+				fmt.Printf("warning: failed to refresh thumbnail of template %d: %v\n", templateID, err)
+			}
+
+			thumbnailRefresh.Lock()
+			if !thumbnailRefresh.pending[templateID] {
+				delete(thumbnailRefresh.pending, templateID)
+				thumbnailRefresh.Unlock()
+				return
+			}
+			thumbnailRefresh.pending[templateID] = false
+			thumbnailRefresh.Unlock()
+		}
+	}()
+}
+
+func refreshThumbnail(templateID uint) error {
+	templateSvc := template.NewService(template.Repository{})
+	templateEntity, err := templateSvc.Get(templateID)
+	if err != nil {
+		return err
+	}
+	if templateEntity, _, err = templateSvc.ResolveByUUID(templateEntity.UUID, template.VersionDraft); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, result, err := PreviewPNG(ctx, templateEntity, SampleData(templateEntity), GenerateOptions{}, 1, DefaultThumbnailWidth)
+	if err != nil {
+		return err
+	}
+	if result.URL == templateEntity.ThumbnailURL {
+		return nil
+	}
+	_, err = templateSvc.UpdateFull(templateID, map[string]interface{}{"thumbnail_url": result.URL})
+	return err
+}
@@ -0,0 +1,36 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/pkg/entities"
+	"strings"
+	"testing"
+
+	"gorm.io/datatypes"
+)
+
+func TestRenderPreviewHTML_UsesSampleData(t *testing.T) {
+	t.Setenv("TAILWIND_BIN", "")
+	t.Setenv("PATH", "")
+
+	tpl := &entities.Template{
+		Content:   `<h1>{{customer.name}}</h1>`,
+		Variables: datatypes.JSON(`{"customer":{"name":"ACME"}}`),
+	}
+	html, err := RenderPreviewHTML(context.Background(), tpl, SampleData(tpl), GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, `<div class="content"><h1>ACME</h1></div>`) {
+		t.Errorf("preview does not contain the rendered body:\n%s", html)
+	}
+}
+
+func TestSampleData_IgnoresInvalidVariables(t *testing.T) {
+	for _, raw := range []string{"", "null", "[1,2]", "{"} {
+		tpl := &entities.Template{Variables: datatypes.JSON(raw)}
+		if data := SampleData(tpl); data == nil {
+			t.Errorf("SampleData(%q) = nil", raw)
+		}
+	}
+}
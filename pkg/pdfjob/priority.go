@@ -0,0 +1,75 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/amqp"
+	"designmypdf/pkg/entities"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Priorities of async jobs; each is served by its own queue lane.
+const (
+	PriorityHigh   = amqp.LaneHigh
+	PriorityNormal = amqp.LaneNormal
+	PriorityLow    = amqp.LaneLow
+)
+
+var (
+	// ErrInvalidPriority is returned for a priority other than high, normal
+	// or low.
+	ErrInvalidPriority = errors.New("invalid priority")
+	// ErrPriorityNotAllowed is returned when a request asks for a priority
+	// above the one of its key.
+	ErrPriorityNotAllowed = errors.New("priority not allowed for this key")
+)
+
+// ParsePriority normalizes a priority such as "HIGH"; empty stays empty.
+func ParsePriority(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case "", PriorityHigh, PriorityNormal, PriorityLow:
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: %q (want high, normal or low)", ErrInvalidPriority, s)
+}
+
+// priorityRank orders priorities, the most urgent first.
+func priorityRank(priority string) int {
+	switch priority {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	}
+	return 1
+}
+
+// KeyPriority returns the priority of the plan of a key: the highest its
+// jobs may ask for, and the default of single jobs.
+func KeyPriority(keyEntity *entities.Key) string {
+	if keyEntity.Priority == "" {
+		return PriorityNormal
+	}
+	return keyEntity.Priority
+}
+
+// ResolvePriority returns the priority of a job queued with keyEntity:
+// requested, which may not exceed the key priority, or by default the key
+// priority for a single job and low for a batch.
+func ResolvePriority(requested string, keyEntity *entities.Key, batch bool) (string, error) {
+	priority, err := ParsePriority(requested)
+	if err != nil {
+		return "", err
+	}
+	plan := KeyPriority(keyEntity)
+	if priority == "" {
+		if batch {
+			return PriorityLow, nil
+		}
+		return plan, nil
+	}
+	if priorityRank(priority) < priorityRank(plan) {
+		return "", fmt.Errorf("%w: %s (at most %s)", ErrPriorityNotAllowed, priority, plan)
+	}
+	return priority, nil
+}
@@ -0,0 +1,36 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/entities"
+	"errors"
+	"testing"
+)
+
+func TestResolvePriority(t *testing.T) {
+	normalKey := &entities.Key{}
+	highKey := &entities.Key{Priority: PriorityHigh}
+	for _, tc := range []struct {
+		name      string
+		requested string
+		key       *entities.Key
+		batch     bool
+		want      string
+	}{
+		{"single job default", "", normalKey, false, PriorityNormal},
+		{"single job of a high plan", "", highKey, false, PriorityHigh},
+		{"batch default", "", highKey, true, PriorityLow},
+		{"lower than the plan", "low", normalKey, false, PriorityLow},
+		{"batch up to the plan", "High", highKey, true, PriorityHigh},
+	} {
+		got, err := ResolvePriority(tc.requested, tc.key, tc.batch)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: ResolvePriority() = %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := ResolvePriority("high", normalKey, false); !errors.Is(err, ErrPriorityNotAllowed) {
+		t.Errorf("high on a normal key: err = %v, want ErrPriorityNotAllowed", err)
+	}
+	if _, err := ResolvePriority("urgent", highKey, false); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("unknown priority: err = %v, want ErrInvalidPriority", err)
+	}
+}
@@ -0,0 +1,177 @@
+package pdfjob
+
+import (
+	"bytes"
+	"crypto/rand"
+	"designmypdf/pkg/secret"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// ErrInvalidProtection is returned for protection options that cannot be applied.
+var ErrInvalidProtection = errors.New("invalid PDF protection")
+
+// Permission restrictions of a protected PDF. PermissionAll lifts the
+// restrictions set as template default.
+const (
+	PermissionNoPrint  = "no_print"
+	PermissionNoCopy   = "no_copy"
+	PermissionNoModify = "no_modify"
+	PermissionAll      = "all"
+)
+
+// deniedFlags lists the permission bits each restriction clears.
+var deniedFlags = map[string]model.PermissionFlags{
+	PermissionNoPrint:  model.PermissionPrintRev2 | model.PermissionPrintRev3,
+	PermissionNoCopy:   model.PermissionExtract | model.PermissionExtractRev3,
+	PermissionNoModify: model.PermissionModify | model.PermissionModAnnFillForm | model.PermissionFillRev3 | model.PermissionAssembleRev3,
+}
+
+// ProtectOptions encrypts the rendered PDF (AES-256) with an optional user
+// password (required to open it) and restricts what readers may do with it.
+// Passwords are never marshalled: they stay out of the cache key, of queued
+// job options and of logs.
+type ProtectOptions struct {
+	UserPassword  string   `json:"-"`
+	OwnerPassword string   `json:"-"`
+	Permissions   []string `json:"permissions,omitempty"`
+}
+
+// ValidatePermissions reports unknown restrictions, or "all" mixed with others.
+func ValidatePermissions(permissions []string) error {
+	for _, p := range permissions {
+		switch {
+		case p == PermissionAll && len(permissions) > 1:
+			return fmt.Errorf("%w: %q cannot be combined with other permissions", ErrInvalidProtection, PermissionAll)
+		case p == PermissionAll:
+		case deniedFlags[p] == 0:
+			return fmt.Errorf("%w: unknown permission %q (want no_print, no_copy, no_modify or all)", ErrInvalidProtection, p)
+		}
+	}
+	return nil
+}
+
+// Validate reports options that can never be applied.
+func (o ProtectOptions) Validate() error {
+	return ValidatePermissions(o.Permissions)
+}
+
+// hasPasswords reports whether the caller chose a password, which makes the
+// output specific to the request.
+func (o *ProtectOptions) hasPasswords() bool {
+	return o != nil && (o.UserPassword != "" || o.OwnerPassword != "")
+}
+
+// active reports whether the PDF must be encrypted at all.
+func (o *ProtectOptions) active() bool {
+	return o.hasPasswords() || o.permissionFlags() != model.PermissionsAll
+}
+
+func (o *ProtectOptions) permissionFlags() model.PermissionFlags {
+	flags := model.PermissionsAll
+	if o == nil {
+		return flags
+	}
+	for _, p := range o.Permissions {
+		flags &^= deniedFlags[p]
+	}
+	return flags
+}
+
+// protectPdf encrypts pdf as requested by o. Without an owner password a
+// random one is used, so restrictions cannot be lifted by the reader.
+func protectPdf(pdf []byte, o *ProtectOptions) ([]byte, error) {
+	if !o.active() {
+		return pdf, nil
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	owner := o.OwnerPassword
+	if owner == "" {
+		random := make([]byte, 24)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		owner = hex.EncodeToString(random)
+	}
+	conf := model.NewAESConfiguration(o.UserPassword, owner, 256)
+	conf.Permissions = o.permissionFlags()
+	var out bytes.Buffer
+	if err := api.Encrypt(bytes.NewReader(pdf), &out, conf); err != nil {
+		return nil, fmt.Errorf("failed to encrypt PDF: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// withTemplatePermissions applies the template default restrictions unless
+// the request names its own. o is never modified.
+func withTemplatePermissions(o *ProtectOptions, defaults []string) *ProtectOptions {
+	if len(defaults) == 0 || (o != nil && len(o.Permissions) > 0) {
+		return o
+	}
+	p := ProtectOptions{}
+	if o != nil {
+		p = *o
+	}
+	p.Permissions = defaults
+	return &p
+}
+
+// ParsePermissions reads a comma-separated permission list such as
+// "no_print,no_copy".
+func ParsePermissions(value string) ([]string, error) {
+	var permissions []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			permissions = append(permissions, p)
+		}
+	}
+	return permissions, ValidatePermissions(permissions)
+}
+
+// jobPasswords is the sealed form of the passwords of a queued job.
+type jobPasswords struct {
+	User  string `json:"user,omitempty"`
+	Owner string `json:"owner,omitempty"`
+}
+
+// sealPasswords seals the passwords of o for a queued job; nil when there
+// are none. It fails with secret.ErrNoKey when ENCRYPTION_KEY is not set.
+func sealPasswords(o *ProtectOptions) ([]byte, error) {
+	if !o.hasPasswords() {
+		return nil, nil
+	}
+	b, err := json.Marshal(jobPasswords{User: o.UserPassword, Owner: o.OwnerPassword})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Seal(b)
+}
+
+// openPasswords restores on o the passwords sealed by sealPasswords.
+func openPasswords(o *ProtectOptions, sealed []byte) (*ProtectOptions, error) {
+	if len(sealed) == 0 {
+		return o, nil
+	}
+	b, err := secret.Open(sealed)
+	if err != nil {
+		return nil, err
+	}
+	var passwords jobPasswords
+	if err := json.Unmarshal(b, &passwords); err != nil {
+		return nil, err
+	}
+	p := ProtectOptions{}
+	if o != nil {
+		p = *o
+	}
+	p.UserPassword, p.OwnerPassword = passwords.User, passwords.Owner
+	return &p, nil
+}
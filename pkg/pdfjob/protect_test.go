@@ -0,0 +1,124 @@
+package pdfjob
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// onePagePDF is a minimal PDF with a classic cross-reference table.
+func onePagePDF() []byte {
+	objects := []string{
+		"<</Type /Catalog /Pages 2 0 R>>",
+		"<</Type /Pages /Kids [3 0 R] /Count 1>>",
+		"<</Type /Page /Parent 2 0 R /MediaBox [0 0 595 842]>>",
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<</Size %d /Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func TestProtectPdf_UserPasswordAndPermissions(t *testing.T) {
+	protected, err := protectPdf(onePagePDF(), &ProtectOptions{
+		UserPassword: "open-me",
+		Permissions:  []string{PermissionNoPrint, PermissionNoCopy},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.ReadContext(bytes.NewReader(protected), model.NewDefaultConfiguration()); err == nil {
+		t.Fatal("protected PDF opened without its password")
+	}
+
+	conf := model.NewDefaultConfiguration()
+	conf.UserPW = "open-me"
+	p, err := api.GetPermissions(bytes.NewReader(protected), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := model.PermissionFlags(uint16(*p))
+	if flags&model.PermissionPrintRev3 != 0 || flags&model.PermissionExtract != 0 {
+		t.Errorf("print/copy still allowed: %012b", flags)
+	}
+	if flags&model.PermissionModify == 0 {
+		t.Errorf("modify should stay allowed: %012b", flags)
+	}
+}
+
+func TestProtectPdf_NoRestrictionsIsNoop(t *testing.T) {
+	in := onePagePDF()
+	for _, o := range []*ProtectOptions{nil, {}, {Permissions: []string{PermissionAll}}} {
+		out, err := protectPdf(in, o)
+		if err != nil || !bytes.Equal(out, in) {
+			t.Errorf("protectPdf(%+v) changed the PDF (err %v)", o, err)
+		}
+	}
+}
+
+func TestProtectOptions_Validate(t *testing.T) {
+	for _, permissions := range [][]string{{"no_fly"}, {PermissionAll, PermissionNoPrint}} {
+		if err := (ProtectOptions{Permissions: permissions}).Validate(); err == nil {
+			t.Errorf("Validate(%v) should fail", permissions)
+		}
+	}
+	opts := GenerateOptions{Sign: &SignOptions{}, Protect: &ProtectOptions{UserPassword: "x"}}
+	if err := opts.Validate(); err == nil {
+		t.Error("signed and protected options should fail")
+	}
+}
+
+func TestProtectOptions_PasswordsStayOutOfJSON(t *testing.T) {
+	b, _ := json.Marshal(GenerateOptions{Protect: &ProtectOptions{UserPassword: "s3cret", OwnerPassword: "0wner", Permissions: []string{PermissionNoCopy}}})
+	if strings.Contains(string(b), "s3cret") || strings.Contains(string(b), "0wner") || !strings.Contains(string(b), PermissionNoCopy) {
+		t.Errorf("marshalled options = %s", b)
+	}
+}
+
+func TestWithTemplatePermissions(t *testing.T) {
+	defaults := []string{PermissionNoPrint}
+	request := &ProtectOptions{UserPassword: "x"}
+	got := withTemplatePermissions(request, defaults)
+	if got.UserPassword != "x" || len(got.Permissions) != 1 || len(request.Permissions) != 0 {
+		t.Errorf("defaults not applied to a copy: %+v, request %+v", got, request)
+	}
+	override := &ProtectOptions{Permissions: []string{PermissionAll}}
+	if got := withTemplatePermissions(override, defaults); got != override {
+		t.Errorf("request permissions should win: %+v", got)
+	}
+}
+
+func TestSealPasswords(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-secret")
+	sealed, err := sealPasswords(&ProtectOptions{UserPassword: "u", OwnerPassword: "o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := openPasswords(&ProtectOptions{Permissions: []string{PermissionNoPrint}}, sealed)
+	if err != nil || got.UserPassword != "u" || got.OwnerPassword != "o" || len(got.Permissions) != 1 {
+		t.Errorf("openPasswords() = %+v, %v", got, err)
+	}
+
+	t.Setenv("ENCRYPTION_KEY", "")
+	if _, err := sealPasswords(&ProtectOptions{UserPassword: "u"}); err == nil {
+		t.Error("sealing without ENCRYPTION_KEY should fail")
+	}
+	if sealed, err := sealPasswords(&ProtectOptions{Permissions: []string{PermissionNoPrint}}); sealed != nil || err != nil {
+		t.Errorf("no passwords: sealPasswords() = %v, %v", sealed, err)
+	}
+}
@@ -3,12 +3,27 @@ package pdfjob
 import (
 	"designmypdf/config/database"
 	"designmypdf/pkg/entities"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// outboxClaimTTL is how long an outbox row stays claimed by a relay; it
+// exceeds the publish of a whole batch (outboxBatchSize confirms).
+const outboxClaimTTL = 5 * time.Minute
+
 type Repository struct{}
 
+// Create inserts a job and its outbox row in one transaction.
 func (r Repository) Create(job *entities.PdfGenerationJob) error {
-	return database.DB.Create(job).Error
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return tx.Create(&entities.PdfJobOutbox{JobID: job.ID, Priority: job.Priority}).Error
+	})
 }
 
 func (r Repository) GetByID(id string) (*entities.PdfGenerationJob, error) {
@@ -26,3 +41,399 @@ func (r Repository) UpdateStatus(id string, status entities.JobStatus, resultPat
 			"error_message": errMsg,
 		}).Error
 }
+
+// MarkCompleted stores the result URL and object key of a finished job.
+func (r Repository) MarkCompleted(id, resultPath, resultKey string, cacheHit bool) error {
+	return database.DB.Model(&entities.PdfGenerationJob{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        entities.JobStatusCompleted,
+			"result_path":   resultPath,
+			"result_key":    resultKey,
+			"cache_hit":     cacheHit,
+			"error_message": "",
+		}).Error
+}
+
+// ScheduleRetry moves a job that failed an attempt to retrying and adds, in
+// the same transaction, the outbox row that publishes it again after delay.
+func (r Repository) ScheduleRetry(id, priority, errMsg string, delay time.Duration) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entities.PdfGenerationJob{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"status":        entities.JobStatusRetrying,
+				"error_message": errMsg,
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&entities.PdfJobOutbox{JobID: id, Priority: priority, Delay: delay}).Error
+	})
+}
+
+// StartAttempt claims a job for workerID: it moves the job to running and
+// counts the attempt, unless the job is settled or running elsewhere (last
+// updated after staleBefore). It returns the attempt number and whether the
+// job was claimed.
+func (r Repository) StartAttempt(id, workerID string, staleBefore time.Time) (int, bool, error) {
+	res := database.DB.Model(&entities.PdfGenerationJob{}).
+		Where("id = ?", id).
+		Where("status IN ? OR (status = ? AND updated_at < ?)",
+			[]entities.JobStatus{entities.JobStatusQueued, entities.JobStatusRetrying}, entities.JobStatusRunning, staleBefore).
+		Updates(map[string]interface{}{
+			"status":    entities.JobStatusRunning,
+			"attempts":  gorm.Expr("attempts + 1"),
+			"worker_id": workerID,
+		})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return 0, false, res.Error
+	}
+	var attempts int
+	err := database.DB.Model(&entities.PdfGenerationJob{}).
+		Where("id = ?", id).
+		Pluck("attempts", &attempts).Error
+	return attempts, true, err
+}
+
+// ListStaleRunning returns the IDs of the jobs still running that were last
+// updated before the given time.
+func (r Repository) ListStaleRunning(before time.Time) ([]string, error) {
+	var ids []string
+	err := database.DB.Model(&entities.PdfGenerationJob{}).
+		Where("status = ? AND updated_at < ?", entities.JobStatusRunning, before).
+		Order("created_at ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// RequeueStale moves a job back to queued, with an outbox row, if it is
+// still running and was last updated before the given time. It returns
+// false when another worker changed it meanwhile.
+func (r Repository) RequeueStale(id string, before time.Time) (bool, error) {
+	return r.requeue(id, "status = ? AND updated_at < ?", entities.JobStatusRunning, before)
+}
+
+// ListRunningOwnedBy returns the IDs of the jobs running on workerID.
+func (r Repository) ListRunningOwnedBy(workerID string) ([]string, error) {
+	var ids []string
+	err := database.DB.Model(&entities.PdfGenerationJob{}).
+		Where("status = ? AND worker_id = ?", entities.JobStatusRunning, workerID).
+		Order("created_at ASC").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// RequeueOwned moves a job back to queued, with an outbox row, if it is
+// still running on workerID. It returns false when the job changed
+// meanwhile.
+func (r Repository) RequeueOwned(id, workerID string) (bool, error) {
+	return r.requeue(id, "status = ? AND worker_id = ?", entities.JobStatusRunning, workerID)
+}
+
+// ListStaleQueued returns at most limit jobs, with their ID and status only,
+// that have no publication pending and were last updated while queued
+// before queuedBefore or while retrying before retryingBefore.
+func (r Repository) ListStaleQueued(queuedBefore, retryingBefore time.Time, limit int) ([]entities.PdfGenerationJob, error) {
+	var jobs []entities.PdfGenerationJob
+	err := database.DB.Select("id, status").
+		Where("(status = ? AND updated_at < ?) OR (status = ? AND updated_at < ?)",
+			entities.JobStatusQueued, queuedBefore, entities.JobStatusRetrying, retryingBefore).
+		Where("NOT EXISTS (?)", database.DB.Model(&entities.PdfJobOutbox{}).
+			Select("1").Where("pdf_job_outboxes.job_id = pdf_generation_jobs.id")).
+		Order("updated_at ASC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// RepublishStale adds an outbox row for a job still in the given status
+// (queued or retrying) and last updated before the given time, and moves it
+// to queued so it is not picked again before it gets another chance. It
+// returns false when the job changed meanwhile.
+func (r Repository) RepublishStale(id string, status entities.JobStatus, before time.Time) (bool, error) {
+	return r.requeue(id, "status = ? AND updated_at < ?", status, before)
+}
+
+// requeue moves job id to queued, with an outbox row, if it matches query.
+func (r Repository) requeue(id string, query string, args ...interface{}) (bool, error) {
+	requeued := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&entities.PdfGenerationJob{}).
+			Where("id = ?", id).
+			Where(query, args...).
+			Updates(map[string]interface{}{
+				"status":     entities.JobStatusQueued,
+				"worker_id":  "",
+				"updated_at": time.Now(),
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		requeued = true
+		var priority string
+		err := tx.Model(&entities.PdfGenerationJob{}).Where("id = ?", id).Pluck("priority", &priority).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&entities.PdfJobOutbox{JobID: id, Priority: priority}).Error
+	})
+	return requeued, err
+}
+
+// RelayOutbox publishes the oldest pending outbox rows, at most limit, and
+// deletes each one the broker confirmed. It stops at the first publish
+// error, recorded on the row, and returns the number of rows published.
+//
+// The rows are claimed first, in a short update, then published outside of
+// any transaction: a slow broker holds neither locks nor a connection. A row
+// claimed for more than outboxClaimTTL is taken over by the next relay, so
+// the rows of a relay that died are published again. Only plain conditional
+// updates are used, which every MySQL and Postgres version supports.
+func (r Repository) RelayOutbox(limit int, publish func(row entities.PdfJobOutbox) error) (int, error) {
+	owner := uuid.New().String()
+	rows, err := r.claimOutbox(owner, limit, time.Now().Add(-outboxClaimTTL))
+	if err != nil {
+		return 0, err
+	}
+	published := 0
+	for i, row := range rows {
+		if pubErr := publish(row); pubErr != nil {
+			err := database.DB.Model(&row).Updates(map[string]interface{}{
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": pubErr.Error(),
+			}).Error
+			if err != nil {
+				fmt.Printf("warning: failed to record outbox publish error of job %s: %v\n", row.JobID, err)
+			}
+			r.releaseOutbox(owner, rows[i:])
+			return published, pubErr
+		}
+		if err := database.DB.Delete(&row).Error; err != nil {
+			r.releaseOutbox(owner, rows[i+1:])
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// claimOutbox marks at most limit pending rows, oldest first, as claimed by
+// owner and returns them. Rows claimed before staleBefore are pending again.
+func (r Repository) claimOutbox(owner string, limit int, staleBefore time.Time) ([]entities.PdfJobOutbox, error) {
+	var ids []uint
+	err := database.DB.Model(&entities.PdfJobOutbox{}).
+		Where("claimed_at IS NULL OR claimed_at < ?", staleBefore).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	// Another relay may claim some of them first: keep those we won.
+	err = database.DB.Model(&entities.PdfJobOutbox{}).
+		Where("id IN ? AND (claimed_at IS NULL OR claimed_at < ?)", ids, staleBefore).
+		Updates(map[string]interface{}{
+			"claimed_by": owner,
+			"claimed_at": time.Now(),
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+	var rows []entities.PdfJobOutbox
+	err = database.DB.Where("id IN ? AND claimed_by = ?", ids, owner).
+		Order("id ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// releaseOutbox makes rows claimed by owner but not published pending again.
+func (r Repository) releaseOutbox(owner string, rows []entities.PdfJobOutbox) {
+	if len(rows) == 0 {
+		return
+	}
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	err := database.DB.Model(&entities.PdfJobOutbox{}).
+		Where("id IN ? AND claimed_by = ?", ids, owner).
+		Updates(map[string]interface{}{
+			"claimed_by": "",
+			"claimed_at": nil,
+		}).Error
+	if err != nil {
+		// They are taken over once their claim expires.
+		fmt.Printf("warning: failed to release outbox rows: %v\n", err)
+	}
+}
+
+// DeadLetter fails a job that ran out of attempts, marks it dead-lettered
+// and adds, in the same transaction, the outbox row that moves it to the
+// dead-letter queue.
+func (r Repository) DeadLetter(id, priority, errMsg string) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&entities.PdfGenerationJob{}).
+			Where("id = ?", id).
+			Updates(map[string]interface{}{
+				"status":           entities.JobStatusFailed,
+				"error_message":    errMsg,
+				"dead_lettered_at": time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&entities.PdfJobOutbox{JobID: id, Priority: priority, DeadLetter: true, Reason: errMsg}).Error
+	})
+}
+
+// ListDeadLettered returns the dead-lettered jobs without their payloads,
+// oldest first; ids restricts the list when not empty.
+func (r Repository) ListDeadLettered(ids []string) ([]entities.PdfGenerationJob, error) {
+	var jobs []entities.PdfGenerationJob
+	q := database.DB.
+		Select("id, key_id, template_uuid, format, output, priority, status, attempts, error_message, batch_id, dead_lettered_at, created_at, updated_at").
+		Where("dead_lettered_at IS NOT NULL")
+	if len(ids) > 0 {
+		q = q.Where("id IN ?", ids)
+	}
+	err := q.Order("dead_lettered_at ASC, id ASC").Find(&jobs).Error
+	return jobs, err
+}
+
+// ResetForReplay puts a dead-lettered job back in the queued state with a
+// fresh attempt count, and adds its outbox row in the same transaction. A
+// batch child no longer counts as failed and reopens its batch, which
+// completes again once the job finishes. It returns false when the job is
+// not dead-lettered.
+func (r Repository) ResetForReplay(id string) (bool, error) {
+	replayed := false
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var job entities.PdfGenerationJob
+		res := tx.Select("id, batch_id, priority").
+			Where("id = ? AND dead_lettered_at IS NOT NULL", id).
+			Limit(1).Find(&job)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		res = tx.Model(&entities.PdfGenerationJob{}).
+			Where("id = ? AND dead_lettered_at IS NOT NULL", id).
+			Updates(map[string]interface{}{
+				"status":           entities.JobStatusQueued,
+				"attempts":         0,
+				"error_message":    "",
+				"dead_lettered_at": nil,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		replayed = true
+		if err := tx.Create(&entities.PdfJobOutbox{JobID: id, Priority: job.Priority}).Error; err != nil {
+			return err
+		}
+		if job.BatchID == nil {
+			return nil
+		}
+		return tx.Model(&entities.PdfGenerationBatch{}).
+			Where("id = ?", *job.BatchID).
+			Updates(map[string]interface{}{
+				"failed_count": gorm.Expr("CASE WHEN failed_count > 0 THEN failed_count - 1 ELSE 0 END"),
+				"status":       entities.JobStatusRunning,
+				"completed_at": nil,
+			}).Error
+	})
+	return replayed, err
+}
+
+// CreateBatch inserts a batch and all of its child jobs, with their outbox
+// rows, in one transaction.
+func (r Repository) CreateBatch(batch *entities.PdfGenerationBatch, jobs []entities.PdfGenerationJob) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Jobs").Create(batch).Error; err != nil {
+			return err
+		}
+		if err := tx.CreateInBatches(jobs, 500).Error; err != nil {
+			return err
+		}
+		outbox := make([]entities.PdfJobOutbox, len(jobs))
+		for i := range jobs {
+			outbox[i].JobID = jobs[i].ID
+			outbox[i].Priority = jobs[i].Priority
+		}
+		return tx.CreateInBatches(outbox, 500).Error
+	})
+}
+
+func (r Repository) GetBatchByID(id string) (*entities.PdfGenerationBatch, error) {
+	var batch entities.PdfGenerationBatch
+	err := database.DB.First(&batch, "id = ?", id).Error
+	return &batch, err
+}
+
+// ListBatchJobs returns the child jobs of a batch without their payloads.
+func (r Repository) ListBatchJobs(batchID string) ([]entities.PdfGenerationJob, error) {
+	var jobs []entities.PdfGenerationJob
+	err := database.DB.
+		Select("id, key_id, template_uuid, format, output, priority, status, result_path, result_key, cache_hit, error_message, attempts, batch_id, created_at, updated_at").
+		Where("batch_id = ?", batchID).
+		Order("created_at ASC, id ASC").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+type batchStatusCount struct {
+	Status entities.JobStatus
+	Count  int64
+}
+
+// CountBatchJobsByStatus returns how many child jobs are in each status.
+func (r Repository) CountBatchJobsByStatus(batchID string) (map[entities.JobStatus]int64, error) {
+	var rows []batchStatusCount
+	err := database.DB.Model(&entities.PdfGenerationJob{}).
+		Select("status, COUNT(*) AS count").
+		Where("batch_id = ?", batchID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[entities.JobStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+// IncrementBatchCounter atomically bumps completed_count or failed_count and
+// moves a queued batch to running.
+func (r Repository) IncrementBatchCounter(batchID string, succeeded bool) error {
+	column := "failed_count"
+	if succeeded {
+		column = "completed_count"
+	}
+	return database.DB.Model(&entities.PdfGenerationBatch{}).
+		Where("id = ?", batchID).
+		Updates(map[string]interface{}{
+			column:   gorm.Expr(column + " + 1"),
+			"status": gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", entities.JobStatusQueued, entities.JobStatusRunning),
+		}).Error
+}
+
+// ClaimBatchCompletion marks a batch finished once every child is accounted for.
+// It returns true for exactly one caller, which then owns the completion side effects.
+func (r Repository) ClaimBatchCompletion(batchID string, status entities.JobStatus) (bool, error) {
+	now := time.Now()
+	res := database.DB.Model(&entities.PdfGenerationBatch{}).
+		Where("id = ? AND completed_at IS NULL AND completed_count + failed_count >= total_count", batchID).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"completed_at": &now,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r Repository) SetBatchZipPath(batchID, zipPath string) error {
+	return database.DB.Model(&entities.PdfGenerationBatch{}).
+		Where("id = ?", batchID).
+		Update("zip_path", zipPath).Error
+}
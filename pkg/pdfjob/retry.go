@@ -0,0 +1,169 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/pkg/amqp"
+	"designmypdf/pkg/entities"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	defaultJobMaxAttempts   = 5
+	defaultJobRetryDelay    = 10 * time.Second
+	defaultJobRetryMaxDelay = 10 * time.Minute
+)
+
+// ErrRedeliver is returned by ProcessJob when a failed attempt could not be
+// scheduled for retry: the worker hands the message back to RabbitMQ instead
+// of acknowledging it.
+var ErrRedeliver = errors.New("job must be redelivered")
+
+// transientError marks a failure that may not happen again, such as an
+// unreachable object store or a crashed Chrome tab.
+type transientError struct{ err error }
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// transient marks err as retryable; nil stays nil.
+func transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+// chromeError marks as transient the failures of a Chrome tab that may not
+// happen again: a timeout, a crashed or closed tab, a lost connection to
+// Chrome. Failures caused by the document itself (script error, invalid
+// page setup, unreachable URL) fail the same way on every attempt.
+func chromeError(err error) error {
+	if isChromeTransient(err) {
+		return transient(err)
+	}
+	return err
+}
+
+func isChromeTransient(err error) bool {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled),
+		errors.Is(err, ErrBrowserPoolBusy),
+		errors.Is(err, chromedp.ErrChannelClosed), errors.Is(err, chromedp.ErrInvalidTarget),
+		errors.Is(err, chromedp.ErrInvalidContext),
+		errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed):
+		return true
+	}
+	// Reported by Chrome as protocol errors.
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "target crashed") || strings.Contains(msg, "target closed")
+}
+
+// IsRetryable reports whether a failed job may succeed when attempted again.
+// Everything else (invalid payload, template errors, certificates, PDF/A
+// conversion) fails the same way every time.
+func IsRetryable(err error) bool {
+	var t *transientError
+	return errors.As(err, &t) ||
+		errors.Is(err, ErrBrowserPoolBusy) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryPolicy bounds the attempts of an async job.
+type RetryPolicy struct {
+	// MaxAttempts counts the first attempt; a job failing the last one is
+	// dead-lettered.
+	MaxAttempts int
+	// Delay is the wait before the second attempt, doubled for each next one
+	// up to MaxDelay.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+// retryPolicyFromEnv reads PDF_JOB_MAX_ATTEMPTS, PDF_JOB_RETRY_DELAY and
+// PDF_JOB_RETRY_MAX_DELAY.
+func retryPolicyFromEnv() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: envInt("PDF_JOB_MAX_ATTEMPTS", defaultJobMaxAttempts, 1),
+		Delay:       envDuration("PDF_JOB_RETRY_DELAY", defaultJobRetryDelay),
+		MaxDelay:    envDuration("PDF_JOB_RETRY_MAX_DELAY", defaultJobRetryMaxDelay),
+	}
+}
+
+// Backoff returns the wait before the attempt following failed attempt
+// number attempt (1-based).
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	d := p.Delay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+// ListDeadLetters returns the jobs that failed their last attempt and have
+// not been replayed, oldest first.
+func (s *Service) ListDeadLetters() ([]entities.PdfGenerationJob, error) {
+	return s.repo.ListDeadLettered(nil)
+}
+
+// ReplayDeadLetters queues dead-lettered jobs again with a fresh attempt
+// count: those of ids, or all of them when ids is empty. Like new jobs, they
+// are published through the outbox; their dead-letter messages are dropped.
+// It returns the IDs of the jobs replayed.
+func (s *Service) ReplayDeadLetters(ids []string) ([]string, error) {
+	var selected map[string]bool
+	if len(ids) > 0 {
+		selected = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			selected[id] = true
+		}
+	}
+
+	var replayed []string
+	defer func() {
+		if len(replayed) > 0 {
+			s.wakeOutbox()
+		}
+	}()
+	err := s.amqpClient.ReplayDeadLetters(func(jobID string) amqp.DeadLetterAction {
+		if selected != nil && !selected[jobID] {
+			return amqp.DeadLetterKeep
+		}
+		reset, err := s.repo.ResetForReplay(jobID)
+		if err != nil {
+			fmt.Printf("warning: failed to reset dead-lettered job %s: %v\n", jobID, err)
+			return amqp.DeadLetterKeep
+		}
+		if reset {
+			replayed = append(replayed, jobID)
+		}
+		// Replayed, already replayed or a duplicate message: the outbox
+		// publishes the job.
+		return amqp.DeadLetterDrop
+	})
+	if err != nil {
+		return replayed, err
+	}
+
+	// Jobs whose dead-letter message was never published.
+	jobs, err := s.repo.ListDeadLettered(ids)
+	if err != nil {
+		return replayed, err
+	}
+	for _, job := range jobs {
+		reset, err := s.repo.ResetForReplay(job.ID)
+		if err != nil {
+			return replayed, err
+		}
+		if reset {
+			replayed = append(replayed, job.ID)
+		}
+	}
+	return replayed, nil
+}
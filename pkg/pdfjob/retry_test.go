@@ -0,0 +1,162 @@
+package pdfjob
+
+import (
+	"context"
+	"designmypdf/config/database"
+	"designmypdf/pkg/entities"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+func TestIsRetryable(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"upload", transient(fmt.Errorf("failed to upload PDF: %w", errors.New("503"))), true},
+		{"wrapped upload", fmt.Errorf("job: %w", transient(errors.New("timeout"))), true},
+		{"browser pool busy", ErrBrowserPoolBusy, true},
+		{"deadline", fmt.Errorf("render: %w", context.DeadlineExceeded), true},
+		{"template", errors.New("failed to render template: parse error"), false},
+		{"image options", ErrInvalidImageOptions, false},
+	} {
+		if got := IsRetryable(tc.err); got != tc.want {
+			t.Errorf("%s: IsRetryable(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+	if transient(nil) != nil {
+		t.Error("transient(nil) != nil")
+	}
+	if err := transient(ErrBrowserPoolBusy); !errors.Is(err, ErrBrowserPoolBusy) || err.Error() != ErrBrowserPoolBusy.Error() {
+		t.Errorf("transient() changed the error: %v", err)
+	}
+}
+
+func TestChromeError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"tab deadline", context.DeadlineExceeded, true},
+		{"crashed tab", errors.New("Target crashed (-32000)"), true},
+		{"closed tab", chromedp.ErrInvalidTarget, true},
+		{"lost connection", io.EOF, true},
+		{"script error", errors.New("exception \"Uncaught ReferenceError: foo is not defined\""), false},
+		{"page setup", errors.New("invalid print parameters: content area is empty (-32000)"), false},
+		{"navigation", errors.New("page load error net::ERR_NAME_NOT_RESOLVED"), false},
+	} {
+		err := chromeError(fmt.Errorf("failed to generate PDF: %w", tc.err))
+		if got := IsRetryable(err); got != tc.want {
+			t.Errorf("%s: IsRetryable(chromeError(%v)) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 10, Delay: 10 * time.Second, MaxDelay: time.Minute}
+	for attempt, want := range map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	} {
+		if got := p.Backoff(attempt); got != want {
+			t.Errorf("Backoff(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyFromEnv(t *testing.T) {
+	t.Setenv("PDF_JOB_MAX_ATTEMPTS", "3")
+	t.Setenv("PDF_JOB_RETRY_DELAY", "2s")
+	t.Setenv("PDF_JOB_RETRY_MAX_DELAY", "bogus")
+	want := RetryPolicy{MaxAttempts: 3, Delay: 2 * time.Second, MaxDelay: defaultJobRetryMaxDelay}
+	if got := retryPolicyFromEnv(); got != want {
+		t.Errorf("retryPolicyFromEnv() = %+v, want %+v", got, want)
+	}
+}
+
+func TestScheduleRetryWritesOutbox(t *testing.T) {
+	useTestDB(t)
+	repo := Repository{}
+	job := &entities.PdfGenerationJob{ID: "job", TemplateUUID: "t", Priority: PriorityLow, Status: entities.JobStatusRunning}
+	if err := database.DB.Create(job).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ScheduleRetry(job.ID, job.Priority, "upload failed", 20*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetByID(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != entities.JobStatusRetrying || got.ErrorMessage != "upload failed" {
+		t.Errorf("job = %s %q, want retrying with its error", got.Status, got.ErrorMessage)
+	}
+	var row entities.PdfJobOutbox
+	if err := database.DB.First(&row, "job_id = ?", job.ID).Error; err != nil {
+		t.Fatalf("no outbox row: %v", err)
+	}
+	if row.Delay != 20*time.Second || row.Priority != PriorityLow {
+		t.Errorf("outbox row = %+v, want a 20s delay in the low lane", row)
+	}
+}
+
+func TestDeadLetterWritesOutbox(t *testing.T) {
+	useTestDB(t)
+	repo := Repository{}
+	job := &entities.PdfGenerationJob{ID: "job", TemplateUUID: "t", Priority: PriorityHigh, Status: entities.JobStatusRunning, Attempts: 5}
+	if err := database.DB.Create(job).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.DeadLetter(job.ID, job.Priority, "timeout (gave up after 5 attempts)"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetByID(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != entities.JobStatusFailed || got.DeadLetteredAt == nil || got.ErrorMessage != "timeout (gave up after 5 attempts)" {
+		t.Errorf("job = %s %q dead-lettered at %v, want failed and dead-lettered", got.Status, got.ErrorMessage, got.DeadLetteredAt)
+	}
+	var row entities.PdfJobOutbox
+	if err := database.DB.First(&row, "job_id = ?", job.ID).Error; err != nil {
+		t.Fatalf("no outbox row: %v", err)
+	}
+	if !row.DeadLetter || row.Reason != got.ErrorMessage || row.Priority != PriorityHigh || row.Delay != 0 {
+		t.Errorf("outbox row = %+v, want a dead-letter row in the high lane", row)
+	}
+}
+
+func TestResetForReplayWritesOutbox(t *testing.T) {
+	useTestDB(t)
+	repo := Repository{}
+	now := time.Now()
+	job := &entities.PdfGenerationJob{ID: "job", TemplateUUID: "t", Priority: PriorityHigh,
+		Status: entities.JobStatusFailed, Attempts: 5, DeadLetteredAt: &now}
+	if err := database.DB.Create(job).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{true, false} {
+		reset, err := repo.ResetForReplay(job.ID)
+		if err != nil || reset != want {
+			t.Fatalf("ResetForReplay() #%d = %v, %v; want %v", i+1, reset, err, want)
+		}
+	}
+	var rows []entities.PdfJobOutbox
+	database.DB.Find(&rows)
+	if len(rows) != 1 || rows[0].Priority != PriorityHigh || rows[0].Delay != 0 {
+		t.Errorf("outbox rows = %+v, want one immediate publish in the high lane", rows)
+	}
+}
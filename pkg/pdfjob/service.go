@@ -10,56 +10,96 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	jobTimeout               = 30 * time.Second
+	defaultRunningStaleAfter = 2 * time.Minute
 )
 
 type Service struct {
 	repo       Repository
 	amqpClient *amqp.Client
+	outboxWake chan struct{}
+	// workerID marks the jobs this process runs (see ResumeOwnJobs).
+	workerID string
 }
 
 func NewService(amqpClient *amqp.Client) *Service {
-	return &Service{repo: Repository{}, amqpClient: amqpClient}
+	return &Service{repo: Repository{}, amqpClient: amqpClient, outboxWake: make(chan struct{}, 1), workerID: workerIDFromEnv()}
 }
 
-// EnqueueJob persists a new job in queued state and publishes it to RabbitMQ.
-func (s *Service) EnqueueJob(keyID uint, templateUUID string, payload []byte, format string) (*entities.PdfGenerationJob, error) {
+// EnqueueJob persists a new job in queued state; RunOutbox publishes it to
+// the RabbitMQ lane of priority (see ResolvePriority).
+func (s *Service) EnqueueJob(keyID uint, templateUUID string, payload []byte, opts GenerateOptions, priority string) (*entities.PdfGenerationJob, error) {
+	passwords, err := sealPasswords(opts.Protect)
+	if err != nil {
+		return nil, err
+	}
 	job := &entities.PdfGenerationJob{
-		ID:           uuid.New().String(),
-		KeyID:        keyID,
-		TemplateUUID: templateUUID,
-		Payload:      payload,
-		Format:       format,
-		Status:       entities.JobStatusQueued,
+		ID:              uuid.New().String(),
+		KeyID:           keyID,
+		TemplateUUID:    templateUUID,
+		Payload:         payload,
+		Format:          opts.Format,
+		Output:          opts.JobOutput(),
+		Options:         marshalJobOptions(opts),
+		NoCache:         opts.NoCache,
+		SealedPasswords: passwords,
+		TemplateVersion: opts.TemplateVersion,
+		Priority:        priority,
+		Status:          entities.JobStatusQueued,
 	}
 
 	if err := s.repo.Create(job); err != nil {
 		return nil, fmt.Errorf("failed to create job: %w", err)
 	}
-
-	if err := s.amqpClient.Publish(job.ID); err != nil {
-		// Job is in DB — worker can be retried manually; log and continue.
-		fmt.Printf("warning: failed to publish job %s to queue: %v\n", job.ID, err)
-	}
+	s.wakeOutbox()
 
 	return job, nil
 }
 
 // ProcessJob is called by the worker for each message consumed from RabbitMQ.
+// A retryable failure is scheduled again with exponential backoff until the
+// job runs out of attempts; it is then moved to the dead-letter queue.
 func (s *Service) ProcessJob(jobID string) error {
 	job, err := s.repo.GetByID(jobID)
-	if err != nil {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return fmt.Errorf("job %s not found: %w", jobID, err)
 	}
+	if err != nil {
+		return fmt.Errorf("%w: failed to load job %s: %v", ErrRedeliver, jobID, err)
+	}
 
-	if err := s.repo.UpdateStatus(jobID, entities.JobStatusRunning, "", ""); err != nil {
-		fmt.Printf("warning: failed to mark job %s running: %v\n", jobID, err)
+	attempts, claimed, err := s.repo.StartAttempt(jobID, s.workerID, time.Now().Add(-runningStaleAfter()))
+	if err != nil {
+		return fmt.Errorf("%w: failed to mark job %s running: %v", ErrRedeliver, jobID, err)
+	}
+	if !claimed {
+		if job.Status == entities.JobStatusRunning {
+			// Running elsewhere, or left running by a worker that died:
+			// drop the duplicate. A dead worker's job is queued again by
+			// ResumeOwnJobs or, once stale, by RequeueInterruptedJobs.
+			fmt.Printf("worker: dropping duplicate message of job %s running on worker %q\n", jobID, job.WorkerID)
+		}
+		// Duplicate message of a settled job, or of one another worker
+		// claimed meanwhile.
+		return nil
 	}
+	job.Attempts = attempts
 
 	templateSvc := template.NewService(template.Repository{})
-	templateEntity, err := templateSvc.GetByUUID(job.TemplateUUID)
+	opts, err := jobGenerateOptions(job)
+	if err != nil {
+		return s.failJob(job, nil, err.Error())
+	}
+	templateEntity, _, err := templateSvc.ResolveByUUID(job.TemplateUUID, opts.TemplateVersion)
 	if err != nil {
 		return s.failJob(job, nil, fmt.Sprintf("template not found: %v", err))
 	}
@@ -71,15 +111,16 @@ func (s *Service) ProcessJob(jobID string) error {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
 	defer cancel()
 
-	pdfURL, err := GeneratePdfForKey(ctx, &job.Key, templateEntity, data, job.Format)
+	result, err := GeneratePdfForKey(ctx, &job.Key, templateEntity, data, opts)
 	if err != nil {
-		return s.failJob(job, templateEntity, err.Error())
+		return s.handleFailure(job, templateEntity, err)
 	}
+	pdfURL := result.URL
 
-	if err := s.repo.UpdateStatus(jobID, entities.JobStatusCompleted, pdfURL, ""); err != nil {
+	if err := s.repo.MarkCompleted(jobID, pdfURL, result.ObjectKey, result.CacheHit); err != nil {
 		fmt.Printf("warning: failed to mark job %s completed: %v\n", jobID, err)
 	}
 
@@ -89,8 +130,10 @@ func (s *Service) ProcessJob(jobID string) error {
 		job.TemplateUUID,
 		job.Payload,
 		map[string]interface{}{
-			"path":   pdfURL,
-			"job_id": job.ID,
+			"path":      pdfURL,
+			"job_id":    job.ID,
+			"cache_hit": result.CacheHit,
+			"output":    opts.JobOutput(),
 		},
 		entities.Success,
 		nil,
@@ -100,11 +143,149 @@ func (s *Service) ProcessJob(jobID string) error {
 	publisher.Publish(webhook.EventPdfJobCompleted, jobID, job.Key.UserID, job.KeyID, map[string]interface{}{
 		"path":          pdfURL,
 		"template_uuid": job.TemplateUUID,
+		"output":        opts.JobOutput(),
 	})
 
+	s.trackBatchProgress(job, true)
+
 	return nil
 }
 
+// ResumeOwnJobs queues again, right away, the jobs this worker left running
+// when it stopped: the worker calls it on start, before consuming. It
+// returns the number of jobs requeued.
+func (s *Service) ResumeOwnJobs() (int, error) {
+	if s.workerID == "" {
+		return 0, nil
+	}
+	ids, err := s.repo.ListRunningOwnedBy(s.workerID)
+	if err != nil {
+		return 0, err
+	}
+	requeued := 0
+	for _, id := range ids {
+		ok, err := s.repo.RequeueOwned(id, s.workerID)
+		if err != nil {
+			return requeued, err
+		}
+		if ok {
+			requeued++
+		}
+	}
+	if requeued > 0 {
+		s.wakeOutbox()
+	}
+	return requeued, nil
+}
+
+// RequeueInterruptedJobs queues again the jobs a stopped worker left
+// running. Only jobs not updated for WORKER_STALE_AFTER are taken, so that
+// jobs being processed by other workers are left alone. It returns the
+// number of jobs requeued.
+func (s *Service) RequeueInterruptedJobs() (int, error) {
+	before := time.Now().Add(-runningStaleAfter())
+	ids, err := s.repo.ListStaleRunning(before)
+	if err != nil {
+		return 0, err
+	}
+	requeued := 0
+	for _, id := range ids {
+		ok, err := s.repo.RequeueStale(id, before)
+		if err != nil {
+			return requeued, err
+		}
+		if ok {
+			requeued++
+		}
+	}
+	if requeued > 0 {
+		s.wakeOutbox()
+	}
+	return requeued, nil
+}
+
+// workerIDFromEnv identifies this process on the jobs it runs. WORKER_ID must
+// differ between workers running at the same time and stay the same across
+// restarts for ResumeOwnJobs to pick the jobs back up. Without it, the host
+// name gets a pid and random suffix, so that workers sharing a host name never
+// claim each other's jobs; their interrupted jobs wait for the stale sweep.
+func workerIDFromEnv() string {
+	if id := os.Getenv("WORKER_ID"); id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "worker"
+	}
+	if len(host) > 40 {
+		host = host[:40]
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+// runningStaleAfter is how long a job may stay running without update before
+// it is considered abandoned; jobs render within jobTimeout.
+func runningStaleAfter() time.Duration {
+	return envDuration("WORKER_STALE_AFTER", defaultRunningStaleAfter)
+}
+
+// jobGenerateOptions rebuilds the render options persisted on a job.
+// Jobs queued before Options existed only carry Format. It fails when the
+// PDF passwords cannot be unsealed: the PDF is never rendered unprotected.
+func jobGenerateOptions(job *entities.PdfGenerationJob) (GenerateOptions, error) {
+	opts := GenerateOptions{Format: job.Format}
+	if len(job.Options) > 0 {
+		if err := json.Unmarshal(job.Options, &opts); err != nil {
+			fmt.Printf("warning: invalid options on job %s: %v\n", job.ID, err)
+		}
+	}
+	opts.NoCache = job.NoCache
+	opts.TemplateVersion = job.TemplateVersion
+	protect, err := openPasswords(opts.Protect, job.SealedPasswords)
+	if err != nil {
+		return opts, fmt.Errorf("failed to unseal PDF passwords: %w", err)
+	}
+	opts.Protect = protect
+	return opts, nil
+}
+
+func marshalJobOptions(opts GenerateOptions) datatypes.JSON {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	return datatypes.JSON(b)
+}
+
+// handleFailure schedules the next attempt of a job that failed with a
+// retryable error, dead-letters it after its last attempt and fails it for
+// good otherwise.
+func (s *Service) handleFailure(job *entities.PdfGenerationJob, templateEntity *entities.Template, err error) error {
+	if !IsRetryable(err) {
+		return s.failJob(job, templateEntity, err.Error())
+	}
+
+	policy := retryPolicyFromEnv()
+	if job.Attempts < policy.MaxAttempts {
+		delay := policy.Backoff(job.Attempts)
+		if schedErr := s.repo.ScheduleRetry(job.ID, job.Priority, err.Error(), delay); schedErr != nil {
+			return fmt.Errorf("%w: job %s attempt %d failed (%v) and its retry could not be scheduled: %v",
+				ErrRedeliver, job.ID, job.Attempts, err, schedErr)
+		}
+		s.wakeOutbox()
+		return fmt.Errorf("job %s attempt %d/%d failed, retrying in %s: %v",
+			job.ID, job.Attempts, policy.MaxAttempts, delay, err)
+	}
+
+	errMsg := fmt.Sprintf("%v (gave up after %d attempts)", err, job.Attempts)
+	if dlErr := s.repo.DeadLetter(job.ID, job.Priority, errMsg); dlErr != nil {
+		return fmt.Errorf("%w: job %s attempt %d failed (%v) and could not be dead-lettered: %v",
+			ErrRedeliver, job.ID, job.Attempts, err, dlErr)
+	}
+	s.wakeOutbox()
+	return s.failJob(job, templateEntity, errMsg)
+}
+
 func (s *Service) failJob(job *entities.PdfGenerationJob, templateEntity *entities.Template, errMsg string) error {
 	if err := s.repo.UpdateStatus(job.ID, entities.JobStatusFailed, "", errMsg); err != nil {
 		fmt.Printf("warning: failed to mark job %s failed: %v\n", job.ID, err)
@@ -120,8 +301,9 @@ func (s *Service) failJob(job *entities.PdfGenerationJob, templateEntity *entiti
 		job.TemplateUUID,
 		job.Payload,
 		map[string]interface{}{
-			"job_id":  job.ID,
-			"message": errMsg,
+			"job_id":   job.ID,
+			"message":  errMsg,
+			"attempts": job.Attempts,
 		},
 		entities.Fail,
 		errors.New(errMsg),
@@ -131,7 +313,10 @@ func (s *Service) failJob(job *entities.PdfGenerationJob, templateEntity *entiti
 	publisher.Publish(webhook.EventPdfJobFailed, job.ID, job.Key.UserID, job.KeyID, map[string]interface{}{
 		"error":         errMsg,
 		"template_uuid": job.TemplateUUID,
+		"attempts":      job.Attempts,
 	})
 
+	s.trackBatchProgress(job, false)
+
 	return fmt.Errorf("job %s failed: %s", job.ID, errMsg)
 }
@@ -0,0 +1,111 @@
+package pdfjob
+
+import (
+	"designmypdf/config/database"
+	"designmypdf/pkg/entities"
+	"errors"
+	"testing"
+	"time"
+)
+
+func createJobs(t *testing.T, jobs ...entities.PdfGenerationJob) {
+	t.Helper()
+	for _, job := range jobs {
+		job.TemplateUUID = "t"
+		if err := database.DB.Create(&job).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResumeOwnJobs(t *testing.T) {
+	useTestDB(t)
+	createJobs(t,
+		entities.PdfGenerationJob{ID: "mine", Status: entities.JobStatusRunning, WorkerID: "w1"},
+		entities.PdfGenerationJob{ID: "other", Status: entities.JobStatusRunning, WorkerID: "w2"},
+		entities.PdfGenerationJob{ID: "done", Status: entities.JobStatusCompleted, WorkerID: "w1"},
+	)
+	s := &Service{repo: Repository{}, outboxWake: make(chan struct{}, 1), workerID: "w1"}
+
+	n, err := s.ResumeOwnJobs()
+	if err != nil || n != 1 {
+		t.Fatalf("ResumeOwnJobs() = %d, %v; want 1", n, err)
+	}
+	for id, want := range map[string]entities.JobStatus{
+		"mine":  entities.JobStatusQueued,
+		"other": entities.JobStatusRunning,
+		"done":  entities.JobStatusCompleted,
+	} {
+		job, _ := s.repo.GetByID(id)
+		if job.Status != want {
+			t.Errorf("job %s is %s, want %s", id, job.Status, want)
+		}
+	}
+	var rows []entities.PdfJobOutbox
+	database.DB.Find(&rows)
+	if len(rows) != 1 || rows[0].JobID != "mine" {
+		t.Errorf("outbox rows = %+v, want one for job mine", rows)
+	}
+}
+
+func TestRequeueInterruptedJobs(t *testing.T) {
+	useTestDB(t)
+	createJobs(t,
+		entities.PdfGenerationJob{ID: "stale", Status: entities.JobStatusRunning, WorkerID: "dead",
+			UpdatedAt: time.Now().Add(-2 * defaultRunningStaleAfter)},
+		entities.PdfGenerationJob{ID: "busy", Status: entities.JobStatusRunning, WorkerID: "alive"},
+	)
+	s := &Service{repo: Repository{}, outboxWake: make(chan struct{}, 1), workerID: "w1"}
+
+	n, err := s.RequeueInterruptedJobs()
+	if err != nil || n != 1 {
+		t.Fatalf("RequeueInterruptedJobs() = %d, %v; want 1", n, err)
+	}
+	if job, _ := s.repo.GetByID("stale"); job.Status != entities.JobStatusQueued || job.WorkerID != "" {
+		t.Errorf("stale job = %s on %q, want queued without worker", job.Status, job.WorkerID)
+	}
+	if job, _ := s.repo.GetByID("busy"); job.Status != entities.JobStatusRunning {
+		t.Errorf("busy job = %s, want running", job.Status)
+	}
+}
+
+func TestWorkerIDFromEnv(t *testing.T) {
+	t.Setenv("WORKER_ID", "")
+	a, b := workerIDFromEnv(), workerIDFromEnv()
+	if a == "" || a == b {
+		t.Errorf("workerIDFromEnv() = %q, %q; want distinct IDs without WORKER_ID", a, b)
+	}
+	if len(a) > 64 {
+		t.Errorf("workerIDFromEnv() = %q, longer than the worker_id column", a)
+	}
+	t.Setenv("WORKER_ID", "w1")
+	if got := workerIDFromEnv(); got != "w1" {
+		t.Errorf("workerIDFromEnv() = %q, want WORKER_ID", got)
+	}
+}
+
+func TestProcessJobDuplicates(t *testing.T) {
+	useTestDB(t)
+	createJobs(t,
+		entities.PdfGenerationJob{ID: "running", Status: entities.JobStatusRunning, WorkerID: "w2"},
+		entities.PdfGenerationJob{ID: "completed", Status: entities.JobStatusCompleted},
+	)
+	s := &Service{repo: Repository{}, outboxWake: make(chan struct{}, 1), workerID: "w1"}
+
+	// A message of a job running elsewhere is acknowledged: the stale sweep
+	// requeues the job if its worker died.
+	if err := s.ProcessJob("running"); err != nil {
+		t.Errorf("ProcessJob(running) = %v, want nil", err)
+	}
+	var job entities.PdfGenerationJob
+	database.DB.First(&job, "id = ?", "running")
+	if job.Status != entities.JobStatusRunning || job.WorkerID != "w2" {
+		t.Errorf("running job = %s on %q, want untouched", job.Status, job.WorkerID)
+	}
+	if err := s.ProcessJob("completed"); err != nil {
+		t.Errorf("ProcessJob(completed) = %v, want nil", err)
+	}
+	if err := s.ProcessJob("missing"); err == nil || errors.Is(err, ErrRedeliver) {
+		t.Errorf("ProcessJob(missing) = %v, want a final error", err)
+	}
+}
@@ -0,0 +1,32 @@
+package pdfjob
+
+import (
+	"designmypdf/pkg/signing"
+)
+
+// SignOptions requests a PAdES signature of the rendered PDF with a signing
+// certificate of the key owner. CertificateID 0 picks the certificate of the
+// template namespace, then the user default.
+type SignOptions struct {
+	CertificateID uint   `json:"certificate_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+	Location      string `json:"location,omitempty"`
+	Visible       bool   `json:"visible,omitempty"`
+	Page          int    `json:"page,omitempty"`
+	Position      string `json:"position,omitempty"`
+}
+
+func (o SignOptions) signingOptions() signing.Options {
+	return signing.Options{
+		Reason:   o.Reason,
+		Location: o.Location,
+		Visible:  o.Visible,
+		Page:     o.Page,
+		Position: o.Position,
+	}
+}
+
+// Validate reports appearance options that can never be applied.
+func (o SignOptions) Validate() error {
+	return o.signingOptions().Validate()
+}
@@ -0,0 +1,72 @@
+// Package secret seals the values the service must keep but never store in
+// clear (signing keys, PDF passwords of queued jobs) with ENCRYPTION_KEY.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoKey is returned when ENCRYPTION_KEY is not set: secrets are never
+// stored in clear.
+var ErrNoKey = errors.New("ENCRYPTION_KEY is not set")
+
+const sealedVersion = 1
+
+// key derives the AES-256 key from ENCRYPTION_KEY.
+func key() ([]byte, error) {
+	secret := os.Getenv("ENCRYPTION_KEY")
+	if secret == "" {
+		return nil, ErrNoKey
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:], nil
+}
+
+func newGCM() (cipher.AEAD, error) {
+	key, err := key()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Seal encrypts plaintext with AES-256-GCM: version byte, nonce, ciphertext.
+func Seal(plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte{sealedVersion}, nonce...)
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Open decrypts the output of Seal.
+func Open(sealed []byte) ([]byte, error) {
+	gcm, err := newGCM()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < 1+gcm.NonceSize() || sealed[0] != sealedVersion {
+		return nil, errors.New("invalid sealed value")
+	}
+	nonce := sealed[1 : 1+gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, sealed[1+gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt (wrong ENCRYPTION_KEY?): %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,30 @@
+package secret
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	t.Setenv("ENCRYPTION_KEY", "test-secret")
+	sealed, err := Seal([]byte("private key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("private key")) {
+		t.Error("sealed value contains the plaintext")
+	}
+	plain, err := Open(sealed)
+	if err != nil || string(plain) != "private key" {
+		t.Errorf("Open() = %q, %v", plain, err)
+	}
+
+	t.Setenv("ENCRYPTION_KEY", "other-secret")
+	if _, err := Open(sealed); err == nil {
+		t.Error("Open with another key should fail")
+	}
+	t.Setenv("ENCRYPTION_KEY", "")
+	if _, err := Seal([]byte("x")); err != ErrNoKey {
+		t.Errorf("Seal without key = %v", err)
+	}
+}
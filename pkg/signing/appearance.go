@@ -0,0 +1,73 @@
+package signing
+
+import (
+	"bytes"
+	"fmt"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	appearanceFontSize = 8.0
+	appearanceLeading  = 10.0
+	appearancePadding  = 6.0
+	// Helvetica at 8pt averages about 4.4pt per character.
+	appearanceMaxChars = 42
+)
+
+// appearanceStream draws the visible signature box: a border and the signer,
+// date, reason and location lines in Helvetica.
+func appearanceStream(id *Identity, opts Options) []byte {
+	lines := []string{
+		"Digitally signed by " + signerName(id),
+		"Date: " + opts.SignedAt.UTC().Format("2006-01-02 15:04:05 MST"),
+	}
+	if opts.Reason != "" {
+		lines = append(lines, "Reason: "+opts.Reason)
+	}
+	if opts.Location != "" {
+		lines = append(lines, "Location: "+opts.Location)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "q 0.2 0.2 0.2 RG 0.5 w 0.25 0.25 %g %g re S Q\n", boxWidth-0.5, boxHeight-0.5)
+	fmt.Fprintf(&b, "BT 0 0 0 rg /F1 %g Tf %g TL %g %g Td\n", appearanceFontSize, appearanceLeading,
+		appearancePadding, boxHeight-appearancePadding-appearanceFontSize)
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("T* ")
+		}
+		fmt.Fprintf(&b, "(%s) Tj\n", escapeLiteral(winAnsi(truncate(line, appearanceMaxChars))))
+	}
+	b.WriteString("ET")
+	return b.Bytes()
+}
+
+func signerName(id *Identity) string {
+	if name := id.Certificate.Subject.CommonName; name != "" {
+		return name
+	}
+	return id.Certificate.Subject.String()
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// winAnsi encodes s for the WinAnsiEncoding of the appearance font;
+// characters outside of it become "?".
+func winAnsi(s string) string {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
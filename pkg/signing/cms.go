@@ -0,0 +1,199 @@
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+var (
+	oidData                 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData           = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttrContentType      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrMessageDigest    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningCertV2    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+	oidSHA256               = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256      = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	asn1SetTag              = 17
+	asn1SequenceTag         = 16
+	asn1ContextSpecificZero = 0
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type encapsulatedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      asn1.RawValue
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signerInfo struct {
+	Version            int
+	SID                issuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// essCertIDv2 omits hashAlgorithm: SHA-256 is the default.
+type essCertIDv2 struct {
+	CertHash     []byte
+	IssuerSerial issuerSerial
+}
+
+type issuerSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+func rawSet(class, tag int, elements ...[]byte) asn1.RawValue {
+	return asn1.RawValue{Class: class, Tag: tag, IsCompound: true, Bytes: bytes.Join(elements, nil)}
+}
+
+func newAttribute(oid asn1.ObjectIdentifier, value interface{}) ([]byte, error) {
+	valueDER, err := asn1.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(attribute{Type: oid, Values: rawSet(asn1.ClassUniversal, asn1SetTag, valueDER)})
+}
+
+// signedAttributes returns the DER encodings of the CAdES baseline signed
+// attributes, sorted as DER requires for a SET OF. PAdES carries the signing
+// time in the signature dictionary (/M), not in a signing-time attribute.
+func signedAttributes(id *Identity, digest []byte) ([][]byte, error) {
+	certHash := sha256.Sum256(id.Certificate.Raw)
+	directoryName := rawSet(asn1.ClassContextSpecific, 4, id.Certificate.RawIssuer)
+	directoryNameDER, err := asn1.Marshal(directoryName)
+	if err != nil {
+		return nil, err
+	}
+	signingCert := signingCertificateV2{Certs: []essCertIDv2{{
+		CertHash: certHash[:],
+		IssuerSerial: issuerSerial{
+			Issuer:       rawSet(asn1.ClassUniversal, asn1SequenceTag, directoryNameDER),
+			SerialNumber: id.Certificate.SerialNumber,
+		},
+	}}}
+
+	var attrs [][]byte
+	for _, a := range []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidAttrContentType, oidData},
+		{oidAttrMessageDigest, digest},
+		{oidAttrSigningCertV2, signingCert},
+	} {
+		der, err := newAttribute(a.oid, a.value)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, der)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+	return attrs, nil
+}
+
+// buildCMS returns a detached CMS SignedData (ETSI.CAdES.detached) over content
+// signed by id, with the certificate chain embedded.
+func buildCMS(id *Identity, content []byte) ([]byte, error) {
+	digest := sha256.Sum256(content)
+	attrs, err := signedAttributes(id, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature covers the attributes encoded as a SET, not as [0].
+	attrsSetDER, err := asn1.Marshal(rawSet(asn1.ClassUniversal, asn1SetTag, attrs...))
+	if err != nil {
+		return nil, err
+	}
+	attrsDigest := sha256.Sum256(attrsSetDER)
+
+	var sigAlg pkix.AlgorithmIdentifier
+	switch id.Key.Public().(type) {
+	case *rsa.PublicKey:
+		sigAlg = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		sigAlg = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", id.Key.Public())
+	}
+	signature, err := id.Key.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	digestAlg := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	digestAlgDER, err := asn1.Marshal(digestAlg)
+	if err != nil {
+		return nil, err
+	}
+	si, err := asn1.Marshal(signerInfo{
+		Version: 1,
+		SID: issuerAndSerialNumber{
+			Issuer:       asn1.RawValue{FullBytes: id.Certificate.RawIssuer},
+			SerialNumber: id.Certificate.SerialNumber,
+		},
+		DigestAlgorithm:    digestAlg,
+		SignedAttrs:        rawSet(asn1.ClassContextSpecific, asn1ContextSpecificZero, attrs...),
+		SignatureAlgorithm: sigAlg,
+		Signature:          signature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	certs := [][]byte{id.Certificate.Raw}
+	for _, c := range id.Chain {
+		certs = append(certs, c.Raw)
+	}
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: rawSet(asn1.ClassUniversal, asn1SetTag, digestAlgDER),
+		EncapContentInfo: encapsulatedContentInfo{ContentType: oidData},
+		Certificates:     rawSet(asn1.ClassContextSpecific, asn1ContextSpecificZero, certs...),
+		SignerInfos:      rawSet(asn1.ClassUniversal, asn1SetTag, si),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     rawSet(asn1.ClassContextSpecific, asn1ContextSpecificZero, sd),
+	})
+}
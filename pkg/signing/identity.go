@@ -0,0 +1,127 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+var ErrInvalidCertificate = errors.New("invalid signing certificate")
+
+// Identity is a decoded signing certificate, its private key and the chain
+// embedded in signatures.
+type Identity struct {
+	Certificate *x509.Certificate
+	Chain       []*x509.Certificate
+	Key         crypto.Signer
+}
+
+// ParsePKCS12 decodes a .p12/.pfx bundle.
+func ParsePKCS12(data []byte, password string) (*Identity, error) {
+	key, cert, chain, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	return newIdentity(key, cert, chain)
+}
+
+// ParsePEM decodes a PEM certificate (followed by its chain) and a PEM
+// private key (PKCS#8, PKCS#1 or SEC 1).
+func ParsePEM(certPEM, keyPEM []byte) (*Identity, error) {
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM private key found", ErrInvalidCertificate)
+	}
+	key, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return newIdentity(key, certs[0], certs[1:])
+}
+
+// parseCertificates decodes the CERTIFICATE blocks of a PEM chain, leaf first.
+func parseCertificates(certPEM []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for rest := certPEM; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%w: no PEM certificate found", ErrInvalidCertificate)
+	}
+	return certs, nil
+}
+
+func parsePrivateKey(der []byte) (interface{}, error) {
+	if key, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unsupported private key encoding", ErrInvalidCertificate)
+}
+
+// newIdentity checks that the key matches the certificate, is RSA or ECDSA,
+// and that the certificate can sign documents today.
+func newIdentity(key interface{}, cert *x509.Certificate, chain []*x509.Certificate) (*Identity, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported private key type %T", ErrInvalidCertificate, key)
+	}
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey:
+		if !pub.Equal(cert.PublicKey) {
+			return nil, fmt.Errorf("%w: private key does not match the certificate", ErrInvalidCertificate)
+		}
+	case *ecdsa.PublicKey:
+		if !pub.Equal(cert.PublicKey) {
+			return nil, fmt.Errorf("%w: private key does not match the certificate", ErrInvalidCertificate)
+		}
+	default:
+		return nil, fmt.Errorf("%w: only RSA and ECDSA keys are supported", ErrInvalidCertificate)
+	}
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return nil, fmt.Errorf("%w: certificate is not valid at this date (%s to %s)",
+			ErrInvalidCertificate, cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly))
+	}
+	if cert.KeyUsage != 0 && cert.KeyUsage&(x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment) == 0 {
+		return nil, fmt.Errorf("%w: certificate key usage does not allow signatures", ErrInvalidCertificate)
+	}
+	return &Identity{Certificate: cert, Chain: chain, Key: signer}, nil
+}
+
+// chainPEM encodes the certificate followed by its chain.
+func (id *Identity) chainPEM() []byte {
+	var out []byte
+	for _, c := range append([]*x509.Certificate{id.Certificate}, id.Chain...) {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})...)
+	}
+	return out
+}
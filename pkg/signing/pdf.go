@@ -0,0 +1,348 @@
+package signing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Signature box positions on the page, in points from its edges.
+const (
+	PositionBottomRight = "bottom-right"
+	PositionBottomLeft  = "bottom-left"
+	PositionTopRight    = "top-right"
+	PositionTopLeft     = "top-left"
+
+	boxWidth  = 200.0
+	boxHeight = 56.0
+	boxMargin = 36.0
+)
+
+var ErrInvalidOptions = errors.New("invalid signature options")
+
+// Options describe the signature applied by Sign.
+type Options struct {
+	Reason   string
+	Location string
+	// Visible draws the signature box; otherwise the signature has no appearance.
+	Visible bool
+	// Page of the visible box, 1-based; 0 is the last page.
+	Page int
+	// Position is one of the Position constants; empty is bottom-right.
+	Position string
+	// SignedAt is the signing time written to the signature; zero is now.
+	SignedAt time.Time
+}
+
+// Validate reports options that can never be applied.
+func (o Options) Validate() error {
+	if o.Page < 0 {
+		return fmt.Errorf("%w: page must be positive", ErrInvalidOptions)
+	}
+	switch o.Position {
+	case "", PositionBottomRight, PositionBottomLeft, PositionTopRight, PositionTopLeft:
+	default:
+		return fmt.Errorf("%w: position must be one of %s, %s, %s or %s", ErrInvalidOptions,
+			PositionBottomRight, PositionBottomLeft, PositionTopRight, PositionTopLeft)
+	}
+	return nil
+}
+
+var startXRefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+
+const byteRangePlaceholder = "[0 0000000000 0000000000 0000000000]"
+
+// Sign applies a PAdES baseline (ETSI.CAdES.detached) signature of id to pdf.
+// The document is first rewritten with a classic cross-reference table unless
+// it already ends with one, then the signature field, its widget and the
+// signature value are appended as an incremental update so the signed byte
+// range covers the whole file except the signature value itself.
+func Sign(pdf []byte, id *Identity, opts Options) ([]byte, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.SignedAt.IsZero() {
+		opts.SignedAt = time.Now()
+	}
+
+	base := pdf
+	if !classicXRef(pdf) {
+		var err error
+		if base, err = normalize(pdf); err != nil {
+			return nil, err
+		}
+	}
+	ctx, err := api.ReadContext(bytes.NewReader(base), model.NewDefaultConfiguration())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if err := ctx.EnsurePageCount(); err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	if ctx.Root == nil || ctx.Size == nil {
+		return nil, errors.New("failed to read PDF: missing trailer")
+	}
+	m := startXRefPattern.FindAllSubmatch(base, -1)
+	if len(m) == 0 {
+		return nil, errors.New("failed to read PDF: missing startxref")
+	}
+	prevXRef, _ := strconv.Atoi(string(m[len(m)-1][1]))
+
+	pageNr := opts.Page
+	if pageNr == 0 {
+		pageNr = ctx.PageCount
+	}
+	if pageNr > ctx.PageCount {
+		return nil, fmt.Errorf("%w: page %d is out of range (document has %d pages)", ErrInvalidOptions, pageNr, ctx.PageCount)
+	}
+	pageDict, pageRef, inherited, err := ctx.PageDict(pageNr, false)
+	if err != nil || pageDict == nil || pageRef == nil {
+		return nil, fmt.Errorf("failed to read page %d: %v", pageNr, err)
+	}
+	catalog, err := ctx.Catalog()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF catalog: %w", err)
+	}
+
+	size := *ctx.Size
+	sigRef := types.NewIndirectRef(size, 0)
+	widgetRef := types.NewIndirectRef(size+1, 0)
+	size += 2
+	var apRef *types.IndirectRef
+	if opts.Visible {
+		apRef = types.NewIndirectRef(size, 0)
+		size++
+	}
+
+	// Page: append the widget to its annotations.
+	annots, err := ctx.DereferenceArray(pageDict["Annots"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page annotations: %w", err)
+	}
+	pageDict.Update("Annots", append(append(types.Array{}, annots...), *widgetRef))
+
+	// Catalog: register the signature field in the AcroForm.
+	acroForm, err := ctx.DereferenceDict(catalog["AcroForm"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AcroForm: %w", err)
+	}
+	if acroForm == nil {
+		acroForm = types.Dict{}
+	}
+	fields, err := ctx.DereferenceArray(acroForm["Fields"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AcroForm fields: %w", err)
+	}
+	acroForm.Update("Fields", append(append(types.Array{}, fields...), *widgetRef))
+	acroForm.Update("SigFlags", types.Integer(3))
+	catalog.Update("AcroForm", acroForm)
+
+	rect := types.Array{types.Integer(0), types.Integer(0), types.Integer(0), types.Integer(0)}
+	var appearance []byte
+	if opts.Visible {
+		mediaBox := types.NewRectangle(0, 0, 595, 842)
+		if inherited != nil && inherited.MediaBox != nil {
+			mediaBox = inherited.MediaBox
+		}
+		llx, lly := boxOrigin(mediaBox, opts.Position)
+		rect = types.Array{types.Float(llx), types.Float(lly), types.Float(llx + boxWidth), types.Float(lly + boxHeight)}
+		appearance = appearanceStream(id, opts)
+	}
+
+	widget := fmt.Sprintf("<</Type /Annot /Subtype /Widget /FT /Sig /T %s /V %s /F 132 /P %s /Rect %s",
+		pdfText(fmt.Sprintf("Signature%d", len(fields)+1)), sigRef.PDFString(), pageRef.PDFString(), rect.PDFString())
+	if apRef != nil {
+		widget += fmt.Sprintf(" /AP <</N %s>>", apRef.PDFString())
+	}
+	widget += ">>"
+
+	contentsLen := estimateSignatureSize(id)
+	sig := fmt.Sprintf("<</Type /Sig /Filter /Adobe.PPKLite /SubFilter /ETSI.CAdES.detached /ByteRange %s /Contents <%s> /M %s /Name %s",
+		byteRangePlaceholder, strings.Repeat("0", 2*contentsLen), pdfText(pdfDate(opts.SignedAt)), pdfText(id.Certificate.Subject.CommonName))
+	if opts.Reason != "" {
+		sig += " /Reason " + pdfText(opts.Reason)
+	}
+	if opts.Location != "" {
+		sig += " /Location " + pdfText(opts.Location)
+	}
+	sig += ">>"
+
+	var out bytes.Buffer
+	out.Write(base)
+	if !bytes.HasSuffix(base, []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	offsets := map[int]int{}
+	writeObject := func(ref types.IndirectRef, body string) {
+		offsets[ref.ObjectNumber.Value()] = out.Len()
+		fmt.Fprintf(&out, "%d %d obj\n%s\nendobj\n", ref.ObjectNumber.Value(), ref.GenerationNumber.Value(), body)
+	}
+	writeObject(*sigRef, sig)
+	writeObject(*widgetRef, widget)
+	if apRef != nil {
+		offsets[apRef.ObjectNumber.Value()] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n<</Type /XObject /Subtype /Form /BBox [0 0 %g %g] /Resources <</Font <</F1 <</Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding>>>>>> /Length %d>>\nstream\n",
+			apRef.ObjectNumber.Value(), boxWidth, boxHeight, len(appearance))
+		out.Write(appearance)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+	writeObject(*pageRef, pageDict.PDFString())
+	writeObject(*ctx.Root, catalog.PDFString())
+
+	xrefOffset := out.Len()
+	out.WriteString("xref\n")
+	numbers := make([]int, 0, len(offsets))
+	for nr := range offsets {
+		numbers = append(numbers, nr)
+	}
+	sort.Ints(numbers)
+	for _, nr := range numbers {
+		gen := 0
+		if nr == pageRef.ObjectNumber.Value() {
+			gen = pageRef.GenerationNumber.Value()
+		} else if nr == ctx.Root.ObjectNumber.Value() {
+			gen = ctx.Root.GenerationNumber.Value()
+		}
+		fmt.Fprintf(&out, "%d 1\n%010d %05d n \n", nr, offsets[nr], gen)
+	}
+	trailer := fmt.Sprintf("<</Size %d /Root %s /Prev %d", size, ctx.Root.PDFString(), prevXRef)
+	if ctx.Info != nil {
+		trailer += " /Info " + ctx.Info.PDFString()
+	}
+	if len(ctx.ID) > 0 {
+		trailer += " /ID " + ctx.ID.PDFString()
+	}
+	trailer += ">>"
+	fmt.Fprintf(&out, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer, xrefOffset)
+
+	return patchSignature(out.Bytes(), offsets[sigRef.ObjectNumber.Value()], id)
+}
+
+// classicXRef reports whether the last cross-reference section of pdf is a
+// table the incremental update can extend as is. Rewriting such a file is
+// avoided: it would reset its Info dictionary and break PDF/A metadata.
+func classicXRef(pdf []byte) bool {
+	m := startXRefPattern.FindAllSubmatch(pdf, -1)
+	if len(m) == 0 {
+		return false
+	}
+	offset, err := strconv.Atoi(string(m[len(m)-1][1]))
+	if err != nil || offset >= len(pdf) {
+		return false
+	}
+	return bytes.HasPrefix(bytes.TrimLeft(pdf[offset:], " \r\n"), []byte("xref"))
+}
+
+// normalize rewrites pdf without object and cross-reference streams, so the
+// incremental update can use a classic cross-reference table.
+func normalize(pdf []byte) ([]byte, error) {
+	conf := model.NewDefaultConfiguration()
+	conf.WriteObjectStream = false
+	conf.WriteXRefStream = false
+	ctx, err := api.ReadContext(bytes.NewReader(pdf), conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := api.WriteContext(ctx, &buf); err != nil {
+		return nil, fmt.Errorf("failed to rewrite PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// patchSignature fills the /ByteRange and /Contents placeholders of the
+// signature dictionary written at sigOffset.
+func patchSignature(doc []byte, sigOffset int, id *Identity) ([]byte, error) {
+	byteRangeAt := bytes.Index(doc[sigOffset:], []byte(byteRangePlaceholder))
+	contentsAt := bytes.Index(doc[sigOffset:], []byte("/Contents <"))
+	if byteRangeAt < 0 || contentsAt < 0 {
+		return nil, errors.New("signature placeholder not found")
+	}
+	byteRangeAt += sigOffset
+	start := sigOffset + contentsAt + len("/Contents ")
+	end := start + bytes.IndexByte(doc[start:], '>') + 1
+
+	byteRange := fmt.Sprintf("[0 %010d %010d %010d]", start, end, len(doc)-end)
+	copy(doc[byteRangeAt:], byteRange)
+
+	signed := make([]byte, 0, len(doc)-(end-start))
+	signed = append(signed, doc[:start]...)
+	signed = append(signed, doc[end:]...)
+	cms, err := buildCMS(id, signed)
+	if err != nil {
+		return nil, err
+	}
+	encoded := hex.EncodeToString(cms)
+	if len(encoded) > end-start-2 {
+		return nil, fmt.Errorf("signature is larger than its placeholder (%d > %d bytes)", len(cms), (end-start-2)/2)
+	}
+	copy(doc[start+1:], encoded)
+	return doc, nil
+}
+
+// estimateSignatureSize returns the bytes reserved for the CMS value: the
+// embedded certificates plus room for the signature and attributes.
+func estimateSignatureSize(id *Identity) int {
+	n := len(id.Certificate.Raw) + 4096
+	for _, c := range id.Chain {
+		n += len(c.Raw)
+	}
+	return n
+}
+
+func boxOrigin(mediaBox *types.Rectangle, position string) (float64, float64) {
+	x := mediaBox.UR.X - boxMargin - boxWidth
+	y := mediaBox.LL.Y + boxMargin
+	switch position {
+	case PositionBottomLeft:
+		x = mediaBox.LL.X + boxMargin
+	case PositionTopLeft:
+		x = mediaBox.LL.X + boxMargin
+		y = mediaBox.UR.Y - boxMargin - boxHeight
+	case PositionTopRight:
+		y = mediaBox.UR.Y - boxMargin - boxHeight
+	}
+	return x, y
+}
+
+// pdfDate formats t as a PDF date string.
+func pdfDate(t time.Time) string {
+	return "D:" + t.UTC().Format("20060102150405") + "Z"
+}
+
+// pdfText encodes s as a PDF text string: a literal string for ASCII,
+// UTF-16BE hex otherwise.
+func pdfText(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r > 126 || r < 32 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return "(" + escapeLiteral(s) + ")"
+	}
+	buf := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(s)) {
+		buf = append(buf, byte(u>>8), byte(u))
+	}
+	return "<" + strings.ToUpper(hex.EncodeToString(buf)) + ">"
+}
+
+var literalEscaper = strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`)
+
+func escapeLiteral(s string) string {
+	return literalEscaper.Replace(s)
+}
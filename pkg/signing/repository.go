@@ -0,0 +1,56 @@
+package signing
+
+import (
+	"designmypdf/pkg/entities"
+
+	"gorm.io/gorm"
+)
+
+// Repository is a GORM implementation of the signing certificate storage.
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(cert *entities.SigningCertificate) error {
+	return r.db.Create(cert).Error
+}
+
+func (r *Repository) Get(userID, id uint) (*entities.SigningCertificate, error) {
+	var cert entities.SigningCertificate
+	if err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func (r *Repository) Delete(cert *entities.SigningCertificate) error {
+	return r.db.Delete(cert).Error
+}
+
+func (r *Repository) List(userID uint) ([]entities.SigningCertificate, error) {
+	var certs []entities.SigningCertificate
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// Latest returns the most recent certificate of a user for a namespace, or
+// the most recent user default when namespaceID is nil.
+func (r *Repository) Latest(userID uint, namespaceID *uint) (*entities.SigningCertificate, error) {
+	query := r.db.Where("user_id = ?", userID)
+	if namespaceID == nil {
+		query = query.Where("namespace_id IS NULL")
+	} else {
+		query = query.Where("namespace_id = ?", *namespaceID)
+	}
+	var cert entities.SigningCertificate
+	if err := query.Order("id DESC").First(&cert).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
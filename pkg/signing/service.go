@@ -0,0 +1,149 @@
+package signing
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"designmypdf/config/database"
+	"designmypdf/pkg/entities"
+	"designmypdf/pkg/secret"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoCertificate is returned when a signature is requested but neither the
+// namespace nor the user has a signing certificate.
+var ErrNoCertificate = errors.New("no signing certificate configured")
+
+// Service defines the interface for signing certificate operations.
+type Service interface {
+	Upload(userID uint, namespaceID *uint, name string, id *Identity) (*entities.SigningCertificate, error)
+	List(userID uint) ([]entities.SigningCertificate, error)
+	Get(userID, ID uint) (*entities.SigningCertificate, error)
+	Delete(userID, ID uint) (*entities.SigningCertificate, error)
+	Identity(userID, namespaceID, certificateID uint) (*Identity, error)
+	Verify(userID uint, pdf []byte) Report
+}
+
+type service struct {
+	repository Repository
+}
+
+// NewService creates a new instance of the signing service.
+func NewService(r Repository) Service {
+	return &service{
+		repository: *NewRepository(database.DB),
+	}
+}
+
+// Upload stores a certificate chain with its private key sealed with
+// ENCRYPTION_KEY.
+func (s *service) Upload(userID uint, namespaceID *uint, name string, id *Identity) (*entities.SigningCertificate, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(id.Key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	sealed, err := secret.Seal(der)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = signerName(id)
+	}
+	fingerprint := sha256.Sum256(id.Certificate.Raw)
+	cert := &entities.SigningCertificate{
+		UserID:         userID,
+		NamespaceID:    namespaceID,
+		Name:           name,
+		Subject:        id.Certificate.Subject.String(),
+		Issuer:         id.Certificate.Issuer.String(),
+		SerialNumber:   id.Certificate.SerialNumber.Text(16),
+		Fingerprint:    hex.EncodeToString(fingerprint[:]),
+		NotBefore:      id.Certificate.NotBefore,
+		NotAfter:       id.Certificate.NotAfter,
+		CertificatePEM: string(id.chainPEM()),
+		EncryptedKey:   sealed,
+	}
+	if err := s.repository.Create(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// List returns the signing certificates of a user.
+func (s *service) List(userID uint) ([]entities.SigningCertificate, error) {
+	return s.repository.List(userID)
+}
+
+// Get returns a signing certificate of a user.
+func (s *service) Get(userID, ID uint) (*entities.SigningCertificate, error) {
+	return s.repository.Get(userID, ID)
+}
+
+// Delete deletes a signing certificate of a user.
+func (s *service) Delete(userID, ID uint) (*entities.SigningCertificate, error) {
+	cert, err := s.repository.Get(userID, ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repository.Delete(cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Identity decrypts the certificate a PDF of the namespace is signed with:
+// certificateID when set, otherwise the namespace certificate, then the
+// default certificate of the user.
+func (s *service) Identity(userID, namespaceID, certificateID uint) (*Identity, error) {
+	var cert *entities.SigningCertificate
+	var err error
+	if certificateID != 0 {
+		cert, err = s.repository.Get(userID, certificateID)
+	} else {
+		cert, err = s.repository.Latest(userID, &namespaceID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			cert, err = s.repository.Latest(userID, nil)
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoCertificate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := secret.Open(cert.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stored key of certificate %d: %w", cert.ID, err)
+	}
+	certs, err := parseCertificates([]byte(cert.CertificatePEM))
+	if err != nil {
+		return nil, err
+	}
+	return newIdentity(key, certs[0], certs[1:])
+}
+
+// Verify checks the signatures of pdf against the system roots and the
+// certificates the user uploaded, so self-signed organization certificates
+// are reported as trusted to their owner.
+func (s *service) Verify(userID uint, pdf []byte) Report {
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	if certs, err := s.repository.List(userID); err == nil {
+		for _, c := range certs {
+			if chain, err := parseCertificates([]byte(c.CertificatePEM)); err == nil {
+				roots.AddCert(chain[len(chain)-1])
+			}
+		}
+	}
+	return Verify(pdf, roots)
+}
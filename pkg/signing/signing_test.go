@@ -0,0 +1,162 @@
+package signing
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// testPDF is a one-page PDF with a classic cross-reference table.
+func testPDF() []byte {
+	objects := []string{
+		"<</Type /Catalog /Pages 2 0 R>>",
+		"<</Type /Pages /Kids [3 0 R] /Count 1>>",
+		"<</Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R>>",
+		"<</Length 35>>\nstream\nBT /F1 12 Tf 72 720 Td (Hi) Tj ET\nendstream",
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, o := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", o)
+	}
+	fmt.Fprintf(&b, "trailer\n<</Size %d /Root 1 0 R>>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func testIdentity(t *testing.T, key crypto.Signer) (*Identity, *x509.Certificate) {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Acme Légal", Organization: []string{"Acme"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := newIdentity(key, cert, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, cert
+}
+
+func TestSignAndVerify(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+		opts Options
+	}{
+		{"ecdsa invisible", ecKey, Options{}},
+		{"rsa visible", rsaKey, Options{Visible: true, Reason: "Contrat signé", Location: "Paris", Position: PositionTopLeft}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, cert := testIdentity(t, tt.key)
+			signed, err := Sign(testPDF(), id, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			roots := x509.NewCertPool()
+			roots.AddCert(cert)
+
+			report := Verify(signed, roots)
+			if !report.Signed || !report.Valid || len(report.Signatures) != 1 {
+				t.Fatalf("report = %+v", report)
+			}
+			sig := report.Signatures[0]
+			if !sig.Intact || !sig.Trusted || !sig.CoversWholeDocument || sig.Error != "" {
+				t.Errorf("signature = %+v", sig)
+			}
+			if sig.Signer != "Acme Légal" || sig.SubFilter != "ETSI.CAdES.detached" || sig.Reason != tt.opts.Reason || sig.Location != tt.opts.Location {
+				t.Errorf("signature details = %+v", sig)
+			}
+			if sig.SignedAt == nil {
+				t.Error("signing time is missing")
+			}
+
+			if report := Verify(signed, x509.NewCertPool()); !report.Valid || report.Signatures[0].Trusted {
+				t.Errorf("unknown root: report = %+v", report)
+			}
+
+			appended := append(append([]byte{}, signed...), []byte("\n% appended\n")...)
+			if sig := Verify(appended, roots).Signatures[0]; !sig.Intact || sig.CoversWholeDocument {
+				t.Errorf("appended content: signature = %+v", sig)
+			}
+
+			tampered := append([]byte{}, signed...)
+			i := bytes.Index(tampered, []byte("(Hi)"))
+			tampered[i+1] = 'h'
+			if report := Verify(tampered, roots); report.Valid || report.Signatures[0].Intact {
+				t.Errorf("tampered document: report = %+v", report)
+			}
+		})
+	}
+}
+
+func TestVerifyUnsigned(t *testing.T) {
+	if report := Verify(testPDF(), nil); report.Signed || report.Valid || len(report.Signatures) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestSignOptionsValidate(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	id, _ := testIdentity(t, ecKey)
+	for _, opts := range []Options{{Page: -1}, {Position: "center"}, {Visible: true, Page: 2}} {
+		if _, err := Sign(testPDF(), id, opts); err == nil {
+			t.Errorf("Sign(%+v) should fail", opts)
+		}
+	}
+}
+
+func TestParsePKCS12(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	id, cert := testIdentity(t, ecKey)
+	p12, err := pkcs12.Modern.Encode(ecKey, cert, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePKCS12(p12, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Certificate.Equal(id.Certificate) {
+		t.Error("certificate mismatch")
+	}
+	if _, err := ParsePKCS12(p12, "wrong"); err == nil {
+		t.Error("wrong password should fail")
+	}
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := newIdentity(otherKey, cert, nil); err == nil {
+		t.Error("mismatched key should fail")
+	}
+}
@@ -0,0 +1,225 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+	"unicode/utf16"
+
+	"github.com/hhrutter/pkcs7"
+)
+
+// SignatureReport is the verification result of one signature of a PDF.
+type SignatureReport struct {
+	Signer       string     `json:"signer"`
+	Issuer       string     `json:"issuer"`
+	SerialNumber string     `json:"serial_number"`
+	Fingerprint  string     `json:"fingerprint"`
+	SubFilter    string     `json:"sub_filter"`
+	SignedAt     *time.Time `json:"signed_at,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	// Intact is true when the signed bytes are unchanged and the signature
+	// value matches the signer certificate.
+	Intact bool `json:"intact"`
+	// Trusted is true when the signer certificate chains to a trusted root.
+	Trusted bool `json:"trusted"`
+	// CoversWholeDocument is false when content was appended after signing.
+	CoversWholeDocument bool   `json:"covers_whole_document"`
+	Error               string `json:"error,omitempty"`
+}
+
+// Report is the verification result of a PDF.
+type Report struct {
+	Signed bool `json:"signed"`
+	// Valid is true when the document has signatures and all are intact.
+	Valid      bool              `json:"valid"`
+	Signatures []SignatureReport `json:"signatures"`
+}
+
+var (
+	byteRangePattern = regexp.MustCompile(`/ByteRange\s*\[\s*(\d+)\s+(\d+)\s+(\d+)\s+(\d+)\s*\]`)
+	subFilterPattern = regexp.MustCompile(`/SubFilter\s*/([A-Za-z0-9.#_-]+)`)
+	datePattern      = regexp.MustCompile(`/M\s*\(D:(\d{14})`)
+)
+
+// Verify checks every signature of pdf. roots are the trusted root
+// certificates; nil uses the system pool.
+func Verify(pdf []byte, roots *x509.CertPool) Report {
+	report := Report{Signatures: []SignatureReport{}}
+	for _, m := range byteRangePattern.FindAllSubmatchIndex(pdf, -1) {
+		var r [4]int
+		for i := range r {
+			r[i], _ = strconv.Atoi(string(pdf[m[2+2*i]:m[3+2*i]]))
+		}
+		sig := verifySignature(pdf, r, signatureDict(pdf, m[0]), roots)
+		report.Signatures = append(report.Signatures, sig)
+	}
+	report.Signed = len(report.Signatures) > 0
+	report.Valid = report.Signed
+	for _, sig := range report.Signatures {
+		report.Valid = report.Valid && sig.Intact
+	}
+	return report
+}
+
+// signatureDict returns the source of the object holding the /ByteRange at pos.
+func signatureDict(pdf []byte, pos int) []byte {
+	start := bytes.LastIndex(pdf[:pos], []byte(" obj"))
+	if start < 0 {
+		start = 0
+	}
+	end := bytes.Index(pdf[pos:], []byte("endobj"))
+	if end < 0 {
+		return pdf[start:]
+	}
+	return pdf[start : pos+end]
+}
+
+func verifySignature(pdf []byte, r [4]int, dict []byte, roots *x509.CertPool) SignatureReport {
+	report := SignatureReport{}
+	if m := subFilterPattern.FindSubmatch(dict); m != nil {
+		report.SubFilter = string(m[1])
+	}
+	if m := datePattern.FindSubmatch(dict); m != nil {
+		if t, err := time.Parse("20060102150405", string(m[1])); err == nil {
+			report.SignedAt = &t
+		}
+	}
+	report.Reason = literalEntry(dict, "Reason")
+	report.Location = literalEntry(dict, "Location")
+
+	if r[0] != 0 || r[1] <= 0 || r[2] <= r[1] || r[3] < 0 || r[2]+r[3] > len(pdf) {
+		report.Error = "invalid byte range"
+		return report
+	}
+	report.CoversWholeDocument = r[2]+r[3] == len(pdf)
+
+	contents := bytes.Trim(pdf[r[1]:r[2]], "<> \r\n")
+	der, err := hex.DecodeString(string(contents))
+	if err != nil {
+		report.Error = "invalid signature contents"
+		return report
+	}
+	// The placeholder is zero-padded after the DER value.
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &raw); err == nil {
+		der = raw.FullBytes
+	}
+	p7, err := pkcs7.Parse(der)
+	if err != nil {
+		report.Error = fmt.Sprintf("invalid signature: %v", err)
+		return report
+	}
+	signed := make([]byte, 0, r[1]+r[3])
+	signed = append(signed, pdf[:r[1]]...)
+	signed = append(signed, pdf[r[2]:r[2]+r[3]]...)
+	p7.Content = signed
+
+	signer := p7.GetOnlySigner()
+	if signer == nil {
+		report.Error = "signature has no single signer certificate"
+		return report
+	}
+	fingerprint := sha256.Sum256(signer.Raw)
+	report.Signer = signerName(&Identity{Certificate: signer})
+	report.Issuer = signer.Issuer.String()
+	report.SerialNumber = signer.SerialNumber.Text(16)
+	report.Fingerprint = hex.EncodeToString(fingerprint[:])
+
+	if err := p7.Verify(); err != nil {
+		report.Error = fmt.Sprintf("signature does not match the document: %v", err)
+		return report
+	}
+	report.Intact = true
+
+	at := time.Now()
+	if report.SignedAt != nil {
+		at = *report.SignedAt
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range p7.Certificates {
+		intermediates.AddCert(c)
+	}
+	if _, err := signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   at,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		report.Error = fmt.Sprintf("signer certificate is not trusted: %v", err)
+	} else {
+		report.Trusted = true
+	}
+	return report
+}
+
+// literalEntry reads a text string entry (literal or UTF-16BE hex) of dict.
+func literalEntry(dict []byte, name string) string {
+	key := []byte("/" + name)
+	i := bytes.Index(dict, key)
+	if i < 0 {
+		return ""
+	}
+	rest := bytes.TrimLeft(dict[i+len(key):], " \r\n\t")
+	if len(rest) == 0 {
+		return ""
+	}
+	switch rest[0] {
+	case '<':
+		end := bytes.IndexByte(rest, '>')
+		if end < 0 {
+			return ""
+		}
+		b, err := hex.DecodeString(string(rest[1:end]))
+		if err != nil {
+			return ""
+		}
+		return decodeText(b)
+	case '(':
+		var out []byte
+		depth := 0
+		for j := 1; j < len(rest); j++ {
+			c := rest[j]
+			switch {
+			case c == '\\' && j+1 < len(rest):
+				j++
+				out = append(out, rest[j])
+			case c == '(':
+				depth++
+				out = append(out, c)
+			case c == ')' && depth == 0:
+				return decodeText(out)
+			case c == ')':
+				depth--
+				out = append(out, c)
+			default:
+				out = append(out, c)
+			}
+		}
+	}
+	return ""
+}
+
+// decodeText decodes a PDF text string: UTF-16BE with a byte order mark, or
+// PDFDocEncoding read as Latin-1.
+func decodeText(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
@@ -43,7 +43,7 @@ type BackblazeStorage struct {
 	bucketName string
 	baseURL    string
 	urlCache   *urlCache
-	clientPool sync.Pool // Pool de clients pour réutiliser les connexions
+	clientPool *sync.Pool // Pool de clients pour réutiliser les connexions
 }
 
 // NewBackblazeStorage crée une nouvelle instance de stockage Backblaze
@@ -64,7 +64,7 @@ func NewBackblazeStorage(accountID, applicationKey, bucketName string) (*Backbla
 	baseURL := bucket.BaseURL()
 	
 	// Initialiser le pool de clients
-	clientPool := sync.Pool{
+	clientPool := &sync.Pool{
 		New: func() interface{} {
 			c, _ := b2.NewClient(context.Background(), accountID, applicationKey)
 			return c
@@ -218,6 +218,62 @@ func (s *BackblazeStorage) GetFileURL(ctx context.Context, objectName string) (s
 	return url, nil
 }
 
+// Put implémente ObjectStore : envoie le contenu de r vers B2 et retourne l'URL publique
+func (s *BackblazeStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 25*time.Second)
+	defer cancel()
+
+	var opts []b2.WriterOption
+	if contentType != "" {
+		opts = append(opts, b2.WithAttrsOption(&b2.Attrs{ContentType: contentType}))
+	}
+	writer := s.bucket.Object(key).NewWriter(ctx, opts...)
+	if size > 5*1024*1024 {
+		writer.ChunkSize = 5 * 1024 * 1024
+		writer.ConcurrentUploads = 4
+	}
+
+	if _, err := io.Copy(writer, r); err != nil {
+		writer.Close()
+		return "", fmt.Errorf("erreur lors de l'upload du fichier: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("erreur lors de la fermeture du writer: %v", err)
+	}
+
+	url := fmt.Sprintf("%s/file/%s/%s", s.baseURL, s.bucketName, key)
+	s.urlCache.set(key, url)
+	return url, nil
+}
+
+// Get implémente ObjectStore : le lecteur reste lié à ctx jusqu'à sa fermeture
+func (s *BackblazeStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj := s.bucket.Object(key)
+	if _, err := obj.Attrs(ctx); err != nil {
+		return nil, fmt.Errorf("erreur lors de la lecture du fichier: %v", err)
+	}
+	return obj.NewReader(ctx), nil
+}
+
+// Delete implémente ObjectStore
+func (s *BackblazeStorage) Delete(ctx context.Context, key string) error {
+	return s.DeleteFile(ctx, key)
+}
+
+// SignedURL implémente ObjectStore : URL de téléchargement valable ttl pour un bucket privé
+func (s *BackblazeStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	token, err := s.GetTemporaryAuthToken(ctx, key, ttl)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/file/%s/%s?Authorization=%s", s.baseURL, s.bucketName, key, token), nil
+}
+
+// List implémente ObjectStore
+func (s *BackblazeStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	return s.ListFiles(ctx, prefix)
+}
+
 // GetTemporaryAuthToken génère un token d'authentification temporaire pour un préfixe
 func (s *BackblazeStorage) GetTemporaryAuthToken(ctx context.Context, prefix string, duration time.Duration) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
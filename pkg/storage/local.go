@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLocalStorageDir is used when STORAGE_LOCAL_DIR is not set.
+const DefaultLocalStorageDir = "./uploads/storage"
+
+// DefaultLocalStoragePublicURL matches the static route mounted by the API for the local backend.
+const DefaultLocalStoragePublicURL = "/api/files"
+
+// DefaultLocalURLTTL is how long the URLs returned by Put stay valid when signing is enabled.
+const DefaultLocalURLTTL = 7 * 24 * time.Hour
+
+// ErrInvalidSignature is returned by Verify for a missing, forged or expired object URL.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// LocalStorage keeps objects on the local filesystem. Intended for on-prem
+// single-node setups and integration tests. With a signing key, object URLs
+// carry an expiring HMAC (expires + signature query parameters) checked by
+// Verify; without one they are not access-controlled (development only).
+type LocalStorage struct {
+	root      string
+	publicURL string
+	secret    []byte
+	urlTTL    time.Duration
+}
+
+// NewLocalStorage creates the root directory if needed.
+func NewLocalStorage(root, publicURL string) (*LocalStorage, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("local storage root: %w", err)
+	}
+	if err := os.MkdirAll(abs, 0755); err != nil {
+		return nil, fmt.Errorf("local storage root: %w", err)
+	}
+	return &LocalStorage{root: abs, publicURL: strings.TrimRight(publicURL, "/")}, nil
+}
+
+// NewLocalStorageFromEnv reads STORAGE_LOCAL_DIR, STORAGE_PUBLIC_URL and the
+// signing settings STORAGE_LOCAL_SECRET / STORAGE_LOCAL_URL_TTL. The API and
+// the worker must share the same secret: the worker signs, the API verifies.
+func NewLocalStorageFromEnv() (*LocalStorage, error) {
+	root := firstNonEmpty(os.Getenv("STORAGE_LOCAL_DIR"), DefaultLocalStorageDir)
+	publicURL := firstNonEmpty(os.Getenv("STORAGE_PUBLIC_URL"), DefaultLocalStoragePublicURL)
+	s, err := NewLocalStorage(root, publicURL)
+	if err != nil {
+		return nil, err
+	}
+	secret := firstNonEmpty(os.Getenv("STORAGE_LOCAL_SECRET"))
+	if secret == "" {
+		log.Printf("Warning: STORAGE_LOCAL_SECRET not set — local storage URLs are public, use for development only")
+		return s, nil
+	}
+	ttl := DefaultLocalURLTTL
+	if v := os.Getenv("STORAGE_LOCAL_URL_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid STORAGE_LOCAL_URL_TTL %q", v)
+		}
+		ttl = d
+	}
+	s.SetSigningKey([]byte(secret), ttl)
+	return s, nil
+}
+
+// SetSigningKey makes Put and SignedURL return URLs signed with secret and
+// Verify reject any other. Put URLs expire after ttl.
+func (s *LocalStorage) SetSigningKey(secret []byte, ttl time.Duration) {
+	s.secret = secret
+	s.urlTTL = ttl
+}
+
+// Root returns the absolute directory objects are stored in.
+func (s *LocalStorage) Root() string {
+	return s.root
+}
+
+// path maps an object key to a file path, rejecting keys that escape the root.
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := filepath.Clean("/" + filepath.FromSlash(key))
+	if clean == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+func (s *LocalStorage) objectURL(key string) string {
+	return s.publicURL + "/" + strings.TrimLeft(key, "/")
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", fmt.Errorf("local put %s: %w", key, err)
+	}
+
+	// Write to a temp file first so concurrent readers never see a partial object.
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".put-*")
+	if err != nil {
+		return "", fmt.Errorf("local put %s: %w", key, err)
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("local put %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("local put %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("local put %s: %w", key, err)
+	}
+	return s.signedURL(key, s.urlTTL), nil
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("local get %s: %w", key, err)
+	}
+	return f, nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("local delete %s: %w", key, err)
+	}
+	return nil
+}
+
+// SignedURL returns the object URL valid for ttl, or the plain URL when no signing key is set.
+func (s *LocalStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	return s.signedURL(key, ttl), nil
+}
+
+func (s *LocalStorage) signedURL(key string, ttl time.Duration) string {
+	if len(s.secret) == 0 {
+		return s.objectURL(key)
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	q := url.Values{"expires": {expires}, "signature": {s.sign(key, expires)}}
+	return s.objectURL(key) + "?" + q.Encode()
+}
+
+// sign is the hex HMAC-SHA256 of the cleaned object key and the expiry timestamp.
+func (s *LocalStorage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.TrimLeft(key, "/") + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the expires and signature query parameters of an object URL.
+// Every request passes when no signing key is set.
+func (s *LocalStorage) Verify(key, expires, signature string) error {
+	if len(s.secret) == 0 {
+		return nil
+	}
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(s.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".put-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if strings.HasPrefix(key, prefix) {
+			files = append(files, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("local list: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalStorage_putGetListDelete(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "http://localhost:5000/api/files")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	ctx := context.Background()
+
+	url, err := s.Put(ctx, "templates/a.pdf", strings.NewReader("%PDF-1.4"), 8, "application/pdf")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if url != "http://localhost:5000/api/files/templates/a.pdf" {
+		t.Errorf("Put url = %q", url)
+	}
+
+	rc, err := s.Get(ctx, "templates/a.pdf")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "%PDF-1.4" {
+		t.Errorf("Get body = %q", body)
+	}
+
+	if _, err := s.Put(ctx, "covers/b.png", strings.NewReader("png"), 3, "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	files, err := s.List(ctx, "templates/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0] != "templates/a.pdf" {
+		t.Errorf("List(templates/) = %v", files)
+	}
+
+	if err := s.Delete(ctx, "templates/a.pdf"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "templates/a.pdf"); err == nil {
+		t.Error("Get after Delete: expected error")
+	}
+}
+
+func TestLocalStorage_keyCannotEscapeRoot(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStorage(root, "/files")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	p, err := s.path("../../etc/passwd")
+	if err != nil {
+		t.Fatalf("path: %v", err)
+	}
+	if !strings.HasPrefix(p, s.Root()) {
+		t.Errorf("path %q escapes root %q", p, s.Root())
+	}
+}
+
+func TestLocalStorage_signedURLs(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/api/files")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	s.SetSigningKey([]byte("secret"), time.Hour)
+	ctx := context.Background()
+
+	raw, err := s.Put(ctx, "pdfs/a.pdf", strings.NewReader("%PDF-1.4"), 8, "application/pdf")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("Put url %q: %v", raw, err)
+	}
+	if u.Path != "/api/files/pdfs/a.pdf" {
+		t.Errorf("Put url path = %q", u.Path)
+	}
+	q := u.Query()
+	if err := s.Verify("pdfs/a.pdf", q.Get("expires"), q.Get("signature")); err != nil {
+		t.Errorf("Verify(Put url) = %v", err)
+	}
+	if err := s.Verify("pdfs/b.pdf", q.Get("expires"), q.Get("signature")); err == nil {
+		t.Error("signature of another key should be rejected")
+	}
+	if err := s.Verify("pdfs/a.pdf", "9999999999", q.Get("signature")); err == nil {
+		t.Error("tampered expiry should be rejected")
+	}
+	if err := s.Verify("pdfs/a.pdf", "", ""); err == nil {
+		t.Error("unsigned request should be rejected")
+	}
+
+	expired, err := s.SignedURL(ctx, "pdfs/a.pdf", -time.Minute)
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	u, _ = url.Parse(expired)
+	if err := s.Verify("pdfs/a.pdf", u.Query().Get("expires"), u.Query().Get("signature")); err == nil {
+		t.Error("expired URL should be rejected")
+	}
+}
+
+func TestLocalStorage_unsignedWithoutKey(t *testing.T) {
+	s, err := NewLocalStorage(t.TempDir(), "/api/files")
+	if err != nil {
+		t.Fatalf("NewLocalStorage: %v", err)
+	}
+	u, err := s.SignedURL(context.Background(), "pdfs/a.pdf", time.Hour)
+	if err != nil || u != "/api/files/pdfs/a.pdf" {
+		t.Errorf("SignedURL = %q, %v", u, err)
+	}
+	if err := s.Verify("pdfs/a.pdf", "", ""); err != nil {
+		t.Errorf("Verify without key = %v", err)
+	}
+}
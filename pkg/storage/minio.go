@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	storagecfg "designmypdf/config/storage"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// MinioStorage stores objects in a MinIO or any S3-compatible bucket.
+type MinioStorage struct {
+	client    *minio.Client
+	bucket    string
+	publicURL string
+}
+
+// NewMinioStorageFromEnv reads STORAGE_ENDPOINT, STORAGE_ACCESS_KEY, STORAGE_SECRET_KEY,
+// STORAGE_BUCKET and optionally STORAGE_USE_SSL, STORAGE_REGION and STORAGE_PUBLIC_URL.
+// STORAGE_ENDPOINT may include a scheme (http://localhost:9000); https implies SSL.
+func NewMinioStorageFromEnv() (*MinioStorage, error) {
+	endpoint := strings.TrimSpace(os.Getenv("STORAGE_ENDPOINT"))
+	bucket := strings.TrimSpace(os.Getenv("STORAGE_BUCKET"))
+	if endpoint == "" || bucket == "" {
+		return nil, fmt.Errorf("minio env missing: set STORAGE_ENDPOINT, STORAGE_ACCESS_KEY, STORAGE_SECRET_KEY, STORAGE_BUCKET")
+	}
+
+	useSSL := strings.EqualFold(os.Getenv("STORAGE_USE_SSL"), "true")
+	if strings.HasPrefix(endpoint, "https://") {
+		useSSL = true
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(endpoint, "https://"), "http://")
+	host = strings.TrimRight(host, "/")
+
+	client, err := storagecfg.InitializeMinio(map[string]string{
+		"STORAGE_ENDPOINT":   host,
+		"STORAGE_ACCESS_KEY": os.Getenv("STORAGE_ACCESS_KEY"),
+		"STORAGE_SECRET_KEY": os.Getenv("STORAGE_SECRET_KEY"),
+		"STORAGE_USE_SSL":    fmt.Sprintf("%t", useSSL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("minio client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("minio bucket check: %w", err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: os.Getenv("STORAGE_REGION")}); err != nil {
+			return nil, fmt.Errorf("minio make bucket: %w", err)
+		}
+	}
+
+	publicURL := strings.TrimRight(strings.TrimSpace(os.Getenv("STORAGE_PUBLIC_URL")), "/")
+	if publicURL == "" {
+		scheme := "http"
+		if useSSL {
+			scheme = "https"
+		}
+		publicURL = fmt.Sprintf("%s://%s/%s", scheme, host, bucket)
+	}
+
+	return &MinioStorage{client: client, bucket: bucket, publicURL: publicURL}, nil
+}
+
+func (s *MinioStorage) objectURL(key string) string {
+	return s.publicURL + "/" + key
+}
+
+func (s *MinioStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 25*time.Second)
+	defer cancel()
+
+	if _, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType}); err != nil {
+		return "", fmt.Errorf("minio put %s: %w", key, err)
+	}
+	return s.objectURL(key), nil
+}
+
+func (s *MinioStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("minio get %s: %w", key, err)
+	}
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, fmt.Errorf("minio get %s: %w", key, err)
+	}
+	return obj, nil
+}
+
+func (s *MinioStorage) Delete(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("minio delete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *MinioStorage) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("minio presign %s: %w", key, err)
+	}
+	return u.String(), nil
+}
+
+func (s *MinioStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	const maxResults = 1000
+	var files []string
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("minio list: %w", obj.Err)
+		}
+		files = append(files, obj.Key)
+		if len(files) >= maxResults {
+			break
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ObjectStore is the storage backend used for generated PDFs and uploaded assets.
+// Put returns the public URL of the stored object.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (string, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Backend names accepted in STORAGE_BACKEND.
+const (
+	BackendBackblaze = "backblaze"
+	BackendMinio     = "minio"
+	BackendS3        = "s3"
+	BackendLocal     = "local"
+)
+
+// BackendFromEnv returns the normalized STORAGE_BACKEND value (backblaze when unset).
+func BackendFromEnv() string {
+	b := strings.ToLower(strings.TrimSpace(os.Getenv("STORAGE_BACKEND")))
+	if b == "" {
+		return BackendBackblaze
+	}
+	return b
+}
+
+// NewFromEnv builds the ObjectStore selected by STORAGE_BACKEND:
+//   - backblaze (default): BACKBLAZE_KEY_ID / BACKBLAZE_APP_KEY / BACKBLAZE_BUCKET_NAME (or legacy B2_*)
+//   - minio or s3: STORAGE_ENDPOINT / STORAGE_ACCESS_KEY / STORAGE_SECRET_KEY / STORAGE_BUCKET
+//   - local: STORAGE_LOCAL_DIR (default ./uploads/storage)
+func NewFromEnv() (ObjectStore, error) {
+	var (
+		store ObjectStore
+		err   error
+	)
+	switch backend := BackendFromEnv(); backend {
+	case BackendBackblaze:
+		keyID, appKey, bucketName, ok := B2ConfigFromEnv()
+		if !ok {
+			return nil, fmt.Errorf("backblaze B2 env missing: set BACKBLAZE_KEY_ID, BACKBLAZE_APP_KEY, BACKBLAZE_BUCKET_NAME")
+		}
+		store, err = NewBackblazeStorage(keyID, appKey, bucketName)
+	case BackendMinio, BackendS3:
+		store, err = NewMinioStorageFromEnv()
+	case BackendLocal:
+		store, err = NewLocalStorageFromEnv()
+	default:
+		return nil, fmt.Errorf("unsupported STORAGE_BACKEND %q (expected backblaze, minio, s3 or local)", backend)
+	}
+	// Never hand back a typed nil pointer wrapped in the interface.
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+var (
+	_ ObjectStore = (*BackblazeStorage)(nil)
+	_ ObjectStore = (*MinioStorage)(nil)
+	_ ObjectStore = (*LocalStorage)(nil)
+)
@@ -51,7 +51,9 @@ func (r *Repository) GetAll() ([]*entities.Template, error) {
 }
 func (r *Repository) GetAllUserTemplates(userID uint) (*[]entities.Template, error) {
 	var templates []entities.Template
-	if err := r.db.Joins("JOIN namespaces ON namespaces.id = templates.namespace_id").
+	// List view: template bodies are left out, see the preview endpoint.
+	if err := r.db.Omit("content", "variables", "variables_schema", "pdf_header", "pdf_footer", "translations").
+		Joins("JOIN namespaces ON namespaces.id = templates.namespace_id").
 		Where("namespaces.user_id = ?", userID).Find(&templates).Error; err != nil {
 		return nil, err
 	}
@@ -92,11 +94,13 @@ func (r *Repository) ListUserTemplates(f ListUserTemplatesFilter) (*ListUserTemp
 	var items []entities.TemplateListItem
 	q := r.db.Model(&entities.Template{}).
 		Select(`templates.id, templates.created_at, templates.updated_at, templates.deleted_at,
-			templates.uuid, templates.name, templates.content, templates.variables,
+			templates.uuid, templates.name,
 			templates.framework, templates.fonts,
-			templates.namespace_id, templates.description,
+			templates.namespace_id, templates.description, templates.thumbnail_url,
 			templates.price, templates.is_marketplace, templates.is_published, templates.category,
-			templates.uses_count, templates.pdf_background_color, templates.pdf_content_padding`).
+			templates.uses_count, templates.pdf_background_color, templates.pdf_content_padding,
+			templates.pdf_format, templates.pdf_orientation, templates.pdf_margin,
+			templates.latest_version, templates.published_version`).
 		Joins("JOIN namespaces ON namespaces.id = templates.namespace_id").
 		Where("namespaces.user_id = ?", f.UserID)
 
@@ -128,3 +132,34 @@ func (r *Repository) UpdateFields(id uint, fields map[string]interface{}) (*enti
 	}
 	return &template, nil
 }
+
+// Transaction runs fn with a repository bound to a single database transaction.
+func (r *Repository) Transaction(fn func(repo *Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepository(tx))
+	})
+}
+
+func (r *Repository) CreateVersion(version *entities.TemplateVersion) error {
+	return r.db.Create(version).Error
+}
+
+func (r *Repository) GetVersion(templateID uint, version int) (*entities.TemplateVersion, error) {
+	var v entities.TemplateVersion
+	if err := r.db.Where("template_id = ? AND version = ?", templateID, version).First(&v).Error; err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListVersions returns the versions of a template, newest first, without their content.
+func (r *Repository) ListVersions(templateID uint) ([]entities.TemplateVersion, error) {
+	var versions []entities.TemplateVersion
+	if err := r.db.Select("id, template_id, version, created_at").
+		Where("template_id = ?", templateID).
+		Order("version DESC").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
@@ -0,0 +1,139 @@
+package template
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"designmypdf/pkg/entities"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// maxCompiledSchemas bounds the compiled schema cache; it is simply reset when full.
+const maxCompiledSchemas = 512
+
+var (
+	compiledSchemasMu sync.Mutex
+	compiledSchemas   = map[string]*jsonschema.Schema{}
+)
+
+// FieldError is one payload value that does not satisfy the template schema.
+// Field is a dotted path ("customer.address.zip", "items.0.price"); empty for the root.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// PayloadError is returned by ValidatePayload with every failing field.
+type PayloadError struct {
+	Errors []FieldError
+}
+
+func (e *PayloadError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		if fe.Field == "" {
+			msgs[i] = fe.Message
+		} else {
+			msgs[i] = fe.Field + ": " + fe.Message
+		}
+	}
+	return "payload does not match template schema: " + strings.Join(msgs, "; ")
+}
+
+// HasSchema reports whether the template declares a variables schema.
+func HasSchema(t *entities.Template) bool {
+	raw := bytes.TrimSpace(t.VariablesSchema)
+	return len(raw) > 0 && !bytes.Equal(raw, []byte("null"))
+}
+
+// CompileSchema parses and compiles a JSON Schema (draft 2020-12 unless $schema
+// says otherwise). Remote and file $refs are not resolved.
+func CompileSchema(raw []byte) (*jsonschema.Schema, error) {
+	sum := sha256.Sum256(raw)
+	key := hex.EncodeToString(sum[:])
+
+	compiledSchemasMu.Lock()
+	sch, ok := compiledSchemas[key]
+	compiledSchemasMu.Unlock()
+	if ok {
+		return sch, nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema JSON: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.UseLoader(jsonschema.SchemeURLLoader{})
+	if err := c.AddResource("variables.json", doc); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	sch, err = c.Compile("variables.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	compiledSchemasMu.Lock()
+	if len(compiledSchemas) >= maxCompiledSchemas {
+		compiledSchemas = map[string]*jsonschema.Schema{}
+	}
+	compiledSchemas[key] = sch
+	compiledSchemasMu.Unlock()
+	return sch, nil
+}
+
+// ValidatePayload checks a raw JSON payload against the template schema.
+// Templates without schema accept anything. A *PayloadError lists field errors.
+func ValidatePayload(t *entities.Template, payload []byte) error {
+	if !HasSchema(t) {
+		return nil
+	}
+	sch, err := CompileSchema(t.VariablesSchema)
+	if err != nil {
+		return fmt.Errorf("template %s: %w", t.UUID, err)
+	}
+
+	inst, err := jsonschema.UnmarshalJSON(bytes.NewReader(payload))
+	if err != nil {
+		return &PayloadError{Errors: []FieldError{{Message: "payload is not valid JSON"}}}
+	}
+
+	err = sch.Validate(inst)
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	var fieldErrors []FieldError
+	for _, unit := range ve.BasicOutput().Errors {
+		if unit.Error == nil {
+			continue
+		}
+		fieldErrors = append(fieldErrors, FieldError{
+			Field:   pointerToField(unit.InstanceLocation),
+			Message: unit.Error.String(),
+		})
+	}
+	if len(fieldErrors) == 0 {
+		fieldErrors = []FieldError{{Message: ve.Error()}}
+	}
+	return &PayloadError{Errors: fieldErrors}
+}
+
+// pointerToField turns a JSON pointer (/items/0/price) into items.0.price.
+func pointerToField(pointer string) string {
+	pointer = strings.TrimPrefix(pointer, "/")
+	if pointer == "" {
+		return ""
+	}
+	parts := strings.Split(pointer, "/")
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(p, "~1", "/"), "~0", "~")
+	}
+	return strings.Join(parts, ".")
+}
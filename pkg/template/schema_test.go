@@ -0,0 +1,68 @@
+package template
+
+import (
+	"designmypdf/pkg/entities"
+	"errors"
+	"testing"
+
+	"gorm.io/datatypes"
+)
+
+const invoiceSchema = `{
+	"type": "object",
+	"required": ["customer", "items"],
+	"properties": {
+		"customer": {
+			"type": "object",
+			"required": ["name"],
+			"properties": {"name": {"type": "string", "minLength": 1}}
+		},
+		"items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {"price": {"type": "number", "minimum": 0}}
+			}
+		}
+	}
+}`
+
+func TestValidatePayload_FieldErrors(t *testing.T) {
+	tpl := &entities.Template{UUID: "tpl", VariablesSchema: datatypes.JSON(invoiceSchema)}
+
+	if err := ValidatePayload(tpl, []byte(`{"customer":{"name":"ACME"},"items":[{"price":12.5}]}`)); err != nil {
+		t.Fatalf("valid payload rejected: %v", err)
+	}
+
+	err := ValidatePayload(tpl, []byte(`{"customer":{},"items":[{"price":-1}]}`))
+	var payloadErr *PayloadError
+	if !errors.As(err, &payloadErr) {
+		t.Fatalf("err = %v, want *PayloadError", err)
+	}
+	fields := map[string]bool{}
+	for _, fe := range payloadErr.Errors {
+		fields[fe.Field] = true
+	}
+	for _, want := range []string{"customer", "items.0.price"} {
+		if !fields[want] {
+			t.Errorf("missing error for %q in %+v", want, payloadErr.Errors)
+		}
+	}
+}
+
+func TestValidatePayload_NoSchema(t *testing.T) {
+	for _, schema := range []string{"", "null"} {
+		tpl := &entities.Template{VariablesSchema: datatypes.JSON(schema)}
+		if err := ValidatePayload(tpl, []byte(`{"anything":1}`)); err != nil {
+			t.Errorf("schema %q: %v", schema, err)
+		}
+	}
+}
+
+func TestCompileSchema_RejectsInvalid(t *testing.T) {
+	for _, schema := range []string{`{"type":`, `{"type":"nope"}`, `{"$ref":"https://example.com/s.json"}`} {
+		if _, err := CompileSchema([]byte(schema)); err == nil {
+			t.Errorf("CompileSchema(%s) succeeded", schema)
+		}
+	}
+}
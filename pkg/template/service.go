@@ -4,6 +4,8 @@ import (
 	"designmypdf/config/database"
 	"designmypdf/pkg/entities"
 	"designmypdf/pkg/namespace"
+	"designmypdf/utils"
+	"strings"
 
 	"gorm.io/datatypes"
 )
@@ -16,9 +18,66 @@ type Service interface {
 	ListUserTemplates(userID uint, namespaceID *uint, query string, page, limit int) (*ListUserTemplatesResult, error)
 	Get(ID uint) (*entities.Template, error)
 	GetByUUID(UUID string) (*entities.Template, error)
-	Update(ID uint, name string, content string, variables datatypes.JSON, fonts entities.MultiString, pdfBackgroundColor string, pdfContentPadding string) (*entities.Template, error)
+	Update(ID uint, name string, content string, variables datatypes.JSON, variablesSchema datatypes.JSON, fonts entities.MultiString, pdf PdfSettings, l10n Localization) (*entities.Template, error)
 	UpdateFull(ID uint, fields map[string]interface{}) (*entities.Template, error)
 	ChangeTemplateNamespace(ID uint, NamespaceID uint) error
+	ResolveByUUID(UUID string, version int) (*entities.Template, int, error)
+	ListVersions(ID uint) ([]entities.TemplateVersion, error)
+	GetVersion(ID uint, version int) (*entities.TemplateVersion, error)
+	Publish(ID uint, version int) (*entities.Template, error)
+	Rollback(ID uint, version int) (*entities.Template, error)
+	DiffVersions(ID uint, from, to int) (*VersionDiff, error)
+}
+
+// PdfSettings groups the per-template rendering defaults stored next to the content.
+type PdfSettings struct {
+	BackgroundColor string
+	ContentPadding  string
+	Format          string
+	Orientation     string
+	Margin          string
+	Header          string
+	Footer          string
+	HeaderHeight    string
+	FooterHeight    string
+	Permissions     entities.MultiString
+	Title           string
+	Author          string
+	Subject         string
+	Keywords        string
+	Filename        string
+}
+
+// PdfSettingsOf returns the rendering defaults currently set on t.
+func PdfSettingsOf(t *entities.Template) PdfSettings {
+	return PdfSettings{
+		BackgroundColor: t.PdfBackgroundColor,
+		ContentPadding:  t.PdfContentPadding,
+		Format:          t.PdfFormat,
+		Orientation:     t.PdfOrientation,
+		Margin:          t.PdfMargin,
+		Header:          t.PdfHeader,
+		Footer:          t.PdfFooter,
+		HeaderHeight:    t.PdfHeaderHeight,
+		FooterHeight:    t.PdfFooterHeight,
+		Permissions:     t.PdfPermissions,
+		Title:           t.PdfTitle,
+		Author:          t.PdfAuthor,
+		Subject:         t.PdfSubject,
+		Keywords:        t.PdfKeywords,
+		Filename:        t.PdfFilename,
+	}
+}
+
+// Localization groups the translation bundles of a template and its default locale.
+type Localization struct {
+	Translations  datatypes.JSON
+	DefaultLocale string
+}
+
+// LocalizationOf returns the localization currently set on t.
+func LocalizationOf(t *entities.Template) Localization {
+	return Localization{Translations: t.Translations, DefaultLocale: t.DefaultLocale}
 }
 
 type service struct {
@@ -32,7 +91,7 @@ func NewService(r Repository) Service {
 	}
 }
 
-// Create creates a new template with the given name and userID.
+// Create creates a new template with the given name and userID, as version 1 (unpublished).
 func (s *service) Create(name string, content string, variables datatypes.JSON, fonts entities.MultiString, namespaceID uint) (*entities.Template, error) {
 	template := &entities.Template{
 		Name:        name,
@@ -42,7 +101,16 @@ func (s *service) Create(name string, content string, variables datatypes.JSON,
 		Fonts:       fonts,
 		Framework:   entities.Tailwind,
 	}
-	if err := s.repository.Create(template); err != nil {
+	err := s.repository.Transaction(func(repo *Repository) error {
+		if err := repo.Create(template); err != nil {
+			return err
+		}
+		if _, err := appendVersion(repo, template); err != nil {
+			return err
+		}
+		return repo.Update(template)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return template, nil
@@ -69,7 +137,7 @@ func (s *service) GetUserTemplates(userID uint) (*[]entities.Template, error) {
 	return Templates, nil
 }
 
-// ListUserTemplates returns a paginated list for dashboard cards (includes content for preview).
+// ListUserTemplates returns a paginated list for dashboard cards (without template bodies).
 func (s *service) ListUserTemplates(userID uint, namespaceID *uint, query string, page, limit int) (*ListUserTemplatesResult, error) {
 	if page < 1 {
 		page = 1
@@ -90,25 +158,206 @@ func (s *service) ListUserTemplates(userID uint, namespaceID *uint, query string
 	})
 }
 
-// Update updates the name of the template with the given ID.
-func (s *service) Update(ID uint, name string, content string, variables datatypes.JSON, fonts entities.MultiString, pdfBackgroundColor string, pdfContentPadding string) (*entities.Template, error) {
-	template, err := s.repository.Get(ID)
+// Update saves the draft of the template with the given ID and appends a
+// version when a rendering field changed. The published version is untouched.
+func (s *service) Update(ID uint, name string, content string, variables datatypes.JSON, variablesSchema datatypes.JSON, fonts entities.MultiString, pdf PdfSettings, l10n Localization) (*entities.Template, error) {
+	var template *entities.Template
+	err := s.repository.Transaction(func(repo *Repository) error {
+		var err error
+		template, err = repo.Get(ID)
+		if err != nil {
+			return err
+		}
+
+		var latest *entities.TemplateVersion
+		if template.LatestVersion > 0 {
+			if latest, err = getVersion(repo, ID, template.LatestVersion); err != nil {
+				return err
+			}
+		} else if strings.TrimSpace(template.Content) != "" {
+			// Template saved before versioning existed: keep rendering what
+			// production renders today until a new version is published.
+			if latest, err = appendVersion(repo, template); err != nil {
+				return err
+			}
+			template.PublishedVersion = latest.Version
+		}
+
+		template.Name = name
+		template.Content = content
+		template.Variables = variables
+		template.VariablesSchema = variablesSchema
+		template.Fonts = fonts
+		template.PdfBackgroundColor = pdf.BackgroundColor
+		template.PdfContentPadding = pdf.ContentPadding
+		template.PdfFormat = pdf.Format
+		template.PdfOrientation = pdf.Orientation
+		template.PdfMargin = pdf.Margin
+		template.PdfHeader = pdf.Header
+		template.PdfFooter = pdf.Footer
+		template.PdfHeaderHeight = pdf.HeaderHeight
+		template.PdfFooterHeight = pdf.FooterHeight
+		template.PdfPermissions = pdf.Permissions
+		template.PdfTitle = pdf.Title
+		template.PdfAuthor = pdf.Author
+		template.PdfSubject = pdf.Subject
+		template.PdfKeywords = pdf.Keywords
+		template.PdfFilename = pdf.Filename
+		template.Translations = l10n.Translations
+		template.DefaultLocale = l10n.DefaultLocale
+
+		if latest == nil || !sameSnapshot(latest, snapshotOf(template)) {
+			if _, err := appendVersion(repo, template); err != nil {
+				return err
+			}
+		}
+		return repo.Update(template)
+	})
 	if err != nil {
 		return nil, err
 	}
-	template.Name = name
-	template.Content = content
-	template.Variables = variables
-	template.Fonts = fonts
-	template.PdfBackgroundColor = pdfBackgroundColor
-	template.PdfContentPadding = pdfContentPadding
+	return template, nil
+}
+
+// ResolveByUUID loads the template to render: version is a version number,
+// VersionPublished or VersionDraft. It returns the template with the version
+// fields applied and the concrete version rendered (VersionDraft for the draft).
+func (s *service) ResolveByUUID(UUID string, version int) (*entities.Template, int, error) {
+	template, err := s.repository.GetByUUID(UUID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version == VersionPublished {
+		version = template.PublishedVersion
+		if version == 0 {
+			version = VersionDraft
+		}
+	}
+	if version != VersionDraft {
+		v, err := getVersion(&s.repository, template.ID, version)
+		if err != nil {
+			return nil, 0, err
+		}
+		applyVersion(template, v)
+	}
+	if err := loadPartials(template); err != nil {
+		return nil, 0, err
+	}
+	return template, version, nil
+}
+
+// loadPartials sets the namespace partials the template calls. Partials are
+// shared and not versioned: every version renders their current content.
+func loadPartials(t *entities.Template) error {
+	if len(utils.PartialReferences(t.Content+"\n"+t.PdfHeader+"\n"+t.PdfFooter)) == 0 {
+		return nil
+	}
+	sources, err := namespace.NewRepository(database.DB).PartialSources(t.NamespaceID)
+	if err != nil {
+		return err
+	}
+	t.Partials = utils.ReachablePartials(sources, t.Content, t.PdfHeader, t.PdfFooter)
+	return nil
+}
 
+// ListVersions returns the versions of a template, newest first, without content.
+func (s *service) ListVersions(ID uint) ([]entities.TemplateVersion, error) {
+	if _, err := s.repository.Get(ID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListVersions(ID)
+}
+
+// GetVersion returns one version of a template.
+func (s *service) GetVersion(ID uint, version int) (*entities.TemplateVersion, error) {
+	return getVersion(&s.repository, ID, version)
+}
+
+// Publish makes version the one generate-pdf renders by default.
+func (s *service) Publish(ID uint, version int) (*entities.Template, error) {
+	template, err := s.repository.Get(ID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := getVersion(&s.repository, ID, version); err != nil {
+		return nil, err
+	}
+	template.PublishedVersion = version
 	if err := s.repository.Update(template); err != nil {
 		return nil, err
 	}
 	return template, nil
 }
 
+// Rollback restores version into the draft, saves it as a new version and
+// publishes it. History is never rewritten.
+func (s *service) Rollback(ID uint, version int) (*entities.Template, error) {
+	var template *entities.Template
+	err := s.repository.Transaction(func(repo *Repository) error {
+		var err error
+		if template, err = repo.Get(ID); err != nil {
+			return err
+		}
+		target, err := getVersion(repo, ID, version)
+		if err != nil {
+			return err
+		}
+		applyVersion(template, target)
+
+		published := template.LatestVersion
+		latest, err := getVersion(repo, ID, template.LatestVersion)
+		if err != nil || !sameSnapshot(latest, target) {
+			v, err := appendVersion(repo, template)
+			if err != nil {
+				return err
+			}
+			published = v.Version
+		}
+		template.PublishedVersion = published
+		return repo.Update(template)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// DiffVersions compares two versions; from/to accept VersionPublished and VersionDraft.
+func (s *service) DiffVersions(ID uint, from, to int) (*VersionDiff, error) {
+	template, err := s.repository.Get(ID)
+	if err != nil {
+		return nil, err
+	}
+	fromVersion, fromNumber, err := s.versionOf(template, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, toNumber, err := s.versionOf(template, to)
+	if err != nil {
+		return nil, err
+	}
+	return &VersionDiff{
+		From:    fromNumber,
+		To:      toNumber,
+		Changes: diffVersions(fromVersion, toVersion, versionLabel(fromNumber), versionLabel(toNumber)),
+	}, nil
+}
+
+// versionOf returns the snapshot of a version of t, the draft being snapshotted on the fly.
+func (s *service) versionOf(t *entities.Template, version int) (*entities.TemplateVersion, int, error) {
+	if version == VersionPublished {
+		version = t.PublishedVersion
+		if version == 0 {
+			version = VersionDraft
+		}
+	}
+	if version == VersionDraft {
+		return snapshotOf(t), VersionDraft, nil
+	}
+	v, err := getVersion(&s.repository, t.ID, version)
+	return v, version, err
+}
+
 // Update updates the name of the template with the given ID.
 func (s *service) ChangeTemplateNamespace(ID uint, NamespaceID uint) error {
 	template, err := s.repository.Get(ID)
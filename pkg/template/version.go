@@ -0,0 +1,192 @@
+package template
+
+import (
+	"designmypdf/pkg/entities"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
+)
+
+const (
+	// VersionPublished resolves to the published version, or to the draft
+	// while the template has never been published.
+	VersionPublished = 0
+	// VersionDraft resolves to the working copy edited in the dashboard.
+	VersionDraft = -1
+)
+
+var ErrVersionNotFound = errors.New("template version not found")
+
+// ParseVersion reads a ?version= value: empty or "published", "draft"
+// (or "latest"), or a version number.
+func ParseVersion(s string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "published":
+		return VersionPublished, nil
+	case "draft", "latest":
+		return VersionDraft, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid version %q (a number, \"published\" or \"draft\")", s)
+	}
+	return n, nil
+}
+
+// FieldDiff is the unified diff of one versioned field.
+type FieldDiff struct {
+	Field string `json:"field"`
+	Diff  string `json:"diff"`
+}
+
+// VersionDiff lists the fields that differ between two versions of a template.
+// From and To are version numbers, VersionDraft for the working copy.
+type VersionDiff struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Changes []FieldDiff `json:"changes"`
+}
+
+// snapshotOf copies the rendering fields of t into an unsaved version.
+func snapshotOf(t *entities.Template) *entities.TemplateVersion {
+	return &entities.TemplateVersion{
+		TemplateID:         t.ID,
+		Content:            t.Content,
+		Framework:          t.Framework,
+		Variables:          t.Variables,
+		VariablesSchema:    t.VariablesSchema,
+		Fonts:              t.Fonts,
+		PdfBackgroundColor: t.PdfBackgroundColor,
+		PdfContentPadding:  t.PdfContentPadding,
+		PdfFormat:          t.PdfFormat,
+		PdfOrientation:     t.PdfOrientation,
+		PdfMargin:          t.PdfMargin,
+		PdfHeader:          t.PdfHeader,
+		PdfFooter:          t.PdfFooter,
+		PdfHeaderHeight:    t.PdfHeaderHeight,
+		PdfFooterHeight:    t.PdfFooterHeight,
+		PdfPermissions:     t.PdfPermissions,
+		PdfTitle:           t.PdfTitle,
+		PdfAuthor:          t.PdfAuthor,
+		PdfSubject:         t.PdfSubject,
+		PdfKeywords:        t.PdfKeywords,
+		PdfFilename:        t.PdfFilename,
+		Translations:       t.Translations,
+		DefaultLocale:      t.DefaultLocale,
+	}
+}
+
+// applyVersion overlays the rendering fields of v on t.
+func applyVersion(t *entities.Template, v *entities.TemplateVersion) {
+	t.Content = v.Content
+	t.Framework = v.Framework
+	t.Variables = v.Variables
+	t.VariablesSchema = v.VariablesSchema
+	t.Fonts = v.Fonts
+	t.PdfBackgroundColor = v.PdfBackgroundColor
+	t.PdfContentPadding = v.PdfContentPadding
+	t.PdfFormat = v.PdfFormat
+	t.PdfOrientation = v.PdfOrientation
+	t.PdfMargin = v.PdfMargin
+	t.PdfHeader = v.PdfHeader
+	t.PdfFooter = v.PdfFooter
+	t.PdfHeaderHeight = v.PdfHeaderHeight
+	t.PdfFooterHeight = v.PdfFooterHeight
+	t.PdfPermissions = v.PdfPermissions
+	t.PdfTitle = v.PdfTitle
+	t.PdfAuthor = v.PdfAuthor
+	t.PdfSubject = v.PdfSubject
+	t.PdfKeywords = v.PdfKeywords
+	t.PdfFilename = v.PdfFilename
+	t.Translations = v.Translations
+	t.DefaultLocale = v.DefaultLocale
+}
+
+// versionFields lists the versioned fields of v as text, in diff order.
+func versionFields(v *entities.TemplateVersion) [][2]string {
+	return [][2]string{
+		{"content", v.Content},
+		{"framework", string(v.Framework)},
+		{"variables", string(v.Variables)},
+		{"variables_schema", string(v.VariablesSchema)},
+		{"fonts", strings.Join(v.Fonts, "\n")},
+		{"pdf_background_color", v.PdfBackgroundColor},
+		{"pdf_content_padding", v.PdfContentPadding},
+		{"pdf_format", v.PdfFormat},
+		{"pdf_orientation", v.PdfOrientation},
+		{"pdf_margin", v.PdfMargin},
+		{"pdf_header", v.PdfHeader},
+		{"pdf_footer", v.PdfFooter},
+		{"pdf_header_height", v.PdfHeaderHeight},
+		{"pdf_footer_height", v.PdfFooterHeight},
+		{"pdf_permissions", strings.Join(v.PdfPermissions, ",")},
+		{"pdf_title", v.PdfTitle},
+		{"pdf_author", v.PdfAuthor},
+		{"pdf_subject", v.PdfSubject},
+		{"pdf_keywords", v.PdfKeywords},
+		{"pdf_filename", v.PdfFilename},
+		{"translations", string(v.Translations)},
+		{"default_locale", v.DefaultLocale},
+	}
+}
+
+func sameSnapshot(a, b *entities.TemplateVersion) bool {
+	fa, fb := versionFields(a), versionFields(b)
+	for i := range fa {
+		if fa[i][1] != fb[i][1] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffVersions returns a unified diff per changed field.
+func diffVersions(from, to *entities.TemplateVersion, fromLabel, toLabel string) []FieldDiff {
+	changes := []FieldDiff{}
+	fa, fb := versionFields(from), versionFields(to)
+	for i := range fa {
+		if fa[i][1] == fb[i][1] {
+			continue
+		}
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(fa[i][1]),
+			B:        difflib.SplitLines(fb[i][1]),
+			FromFile: fromLabel + "/" + fa[i][0],
+			ToFile:   toLabel + "/" + fb[i][0],
+			Context:  3,
+		})
+		changes = append(changes, FieldDiff{Field: fa[i][0], Diff: diff})
+	}
+	return changes
+}
+
+func versionLabel(version int) string {
+	if version == VersionDraft {
+		return "draft"
+	}
+	return fmt.Sprintf("v%d", version)
+}
+
+// appendVersion stores the current state of t as version LatestVersion+1.
+// The caller saves t afterwards.
+func appendVersion(repo *Repository, t *entities.Template) (*entities.TemplateVersion, error) {
+	v := snapshotOf(t)
+	v.Version = t.LatestVersion + 1
+	if err := repo.CreateVersion(v); err != nil {
+		return nil, fmt.Errorf("failed to save version: %w", err)
+	}
+	t.LatestVersion = v.Version
+	return v, nil
+}
+
+func getVersion(repo *Repository, templateID uint, version int) (*entities.TemplateVersion, error) {
+	v, err := repo.GetVersion(templateID, version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	return v, err
+}
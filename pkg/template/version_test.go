@@ -0,0 +1,42 @@
+package template
+
+import (
+	"designmypdf/pkg/entities"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	cases := map[string]int{"": VersionPublished, "published": VersionPublished, "draft": VersionDraft, "Latest": VersionDraft, "3": 3}
+	for in, want := range cases {
+		got, err := ParseVersion(in)
+		if err != nil || got != want {
+			t.Errorf("ParseVersion(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"0", "-1", "v2", "1.5"} {
+		if _, err := ParseVersion(in); err == nil {
+			t.Errorf("ParseVersion(%q) succeeded", in)
+		}
+	}
+}
+
+func TestDiffVersions_OnlyChangedFields(t *testing.T) {
+	tpl := &entities.Template{Content: "<h1>{{title}}</h1>\n<p>Total</p>\n", PdfFormat: "A4"}
+	from := snapshotOf(tpl)
+	tpl.Content = "<h1>{{title}}</h1>\n<p>Grand total</p>\n"
+	to := snapshotOf(tpl)
+
+	if sameSnapshot(from, to) {
+		t.Fatal("sameSnapshot = true for different content")
+	}
+	changes := diffVersions(from, to, "v1", "v2")
+	if len(changes) != 1 || changes[0].Field != "content" {
+		t.Fatalf("changes = %+v, want only content", changes)
+	}
+	for _, want := range []string{"--- v1/content", "+++ v2/content", "-<p>Total</p>", "+<p>Grand total</p>"} {
+		if !strings.Contains(changes[0].Diff, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, changes[0].Diff)
+		}
+	}
+}
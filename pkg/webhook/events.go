@@ -5,9 +5,11 @@ const (
 	EventPdfJobQueued    = "PdfJobQueued"
 	EventPdfJobCompleted = "PdfJobCompleted"
 	EventPdfJobFailed    = "PdfJobFailed"
+
+	EventPdfBatchCompleted = "PdfBatchCompleted"
 )
 
 // AllEvents returns the list of all supported event names for API responses.
 func AllEvents() []string {
-	return []string{EventPdfJobQueued, EventPdfJobCompleted, EventPdfJobFailed}
+	return []string{EventPdfJobQueued, EventPdfJobCompleted, EventPdfJobFailed, EventPdfBatchCompleted}
 }
@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	OrientationPortrait  = "portrait"
+	OrientationLandscape = "landscape"
+)
+
+// Bounds accepted for custom paper sizes (Chrome rejects absurd values).
+const (
+	minPaperMm = 25.0
+	maxPaperMm = 5000.0
+)
+
+var customPaperRe = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?)\s*[x×]\s*(\d+(?:\.\d+)?)\s*(mm|cm|in)?$`)
+
+var lengthRe = regexp.MustCompile(`(?i)^(\d+(?:\.\d+)?|\.\d+)(mm|cm|in|px)?$`)
+
+// DefaultPdfHeaderFooterHeight is reserved for a header or footer without explicit height.
+const DefaultPdfHeaderFooterHeight = "15mm"
+
+// Margins are page margins in millimetres, applied by PrintToPDF.
+type Margins struct {
+	Top, Right, Bottom, Left float64
+}
+
+// PageLayout is a resolved paper size (after orientation) with its margins.
+type PageLayout struct {
+	WidthMm  float64
+	HeightMm float64
+	Margins  Margins
+}
+
+// ParsePaperSize accepts a named size (A4, Letter, B5...) or "W x H" with an
+// optional mm (default), cm or in unit, e.g. "210x99", "8.5x14in".
+// Empty means A4. Sizes are returned portrait as written.
+func ParsePaperSize(spec string) (widthMm, heightMm float64, err error) {
+	key := strings.ToUpper(strings.TrimSpace(spec))
+	if key == "" {
+		key = "A4"
+	}
+	if d, ok := paperSizesMM[key]; ok {
+		return d.widthMm, d.heightMm, nil
+	}
+
+	m := customPaperRe.FindStringSubmatch(strings.TrimSpace(spec))
+	if m == nil {
+		return 0, 0, fmt.Errorf("unknown paper size %q", spec)
+	}
+	w, _ := strconv.ParseFloat(m[1], 64)
+	h, _ := strconv.ParseFloat(m[2], 64)
+	factor := unitToMm(m[3])
+	w, h = w*factor, h*factor
+	if w < minPaperMm || h < minPaperMm || w > maxPaperMm || h > maxPaperMm {
+		return 0, 0, fmt.Errorf("paper size %q out of range (%.0f-%.0fmm)", spec, minPaperMm, maxPaperMm)
+	}
+	return w, h, nil
+}
+
+// ParseOrientation normalizes portrait/landscape; empty means portrait.
+func ParseOrientation(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", OrientationPortrait:
+		return OrientationPortrait, nil
+	case OrientationLandscape:
+		return OrientationLandscape, nil
+	}
+	return "", fmt.Errorf("invalid orientation %q (portrait or landscape)", s)
+}
+
+// ParseMargins reads a CSS-like shorthand of 1 to 4 lengths in mm (default),
+// cm, in or px, e.g. "10mm", "0.5in 1in", "10 15 10 15". Empty means no margin.
+func ParseMargins(s string) (Margins, error) {
+	parts := strings.Fields(strings.TrimSpace(s))
+	if len(parts) == 0 {
+		return Margins{}, nil
+	}
+	if len(parts) > 4 {
+		return Margins{}, fmt.Errorf("invalid margin %q: at most 4 values", s)
+	}
+
+	v := make([]float64, len(parts))
+	for i, p := range parts {
+		mm, err := ParseLengthMm(p)
+		if err != nil {
+			return Margins{}, fmt.Errorf("invalid margin value %q", p)
+		}
+		v[i] = mm
+	}
+
+	switch len(v) {
+	case 1:
+		return Margins{v[0], v[0], v[0], v[0]}, nil
+	case 2:
+		return Margins{v[0], v[1], v[0], v[1]}, nil
+	case 3:
+		return Margins{v[0], v[1], v[2], v[1]}, nil
+	default:
+		return Margins{v[0], v[1], v[2], v[3]}, nil
+	}
+}
+
+// ParseLengthMm converts one length in mm (default), cm, in or px to millimetres.
+func ParseLengthMm(s string) (float64, error) {
+	m := lengthRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid length %q", s)
+	}
+	n, _ := strconv.ParseFloat(m[1], 64)
+	return n * unitToMm(m[2]), nil
+}
+
+// IsValidPdfHeaderFooterHeight returns true for empty (use default) or a single length.
+func IsValidPdfHeaderFooterHeight(s string) bool {
+	if strings.TrimSpace(s) == "" {
+		return true
+	}
+	_, err := ParseLengthMm(s)
+	return err == nil
+}
+
+// NewPageLayout resolves format, orientation and margins into a PageLayout.
+// The page is only rotated for an explicit orientation: without one, a custom
+// size such as 100x50mm keeps the shape it was given.
+func NewPageLayout(format, orientation, margin string) (PageLayout, error) {
+	w, h, err := ParsePaperSize(format)
+	if err != nil {
+		return PageLayout{}, err
+	}
+	o, err := ParseOrientation(orientation)
+	if err != nil {
+		return PageLayout{}, err
+	}
+	explicit := strings.TrimSpace(orientation) != ""
+	if explicit && (o == OrientationLandscape && w < h || o == OrientationPortrait && w > h) {
+		w, h = h, w
+	}
+	m, err := ParseMargins(margin)
+	if err != nil {
+		return PageLayout{}, err
+	}
+	if m.Left+m.Right >= w || m.Top+m.Bottom >= h {
+		return PageLayout{}, errors.New("margins leave no printable area")
+	}
+	return PageLayout{WidthMm: w, HeightMm: h, Margins: m}, nil
+}
+
+// ReserveHeaderFooter grows the top/bottom margins so Chrome has room to draw
+// the header and footer; the printable area, and so the pagination math,
+// shrinks accordingly. Heights of 0 leave the margin untouched.
+func (l PageLayout) ReserveHeaderFooter(headerMm, footerMm float64) (PageLayout, error) {
+	l.Margins.Top = math.Max(l.Margins.Top, headerMm)
+	l.Margins.Bottom = math.Max(l.Margins.Bottom, footerMm)
+	if l.Margins.Top+l.Margins.Bottom >= l.HeightMm {
+		return PageLayout{}, errors.New("header and footer leave no printable area")
+	}
+	return l, nil
+}
+
+// layoutOrA4 is the lenient form used by the helpers taking a format title.
+func layoutOrA4(format string) PageLayout {
+	l, err := NewPageLayout(format, "", "")
+	if err != nil {
+		l, _ = NewPageLayout("A4", "", "")
+	}
+	return l
+}
+
+// PaperInches returns the page size in inches for PrintToPDF.
+func (l PageLayout) PaperInches() (width, height float64) {
+	return l.WidthMm / 25.4, l.HeightMm / 25.4
+}
+
+// MarginInches returns top, right, bottom, left margins in inches for PrintToPDF.
+func (l PageLayout) MarginInches() (top, right, bottom, left float64) {
+	return l.Margins.Top / 25.4, l.Margins.Right / 25.4, l.Margins.Bottom / 25.4, l.Margins.Left / 25.4
+}
+
+// ViewportCssPixels returns the printable area (page minus margins) in CSS pixels.
+func (l PageLayout) ViewportCssPixels() (widthPx, heightPx int) {
+	w := l.WidthMm - l.Margins.Left - l.Margins.Right
+	h := l.HeightMm - l.Margins.Top - l.Margins.Bottom
+	return int(math.Round(w * CssPxPerMm)), int(math.Round(h * CssPxPerMm))
+}
+
+// ContentAreaHeightPx is usable content height per page (printable height minus vertical padding).
+func (l PageLayout) ContentAreaHeightPx(paddingStored string) int {
+	_, h := l.ViewportCssPixels()
+	out := h - ParseVerticalPaddingPx(EffectivePdfContentPadding(paddingStored))
+	if out < 1 {
+		return 1
+	}
+	return out
+}
+
+// ContentAreaWidthPx is the fixed .content width in CSS pixels.
+func (l PageLayout) ContentAreaWidthPx() int {
+	w, _ := l.ViewportCssPixels()
+	return w
+}
+
+func unitToMm(unit string) float64 {
+	switch strings.ToLower(unit) {
+	case "cm":
+		return 10
+	case "in":
+		return 25.4
+	case "px":
+		return 1 / CssPxPerMm
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePaperSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		wantW float64
+		wantH float64
+		ok    bool
+	}{
+		{"empty is A4", "", 210, 297, true},
+		{"named lower case", "letter", 215.9, 279.4, true},
+		{"b series", "B5", 176, 250, true},
+		{"custom mm", "100x150", 100, 150, true},
+		{"custom inches", "8.5 x 14in", 215.9, 355.6, true},
+		{"custom cm", "10x15cm", 100, 150, true},
+		{"too small", "10x10mm", 0, 0, false},
+		{"unknown", "C4", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, err := ParsePaperSize(tt.in)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ParsePaperSize(%q) error = %v, want ok=%v", tt.in, err, tt.ok)
+			}
+			if math.Abs(w-tt.wantW) > 0.01 || math.Abs(h-tt.wantH) > 0.01 {
+				t.Errorf("ParsePaperSize(%q) = %gx%g, want %gx%g", tt.in, w, h, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestParseMargins(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Margins
+	}{
+		{"", Margins{}},
+		{"10", Margins{10, 10, 10, 10}},
+		{"10mm 20mm", Margins{10, 20, 10, 20}},
+		{"1cm 2cm 3cm", Margins{10, 20, 30, 20}},
+		{"1in 0 0 0", Margins{25.4, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		got, err := ParseMargins(tt.in)
+		if err != nil {
+			t.Fatalf("ParseMargins(%q) error = %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseMargins(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+	if _, err := ParseMargins("1 2 3 4 5"); err == nil {
+		t.Error("ParseMargins with 5 values should fail")
+	}
+}
+
+func TestNewPageLayout_LandscapeAndMargins(t *testing.T) {
+	l, err := NewPageLayout("A4", "landscape", "10mm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.WidthMm != 297 || l.HeightMm != 210 {
+		t.Fatalf("landscape A4 = %gx%g, want 297x210", l.WidthMm, l.HeightMm)
+	}
+	w, h := l.ViewportCssPixels()
+	wantW := int(math.Round(277 * CssPxPerMm))
+	wantH := int(math.Round(190 * CssPxPerMm))
+	if w != wantW || h != wantH {
+		t.Errorf("ViewportCssPixels() = %dx%d, want %dx%d", w, h, wantW, wantH)
+	}
+	if got := l.ContentAreaHeightPx("0"); got != wantH {
+		t.Errorf("ContentAreaHeightPx(0) = %d, want %d", got, wantH)
+	}
+
+	if _, err := NewPageLayout("A6", "", "60mm"); err == nil {
+		t.Error("margins wider than the page should fail")
+	}
+}
+
+func TestNewPageLayout_CustomSizeOrientation(t *testing.T) {
+	tests := []struct {
+		format, orientation string
+		wantW, wantH        float64
+	}{
+		{"100x50mm", "", 100, 50},
+		{"100x50mm", "portrait", 50, 100},
+		{"100x50mm", "landscape", 100, 50},
+		{"50x100mm", "", 50, 100},
+		{"50x100mm", "landscape", 100, 50},
+		{"A4", "", 210, 297},
+	}
+	for _, tt := range tests {
+		l, err := NewPageLayout(tt.format, tt.orientation, "")
+		if err != nil {
+			t.Fatalf("NewPageLayout(%q, %q): %v", tt.format, tt.orientation, err)
+		}
+		if l.WidthMm != tt.wantW || l.HeightMm != tt.wantH {
+			t.Errorf("NewPageLayout(%q, %q) = %gx%g, want %gx%g", tt.format, tt.orientation, l.WidthMm, l.HeightMm, tt.wantW, tt.wantH)
+		}
+	}
+}
@@ -2,10 +2,10 @@ package utils
 
 import (
 	"context"
-	"errors"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/chromedp/cdproto/page"
 	"github.com/chromedp/chromedp"
@@ -18,24 +18,18 @@ type Format struct {
 	Height float64
 }
 
-// Define the available formats
-var formats = []Format{
-	{"A1", 23.39, 33.11},
-	{"A2", 16.54, 23.39},
-	{"A3", 11.69, 16.54},
-	{"A4", 8.27, 11.69},
-	{"A5", 5.83, 8.27},
-	{"A6", 4.13, 5.83},
-}
-
-// GetFormat retrieves the format based on the title.
+// GetFormat retrieves the portrait size of a named (A4, Letter, B5...) or
+// custom ("210x99mm") paper size, in inches.
 func GetFormat(title string) (*Format, error) {
-	for _, f := range formats {
-		if f.Title == title {
-			return &f, nil
-		}
+	w, h, err := ParsePaperSize(title)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("format not found")
+	return &Format{
+		Title:  strings.ToUpper(strings.TrimSpace(title)),
+		Width:  w / 25.4,
+		Height: h / 25.4,
+	}, nil
 }
 
 // GeneratePDF generates a PDF from the given HTML content and saves it to a file
@@ -18,30 +18,34 @@ type paperMm struct {
 	heightMm float64
 }
 
+// paperSizesMM lists named sizes in portrait, keyed by upper-case name.
 var paperSizesMM = map[string]paperMm{
-	"A1": {841, 1189},
-	"A2": {594, 841},
-	"A3": {420, 594},
-	"A4": {210, 297},
-	"A5": {148, 210},
-	"A6": {105, 148},
+	"A0":        {841, 1189},
+	"A1":        {594, 841},
+	"A2":        {420, 594},
+	"A3":        {297, 420},
+	"A4":        {210, 297},
+	"A5":        {148, 210},
+	"A6":        {105, 148},
+	"B0":        {1000, 1414},
+	"B1":        {707, 1000},
+	"B2":        {500, 707},
+	"B3":        {353, 500},
+	"B4":        {250, 353},
+	"B5":        {176, 250},
+	"B6":        {125, 176},
+	"LETTER":    {215.9, 279.4},
+	"LEGAL":     {215.9, 355.6},
+	"TABLOID":   {279.4, 431.8},
+	"EXECUTIVE": {184.15, 266.7},
 }
 
 var paddingTokenRe = regexp.MustCompile(`(?i)^([\d.]+|\.\d+)(px|rem|mm|cm|%)?$`)
 
-// PaperViewportCssPixels returns page width/height in CSS pixels (portrait API path).
+// PaperViewportCssPixels returns page width/height in CSS pixels (portrait, no margins).
+// Unknown formats fall back to A4; use NewPageLayout for orientation and margins.
 func PaperViewportCssPixels(formatTitle string) (widthPx, heightPx int) {
-	key := strings.ToUpper(strings.TrimSpace(formatTitle))
-	if key == "" {
-		key = "A4"
-	}
-	d, ok := paperSizesMM[key]
-	if !ok {
-		d = paperSizesMM["A4"]
-	}
-	w := int(math.Round(d.widthMm * CssPxPerMm))
-	h := int(math.Round(d.heightMm * CssPxPerMm))
-	return w, h
+	return layoutOrA4(formatTitle).ViewportCssPixels()
 }
 
 // ParseVerticalPaddingPx parses CSS padding shorthand (top + bottom) into pixels.
@@ -93,20 +97,12 @@ func ParseVerticalPaddingPx(paddingCss string) int {
 
 // ContentAreaHeightPx is usable content height per page (page height minus vertical padding).
 func ContentAreaHeightPx(formatTitle, paddingStored string) int {
-	_, h := PaperViewportCssPixels(formatTitle)
-	padCss := EffectivePdfContentPadding(paddingStored)
-	verticalPad := ParseVerticalPaddingPx(padCss)
-	out := h - verticalPad
-	if out < 1 {
-		return 1
-	}
-	return out
+	return layoutOrA4(formatTitle).ContentAreaHeightPx(paddingStored)
 }
 
 // ContentAreaWidthPx is the fixed .content width in CSS pixels.
 func ContentAreaWidthPx(formatTitle string) int {
-	w, _ := PaperViewportCssPixels(formatTitle)
-	return w
+	return layoutOrA4(formatTitle).ContentAreaWidthPx()
 }
 
 // PdfPrintBreakCSS is shared with frontend pdfPageLayout.ts.
@@ -4,9 +4,43 @@ import (
 	"github.com/aymerick/raymond"
 )
 
+// RenderOptions are the per-render additions to the Handlebars environment.
+type RenderOptions struct {
+	// Partials are the sources of the partials usable with {{> name}}.
+	Partials map[string]string
+	// Locale is the default locale of the formatting helpers and {{@locale}}.
+	Locale string
+	// Messages are the translations of {{t "key"}} (see Translations.Messages).
+	Messages map[string]string
+}
+
 func RenderTemplate(htmlContent string, data interface{}) (string, error) {
+	return RenderTemplateWith(htmlContent, data, RenderOptions{})
+}
+
+// RenderTemplateWith renders htmlContent like RenderTemplate with the partials,
+// locale and translations of opts.
+func RenderTemplateWith(htmlContent string, data interface{}, opts RenderOptions) (string, error) {
+	registerTemplateHelpers()
+
 	// Parse and execute the template with data using Handlebars
-	rendered, err := raymond.Render(string(htmlContent), data)
+	tpl, err := raymond.Parse(htmlContent)
+	if err != nil {
+		return "", err
+	}
+	if len(opts.Partials) > 0 {
+		// raymond recurses without limit: a cycle would overflow the stack.
+		if err := CheckPartialCycles(opts.Partials); err != nil {
+			return "", err
+		}
+		tpl.RegisterPartials(opts.Partials)
+	}
+	privData := raymond.NewDataFrame()
+	if opts.Locale != "" {
+		privData.Set(localeDataKey, opts.Locale)
+	}
+	privData.Set(messagesDataKey, opts.Messages)
+	rendered, err := tpl.ExecWith(data, privData)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aymerick/raymond"
+)
+
+// TemplateHelper documents a Handlebars helper available in every template.
+type TemplateHelper struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Usage       []string `json:"usage"`
+	fn          interface{}
+}
+
+var templateHelpers = []TemplateHelper{
+	{
+		Name:        "t",
+		Description: "Translates a key with the template translations of the render locale (see locale fallbacks); hash arguments fill {name} placeholders and count picks the key.zero/key.one/key.other variant.",
+		Usage:       []string{`{{t "invoice.title"}}`, `{{t "greeting" name=customer.name}}`, `{{t "items" count=items.length}}`},
+		fn:          translateHelper,
+	},
+	{
+		Name:        "formatCurrency",
+		Description: "Formats an amount in an ISO 4217 currency for a locale (default: the render locale, else en-US).",
+		Usage:       []string{`{{formatCurrency total "EUR" locale="fr-FR"}} → 1 234,50 €`},
+		fn:          formatCurrencyHelper,
+	},
+	{
+		Name:        "formatNumber",
+		Description: "Formats a number with the separators of a locale (default: the render locale); decimals defaults to 2.",
+		Usage:       []string{`{{formatNumber 1234.5 decimals=1 locale="de-DE"}} → 1.234,5`},
+		fn:          formatNumberHelper,
+	},
+	{
+		Name:        "formatDate",
+		Description: "Formats a date (RFC 3339, YYYY-MM-DD, Unix seconds or \"now\") with YYYY, YY, MMMM, MMM, MM, M, DD, D, dddd, ddd, HH, mm, ss tokens; month and day names follow locale (default: the render locale).",
+		Usage:       []string{`{{formatDate issued_at "DD MMMM YYYY" locale="fr"}} → 05 mars 2025`, `{{formatDate "now" "YYYY-MM-DD"}}`},
+		fn:          formatDateHelper,
+	},
+	{
+		Name:        "sum",
+		Description: "Sums a list of numbers, or a field of a list of objects, optionally multiplied by another field.",
+		Usage:       []string{`{{sum amounts}}`, `{{sum items field="price" times="quantity"}}`},
+		fn:          sumHelper,
+	},
+	{
+		Name:        "add",
+		Description: "Adds two numbers.",
+		Usage:       []string{`{{add subtotal shipping}}`},
+		fn: func(a, b interface{}) interface{} {
+			return arithmetic("add", a, b, func(x, y float64) float64 { return x + y })
+		},
+	},
+	{
+		Name:        "subtract",
+		Description: "Subtracts the second number from the first.",
+		Usage:       []string{`{{subtract total discount}}`},
+		fn: func(a, b interface{}) interface{} {
+			return arithmetic("subtract", a, b, func(x, y float64) float64 { return x - y })
+		},
+	},
+	{
+		Name:        "multiply",
+		Description: "Multiplies two numbers.",
+		Usage:       []string{`{{multiply quantity price}}`, `{{formatCurrency (multiply quantity price) "EUR"}}`},
+		fn: func(a, b interface{}) interface{} {
+			return arithmetic("multiply", a, b, func(x, y float64) float64 { return x * y })
+		},
+	},
+	{
+		Name:        "divide",
+		Description: "Divides the first number by the second (division by zero is an error).",
+		Usage:       []string{`{{divide total 3}}`},
+		fn:          divideHelper,
+	},
+	{
+		Name:        "round",
+		Description: "Rounds a number half away from zero; decimals defaults to 0.",
+		Usage:       []string{`{{round 2.345 decimals=2}} → 2.35`},
+		fn:          roundHelper,
+	},
+	{
+		Name:        "eq",
+		Description: "True when both values are equal (numbers are compared numerically). Use as a subexpression.",
+		Usage:       []string{`{{#if (eq status "paid")}}Paid{{/if}}`},
+		fn:          func(a, b interface{}) interface{} { return compareValues(a, b) == 0 },
+	},
+	{
+		Name:        "ne",
+		Description: "True when the values differ.",
+		Usage:       []string{`{{#if (ne country "FR")}}Export{{/if}}`},
+		fn:          func(a, b interface{}) interface{} { return compareValues(a, b) != 0 },
+	},
+	{
+		Name:        "gt",
+		Description: "True when the first value is greater than the second.",
+		Usage:       []string{`{{#if (gt total 1000)}}Large order{{/if}}`},
+		fn:          func(a, b interface{}) interface{} { return compareValues(a, b) > 0 },
+	},
+	{
+		Name:        "gte",
+		Description: "True when the first value is greater than or equal to the second.",
+		Usage:       []string{`{{#if (gte quantity 10)}}Bulk{{/if}}`},
+		fn:          func(a, b interface{}) interface{} { return compareValues(a, b) >= 0 },
+	},
+	{
+		Name:        "lt",
+		Description: "True when the first value is less than the second.",
+		Usage:       []string{`{{#if (lt stock 5)}}Low stock{{/if}}`},
+		fn:          func(a, b interface{}) interface{} { return compareValues(a, b) < 0 },
+	},
+	{
+		Name:        "lte",
+		Description: "True when the first value is less than or equal to the second.",
+		Usage:       []string{`{{#if (lte balance 0)}}Settled{{/if}}`},
+		fn:          func(a, b interface{}) interface{} { return compareValues(a, b) <= 0 },
+	},
+	{
+		Name:        "default",
+		Description: "Returns the value, or the fallback when the value is missing, empty, false or 0.",
+		Usage:       []string{`{{default customer.vat_number "N/A"}}`},
+		fn:          defaultHelper,
+	},
+	{
+		Name:        "uppercase",
+		Description: "Converts a string to upper case.",
+		Usage:       []string{`{{uppercase invoice.reference}}`},
+		fn:          func(s string) string { return strings.ToUpper(s) },
+	},
+	{
+		Name:        "lowercase",
+		Description: "Converts a string to lower case.",
+		Usage:       []string{`{{lowercase email}}`},
+		fn:          func(s string) string { return strings.ToLower(s) },
+	},
+	{
+		Name:        "pluralize",
+		Description: "Prints the count followed by the singular or plural word (singular + \"s\" unless plural is given). includeCount=false prints the word only.",
+		Usage:       []string{`{{pluralize items.length "item"}} → 3 items`, `{{pluralize count "box" plural="boxes" includeCount=false}}`},
+		fn:          pluralizeHelper,
+	},
+	{
+		Name:        "qrcode",
+		Description: "Encodes text as a QR code PNG data URL for an <img src>; size in pixels (default 200).",
+		Usage:       []string{`<img src="{{qrcode payment_link size=160}}">`},
+		fn:          qrcodeHelper,
+	},
+	{
+		Name:        "barcode",
+		Description: "Encodes text as a barcode PNG data URL; type is code128 (default), code39 or ean13, width/height in pixels (default 300x80).",
+		Usage:       []string{`<img src="{{barcode sku type="ean13"}}">`},
+		fn:          barcodeHelper,
+	},
+}
+
+var registerHelpersOnce sync.Once
+
+// registerTemplateHelpers registers the helper library with raymond (once per process).
+func registerTemplateHelpers() {
+	registerHelpersOnce.Do(func() {
+		for _, h := range templateHelpers {
+			raymond.RegisterHelper(h.Name, h.fn)
+		}
+	})
+}
+
+// TemplateHelpers returns the documentation of the helpers available in templates.
+func TemplateHelpers() []TemplateHelper {
+	out := make([]TemplateHelper, len(templateHelpers))
+	copy(out, templateHelpers)
+	return out
+}
+
+// helperError aborts the render; raymond turns it into the Render error.
+func helperError(helper, format string, args ...interface{}) error {
+	return fmt.Errorf("helper %s: %s", helper, fmt.Sprintf(format, args...))
+}
+
+// toFloat converts numbers and numeric strings.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case nil:
+		return 0, false
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func mustFloat(helper string, v interface{}) float64 {
+	f, ok := toFloat(v)
+	if !ok {
+		panic(helperError(helper, "%v is not a number", v))
+	}
+	return f
+}
+
+// cleanFloat drops binary noise (0.1+0.2) so results print as expected.
+func cleanFloat(f float64) float64 {
+	return math.Round(f*1e9) / 1e9
+}
+
+func hashInt(options *raymond.Options, name string, def int) int {
+	v := options.HashProp(name)
+	if v == nil {
+		return def
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return def
+	}
+	return int(f)
+}
+
+func hashBool(options *raymond.Options, name string, def bool) bool {
+	v := options.HashProp(name)
+	if v == nil {
+		return def
+	}
+	return raymond.IsTrue(v)
+}
+
+func arithmetic(helper string, a, b interface{}, op func(x, y float64) float64) interface{} {
+	return cleanFloat(op(mustFloat(helper, a), mustFloat(helper, b)))
+}
+
+func divideHelper(a, b interface{}) interface{} {
+	divisor := mustFloat("divide", b)
+	if divisor == 0 {
+		panic(helperError("divide", "division by zero"))
+	}
+	return cleanFloat(mustFloat("divide", a) / divisor)
+}
+
+func roundHelper(v interface{}, options *raymond.Options) interface{} {
+	decimals := hashInt(options, "decimals", 0)
+	factor := math.Pow(10, float64(decimals))
+	return cleanFloat(math.Round(mustFloat("round", v)*factor) / factor)
+}
+
+func sumHelper(list interface{}, options *raymond.Options) interface{} {
+	field := options.HashStr("field")
+	times := options.HashStr("times")
+
+	rv := reflect.ValueOf(list)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		if list == nil {
+			return 0.0
+		}
+		return mustFloat("sum", list)
+	}
+
+	total := 0.0
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i).Interface()
+		value := item
+		if field != "" {
+			value = fieldOf(item, field)
+		}
+		if value == nil {
+			continue
+		}
+		n := mustFloat("sum", value)
+		if times != "" {
+			n *= mustFloat("sum", fieldOf(item, times))
+		}
+		total += n
+	}
+	return cleanFloat(total)
+}
+
+// fieldOf reads a key of a JSON object decoded as a map.
+func fieldOf(item interface{}, field string) interface{} {
+	if m, ok := item.(map[string]interface{}); ok {
+		return m[field]
+	}
+	rv := reflect.ValueOf(item)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		if v := rv.MapIndex(reflect.ValueOf(field)); v.IsValid() {
+			return v.Interface()
+		}
+	}
+	return nil
+}
+
+// compareValues compares numerically when both values are numbers, as strings otherwise.
+func compareValues(a, b interface{}) int {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(raymond.Str(a), raymond.Str(b))
+}
+
+func defaultHelper(value, fallback interface{}) interface{} {
+	if raymond.IsTrue(value) {
+		return value
+	}
+	return fallback
+}
+
+func pluralizeHelper(count interface{}, singular string, options *raymond.Options) string {
+	n := mustFloat("pluralize", count)
+	word := singular
+	if n != 1 && n != -1 {
+		word = options.HashStr("plural")
+		if word == "" {
+			word = singular + "s"
+		}
+	}
+	if !hashBool(options, "includeCount", true) {
+		return word
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64) + " " + word
+}
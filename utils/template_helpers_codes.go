@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"strings"
+
+	"github.com/aymerick/raymond"
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+)
+
+const (
+	defaultQRCodeSize    = 200
+	defaultBarcodeWidth  = 300
+	defaultBarcodeHeight = 80
+	maxCodeImageSize     = 2000
+)
+
+func qrcodeHelper(text string, options *raymond.Options) raymond.SafeString {
+	if text == "" {
+		panic(helperError("qrcode", "text is empty"))
+	}
+	code, err := qr.Encode(text, qr.M, qr.Auto)
+	if err != nil {
+		panic(helperError("qrcode", "%v", err))
+	}
+	size := codeImageSize(hashInt(options, "size", defaultQRCodeSize))
+	return codeDataURL("qrcode", code, size, size)
+}
+
+func barcodeHelper(text string, options *raymond.Options) raymond.SafeString {
+	if text == "" {
+		panic(helperError("barcode", "text is empty"))
+	}
+	var code barcode.Barcode
+	var err error
+	switch kind := strings.ToLower(options.HashStr("type")); kind {
+	case "", "code128":
+		code, err = code128.Encode(text)
+	case "code39":
+		code, err = code39.Encode(text, false, true)
+	case "ean13", "ean8", "ean":
+		code, err = ean.Encode(text)
+	default:
+		panic(helperError("barcode", "unsupported type %q (code128, code39, ean13)", kind))
+	}
+	if err != nil {
+		panic(helperError("barcode", "%v", err))
+	}
+	width := codeImageSize(hashInt(options, "width", defaultBarcodeWidth))
+	height := codeImageSize(hashInt(options, "height", defaultBarcodeHeight))
+	return codeDataURL("barcode", code, width, height)
+}
+
+func codeImageSize(n int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > maxCodeImageSize {
+		return maxCodeImageSize
+	}
+	return n
+}
+
+// codeDataURL scales the code (never below one pixel per module) and encodes it as a PNG data URL.
+func codeDataURL(helper string, code barcode.Barcode, width, height int) raymond.SafeString {
+	bounds := code.Bounds()
+	if width < bounds.Dx() {
+		width = bounds.Dx()
+	}
+	if height < bounds.Dy() {
+		height = bounds.Dy()
+	}
+	scaled, err := barcode.Scale(code, width, height)
+	if err != nil {
+		panic(helperError(helper, "%v", err))
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		panic(helperError(helper, "%v", err))
+	}
+	return raymond.SafeString("data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aymerick/raymond"
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+const defaultHelperLocale = "en-US"
+
+// suffixCurrencyLanguages write the currency symbol after the amount ("12,50 €").
+var suffixCurrencyLanguages = map[string]bool{
+	"fr": true, "de": true, "es": true, "it": true, "pl": true, "cs": true,
+	"sk": true, "sv": true, "fi": true, "nb": true, "no": true, "da": true,
+	"ru": true, "uk": true, "hu": true, "ro": true, "bg": true, "hr": true,
+	"sl": true, "lt": true, "lv": true, "et": true, "el": true, "vi": true,
+}
+
+// helperLocale returns the locale hash argument, defaulting to the render
+// locale and then to en-US.
+func helperLocale(helper string, options *raymond.Options) language.Tag {
+	name := options.HashStr("locale")
+	if name == "" {
+		name = options.DataStr(localeDataKey)
+	}
+	if name == "" {
+		name = defaultHelperLocale
+	}
+	tag, err := language.Parse(name)
+	if err != nil {
+		panic(helperError(helper, "unknown locale %q", name))
+	}
+	return tag
+}
+
+func formatDecimal(tag language.Tag, value float64, decimals int) string {
+	return message.NewPrinter(tag).Sprint(number.Decimal(value, number.Scale(decimals)))
+}
+
+func formatNumberHelper(value interface{}, options *raymond.Options) string {
+	tag := helperLocale("formatNumber", options)
+	return formatDecimal(tag, mustFloat("formatNumber", value), hashInt(options, "decimals", 2))
+}
+
+func formatCurrencyHelper(amount interface{}, code string, options *raymond.Options) string {
+	tag := helperLocale("formatCurrency", options)
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		panic(helperError("formatCurrency", "unknown currency %q", code))
+	}
+
+	value := mustFloat("formatCurrency", amount)
+	scale, _ := currency.Standard.Rounding(unit)
+	symbol := message.NewPrinter(tag).Sprint(currency.Symbol(unit))
+
+	negative := value < 0
+	if negative {
+		value = -value
+	}
+	formatted := formatDecimal(tag, value, scale)
+
+	base, _ := tag.Base()
+	if base.String() == "pt" && tag.String() != "pt-PT" {
+		formatted = symbol + " " + formatted
+	} else if suffixCurrencyLanguages[base.String()] || tag.String() == "pt-PT" {
+		formatted = formatted + " " + symbol
+	} else {
+		formatted = symbol + formatted
+	}
+	if negative {
+		formatted = "-" + formatted
+	}
+	return formatted
+}
+
+type dateNames struct {
+	months   [12]string
+	weekdays [7]string // Sunday first, as time.Weekday
+}
+
+var localeDateNames = map[string]dateNames{
+	"en": {
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	},
+	"fr": {
+		months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	},
+	"de": {
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	},
+	"es": {
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	},
+	"it": {
+		months:   [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		weekdays: [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+	},
+	"pt": {
+		months:   [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		weekdays: [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+	},
+	"nl": {
+		months:   [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		weekdays: [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+	},
+}
+
+// dateLayoutPresets are named layouts accepted in place of a token layout.
+var dateLayoutPresets = map[string]string{
+	"iso":      "YYYY-MM-DD",
+	"datetime": "YYYY-MM-DD HH:mm",
+	"short":    "DD/MM/YYYY",
+	"long":     "D MMMM YYYY",
+	"full":     "dddd D MMMM YYYY",
+}
+
+// dateTokens are matched longest first.
+var dateTokens = []string{"YYYY", "YY", "MMMM", "MMM", "MM", "M", "DD", "D", "dddd", "ddd", "HH", "mm", "ss"}
+
+var dateInputLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+func parseHelperDate(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		s := strings.TrimSpace(v)
+		if s == "now" {
+			return time.Now(), true
+		}
+		for _, layout := range dateInputLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Unix(n, 0).UTC(), true
+		}
+		return time.Time{}, false
+	}
+	if n, ok := toFloat(value); ok {
+		return time.Unix(int64(n), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func formatDateHelper(value interface{}, layout string, options *raymond.Options) string {
+	if value == nil || value == "" {
+		return ""
+	}
+	t, ok := parseHelperDate(value)
+	if !ok {
+		panic(helperError("formatDate", "%v is not a date", value))
+	}
+	if preset, ok := dateLayoutPresets[layout]; ok {
+		layout = preset
+	}
+
+	base, _ := helperLocale("formatDate", options).Base()
+	names, ok := localeDateNames[base.String()]
+	if !ok {
+		names = localeDateNames["en"]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(layout); {
+		if layout[i] == '[' {
+			// [literal] text is copied as is.
+			if end := strings.IndexByte(layout[i:], ']'); end > 0 {
+				b.WriteString(layout[i+1 : i+end])
+				i += end + 1
+				continue
+			}
+		}
+		token := ""
+		for _, tok := range dateTokens {
+			if strings.HasPrefix(layout[i:], tok) {
+				token = tok
+				break
+			}
+		}
+		if token == "" {
+			b.WriteByte(layout[i])
+			i++
+			continue
+		}
+		b.WriteString(formatDateToken(t, token, names))
+		i += len(token)
+	}
+	return b.String()
+}
+
+func formatDateToken(t time.Time, token string, names dateNames) string {
+	switch token {
+	case "YYYY":
+		return strconv.Itoa(t.Year())
+	case "YY":
+		return t.Format("06")
+	case "MMMM":
+		return names.months[t.Month()-1]
+	case "MMM":
+		return abbreviate(names.months[t.Month()-1])
+	case "MM":
+		return t.Format("01")
+	case "M":
+		return strconv.Itoa(int(t.Month()))
+	case "DD":
+		return t.Format("02")
+	case "D":
+		return strconv.Itoa(t.Day())
+	case "dddd":
+		return names.weekdays[t.Weekday()]
+	case "ddd":
+		return abbreviate(names.weekdays[t.Weekday()])
+	case "HH":
+		return t.Format("15")
+	case "mm":
+		return t.Format("04")
+	case "ss":
+		return t.Format("05")
+	}
+	return token
+}
+
+func abbreviate(name string) string {
+	runes := []rune(name)
+	if len(runes) <= 3 {
+		return name
+	}
+	return string(runes[:3])
+}
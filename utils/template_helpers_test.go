@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateHelpers(t *testing.T) {
+	data := map[string]interface{}{
+		"total":  1234.5,
+		"status": "paid",
+		"items": []interface{}{
+			map[string]interface{}{"price": 10.5, "quantity": 2.0},
+			map[string]interface{}{"price": 0.1, "quantity": 3.0},
+		},
+		"amounts": []interface{}{0.1, 0.2},
+		"date":    "2025-03-05T14:07:09Z",
+		"empty":   "",
+		"count":   3,
+	}
+	tests := []struct {
+		name string
+		tpl  string
+		want string
+	}{
+		{"currency en", `{{formatCurrency total "USD"}}`, "$1,234.50"},
+		{"currency fr", `{{formatCurrency total "EUR" locale="fr-FR"}}`, "1 234,50 €"},
+		{"currency de", `{{formatCurrency total "EUR" locale="de-DE"}}`, "1.234,50 €"},
+		{"currency no decimals", `{{formatCurrency total "JPY" locale="ja"}}`, "￥1,234"},
+		{"currency negative", `{{formatCurrency -5 "USD"}}`, "-$5.00"},
+		{"number", `{{formatNumber total decimals=1 locale="de-DE"}}`, "1.234,5"},
+		{"date tokens", `{{formatDate date "DD/MM/YYYY HH:mm:ss"}}`, "05/03/2025 14:07:09"},
+		{"date locale", `{{formatDate date "dddd D MMMM YYYY" locale="fr"}}`, "mercredi 5 mars 2025"},
+		{"date preset", `{{formatDate "2025-03-05" "iso"}}`, "2025-03-05"},
+		{"date literal", `{{formatDate date "[Day] D"}}`, "Day 5"},
+		{"sum list", `{{sum amounts}}`, "0.3"},
+		{"sum fields", `{{sum items field="price" times="quantity"}}`, "21.3"},
+		{"multiply", `{{multiply 3 0.1}}`, "0.3"},
+		{"nested", `{{formatCurrency (multiply 2 (add 1 0.5)) "USD"}}`, "$3.00"},
+		{"round", `{{round 2.345 decimals=2}}`, "2.35"},
+		{"eq", `{{#if (eq status "paid")}}yes{{else}}no{{/if}}`, "yes"},
+		{"eq numbers", `{{#if (eq count "3")}}yes{{else}}no{{/if}}`, "yes"},
+		{"gt", `{{#if (gt total 1000)}}big{{/if}}`, "big"},
+		{"lte", `{{#if (lte total 1000)}}small{{else}}big{{/if}}`, "big"},
+		{"default", `{{default empty "N/A"}}`, "N/A"},
+		{"default missing", `{{default missing.field "N/A"}}`, "N/A"},
+		{"uppercase", `{{uppercase status}}`, "PAID"},
+		{"pluralize", `{{pluralize count "item"}}`, "3 items"},
+		{"pluralize one", `{{pluralize 1 "box" plural="boxes"}}`, "1 box"},
+		{"pluralize word", `{{pluralize 2 "box" plural="boxes" includeCount=false}}`, "boxes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.tpl, data)
+			if err != nil {
+				t.Fatalf("RenderTemplate(%q) error: %v", tt.tpl, err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderTemplate(%q) = %q, want %q", tt.tpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateHelperCodes(t *testing.T) {
+	for _, tpl := range []string{
+		`{{qrcode "https://example.com" size=120}}`,
+		`{{barcode "ABC-123"}}`,
+		`{{barcode "5901234123457" type="ean13"}}`,
+	} {
+		got, err := RenderTemplate(tpl, nil)
+		if err != nil {
+			t.Fatalf("RenderTemplate(%q) error: %v", tpl, err)
+		}
+		if !strings.HasPrefix(got, "data:image/png;base64,") {
+			t.Errorf("RenderTemplate(%q) = %.40q, want a PNG data URL", tpl, got)
+		}
+	}
+}
+
+func TestTemplateHelperErrors(t *testing.T) {
+	for _, tpl := range []string{
+		`{{formatCurrency "abc" "EUR"}}`,
+		`{{formatCurrency 1 "XYZW"}}`,
+		`{{divide 1 0}}`,
+		`{{formatDate "yesterday" "iso"}}`,
+		`{{barcode "123" type="ean13"}}`,
+	} {
+		if _, err := RenderTemplate(tpl, nil); err == nil {
+			t.Errorf("RenderTemplate(%q) should fail", tpl)
+		}
+	}
+}
+
+func TestTemplateHelpersDocumented(t *testing.T) {
+	for _, h := range TemplateHelpers() {
+		if h.Description == "" || len(h.Usage) == 0 {
+			t.Errorf("helper %s is missing its documentation", h.Name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aymerick/raymond"
+	"golang.org/x/text/language"
+)
+
+// FallbackLocale is the last locale tried for a missing translation.
+const FallbackLocale = "en"
+
+// Private data keys of a render (see RenderTemplateWith); locale is readable as {{@locale}}.
+const (
+	localeDataKey   = "locale"
+	messagesDataKey = "_messages"
+)
+
+// Translations are the message bundles of a template by canonical locale,
+// with nested keys flattened to dotted keys.
+type Translations map[string]map[string]string
+
+// NormalizeLocale returns the canonical BCP 47 form of a locale ("fr_fr" → "fr-FR").
+func NormalizeLocale(locale string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(locale))
+	if err != nil {
+		return "", fmt.Errorf("invalid locale %q", locale)
+	}
+	return tag.String(), nil
+}
+
+// ParseTranslations reads the translations JSON of a template:
+// {"en": {"invoice": {"title": "Invoice"}}, "fr": {"invoice.title": "Facture"}}.
+// Locales are normalized and nested objects flattened to dotted keys.
+func ParseTranslations(raw []byte) (Translations, error) {
+	bundles := Translations{}
+	if len(strings.TrimSpace(string(raw))) == 0 || string(raw) == "null" {
+		return bundles, nil
+	}
+	var byLocale map[string]map[string]interface{}
+	if err := json.Unmarshal(raw, &byLocale); err != nil {
+		return nil, fmt.Errorf("translations must map locales to objects of messages: %v", err)
+	}
+	for locale, messages := range byLocale {
+		canonical, err := NormalizeLocale(locale)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := bundles[canonical]; dup {
+			return nil, fmt.Errorf("locale %q is defined twice", canonical)
+		}
+		flat := map[string]string{}
+		if err := flattenMessages(flat, "", messages); err != nil {
+			return nil, fmt.Errorf("locale %q: %v", locale, err)
+		}
+		bundles[canonical] = flat
+	}
+	return bundles, nil
+}
+
+func flattenMessages(out map[string]string, prefix string, messages map[string]interface{}) error {
+	for key, value := range messages {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case string:
+			out[key] = v
+		case map[string]interface{}:
+			if err := flattenMessages(out, key, v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("message %q must be a string", key)
+		}
+	}
+	return nil
+}
+
+// Locales returns the locales of the bundles, sorted.
+func (t Translations) Locales() []string {
+	locales := make([]string, 0, len(t))
+	for locale := range t {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// LocaleFallbacks returns the locales tried in order for a message: the
+// requested locale and its parents (fr-CA, fr), then the template default
+// locale and its parents, then FallbackLocale.
+func LocaleFallbacks(requested, defaultLocale string) []string {
+	var chain []string
+	seen := map[string]bool{}
+	add := func(locale string) {
+		tag, err := language.Parse(locale)
+		if err != nil {
+			return
+		}
+		for ; tag != language.Und; tag = tag.Parent() {
+			if s := tag.String(); !seen[s] {
+				seen[s] = true
+				chain = append(chain, s)
+			}
+		}
+	}
+	add(requested)
+	add(defaultLocale)
+	add(FallbackLocale)
+	return chain
+}
+
+// Messages merges the bundles along the fallback chain of locale: each key
+// takes the message of the first locale of the chain that defines it.
+func (t Translations) Messages(locale, defaultLocale string) map[string]string {
+	messages := map[string]string{}
+	chain := LocaleFallbacks(locale, defaultLocale)
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, msg := range t[chain[i]] {
+			messages[key] = msg
+		}
+	}
+	return messages
+}
+
+// translateHelper is {{t "key"}}. Hash arguments fill {name} placeholders
+// (escaped); count also selects the key.zero / key.one / key.other variant.
+// A key missing from every bundle of the fallback chain renders as the key.
+func translateHelper(key string, options *raymond.Options) raymond.SafeString {
+	messages, _ := options.Data(messagesDataKey).(map[string]string)
+	hash := options.Hash()
+
+	msg, ok := "", false
+	if count, hasCount := hash["count"]; hasCount {
+		n, _ := toFloat(count)
+		variants := []string{key + ".other"}
+		switch n {
+		case 0:
+			variants = []string{key + ".zero", key + ".other"}
+		case 1:
+			variants = []string{key + ".one"}
+		}
+		for _, variant := range variants {
+			if msg, ok = messages[variant]; ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		if msg, ok = messages[key]; !ok {
+			return raymond.SafeString(raymond.Escape(key))
+		}
+	}
+
+	if len(hash) > 0 && strings.Contains(msg, "{") {
+		pairs := make([]string, 0, 2*len(hash))
+		for name, value := range hash {
+			pairs = append(pairs, "{"+name+"}", raymond.Escape(raymond.Str(value)))
+		}
+		msg = strings.NewReplacer(pairs...).Replace(msg)
+	}
+	return raymond.SafeString(msg)
+}
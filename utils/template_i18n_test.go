@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTranslations(t *testing.T) {
+	bundles, err := ParseTranslations([]byte(`{
+		"en": {"invoice": {"title": "Invoice", "due": "Due on {date}"}},
+		"fr_fr": {"invoice.title": "Facture"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bundles["en"]["invoice.due"]; got != "Due on {date}" {
+		t.Errorf("nested key = %q", got)
+	}
+	if got := bundles["fr-FR"]["invoice.title"]; got != "Facture" {
+		t.Errorf("normalized locale bundle = %v", bundles["fr-FR"])
+	}
+	if got := bundles.Locales(); !reflect.DeepEqual(got, []string{"en", "fr-FR"}) {
+		t.Errorf("Locales() = %v", got)
+	}
+
+	for _, raw := range []string{
+		`{"en": "Invoice"}`,
+		`{"en": {"count": 3}}`,
+		`{"not a locale!": {}}`,
+		`{"fr-FR": {}, "fr_FR": {}}`,
+	} {
+		if _, err := ParseTranslations([]byte(raw)); err == nil {
+			t.Errorf("ParseTranslations(%s) should fail", raw)
+		}
+	}
+	if b, err := ParseTranslations(nil); err != nil || len(b) != 0 {
+		t.Errorf("empty translations = %v, %v", b, err)
+	}
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	got := LocaleFallbacks("fr-CA", "es-ES")
+	want := []string{"fr-CA", "fr", "es-ES", "es", "en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LocaleFallbacks() = %v, want %v", got, want)
+	}
+	if got := LocaleFallbacks("", ""); !reflect.DeepEqual(got, []string{"en"}) {
+		t.Errorf("LocaleFallbacks(empty) = %v", got)
+	}
+}
+
+func TestTranslateHelper(t *testing.T) {
+	bundles, err := ParseTranslations([]byte(`{
+		"en": {"title": "Invoice", "thanks": "Thank you {name}!", "items": {"one": "{count} item", "other": "{count} items"}, "only_en": "English"},
+		"fr": {"title": "Facture", "thanks": "Merci {name} !", "items": {"zero": "aucun article", "one": "{count} article", "other": "{count} articles"}},
+		"es": {"title": "Factura"}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{"name": "<Ana>", "n": 0, "total": 1234.5}
+	tpl := `{{t "title"}}|{{t "thanks" name=name}}|{{t "items" count=n}}|{{t "only_en"}}|{{t "missing.key"}}|{{formatNumber total}}|{{@locale}}`
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"fr-FR", "Facture|Merci &lt;Ana&gt; !|aucun article|English|missing.key|1\u00a0234,50|fr-FR"},
+		{"es", "Factura|Thank you &lt;Ana&gt;!|0 items|English|missing.key|1.234,50|es"},
+		{"de", "Invoice|Thank you &lt;Ana&gt;!|0 items|English|missing.key|1.234,50|de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			got, err := RenderTemplateWith(tpl, data, RenderOptions{Locale: tt.locale, Messages: bundles.Messages(tt.locale, "")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("render = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// partialRefPattern matches {{> name}}, {{~> name}} and {{> "name"}} partial calls.
+var partialRefPattern = regexp.MustCompile(`\{\{~?>\s*(?:"([^"]+)"|'([^']+)'|([^\s}~()"']+))`)
+
+// PartialNamePattern is the accepted form of partial names.
+var PartialNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_-]{0,63}$`)
+
+// PartialReferences returns the distinct partial names called by a template
+// source, in order of first use. Dynamic partials ({{> (expr)}}) are ignored.
+func PartialReferences(source string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range partialRefPattern.FindAllStringSubmatch(source, -1) {
+		name := m[1] + m[2] + m[3]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ReachablePartials returns the partials of all that are called, directly or
+// through other partials, by sources.
+func ReachablePartials(all map[string]string, sources ...string) map[string]string {
+	reachable := map[string]string{}
+	var queue []string
+	for _, src := range sources {
+		queue = append(queue, PartialReferences(src)...)
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, done := reachable[name]; done {
+			continue
+		}
+		src, ok := all[name]
+		if !ok {
+			continue
+		}
+		reachable[name] = src
+		queue = append(queue, PartialReferences(src)...)
+	}
+	return reachable
+}
+
+// CheckPartialCycles returns an error naming the partials of a cycle when a
+// partial calls itself, directly or through other partials.
+func CheckPartialCycles(partials map[string]string) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+				}
+			}
+			return fmt.Errorf("partial cycle: %s > %s", strings.Join(path[start:], " > "), name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, ref := range PartialReferences(partials[name]) {
+			if _, ok := partials[ref]; !ok {
+				continue
+			}
+			if err := visit(ref); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	names := make([]string, 0, len(partials))
+	for name := range partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
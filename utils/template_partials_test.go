@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPartialReferences(t *testing.T) {
+	src := `{{> letterhead}} {{~> footer company}} {{> "legal-notice"}} {{> letterhead}} {{> (dynamic)}}`
+	want := []string{"letterhead", "footer", "legal-notice"}
+	if got := PartialReferences(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("PartialReferences() = %v, want %v", got, want)
+	}
+}
+
+func TestReachablePartials(t *testing.T) {
+	all := map[string]string{
+		"letterhead": `<header>{{> logo}}</header>`,
+		"logo":       `<img src="{{logo_url}}">`,
+		"unused":     `<p>unused</p>`,
+	}
+	got := ReachablePartials(all, `{{> letterhead}}{{> missing}}`)
+	if len(got) != 2 || got["letterhead"] == "" || got["logo"] == "" {
+		t.Errorf("ReachablePartials() = %v, want letterhead and logo", got)
+	}
+}
+
+func TestCheckPartialCycles(t *testing.T) {
+	if err := CheckPartialCycles(map[string]string{"a": `{{> b}}`, "b": `{{> c}}`, "c": `x`}); err != nil {
+		t.Errorf("acyclic partials: unexpected error %v", err)
+	}
+	err := CheckPartialCycles(map[string]string{"a": `{{> b}}`, "b": `{{> a}}`})
+	if err == nil || !strings.Contains(err.Error(), "a > b > a") {
+		t.Errorf("cycle: error = %v, want a > b > a", err)
+	}
+	if err := CheckPartialCycles(map[string]string{"self": `{{> self}}`}); err == nil {
+		t.Error("self-reference should be a cycle")
+	}
+}
+
+func TestRenderTemplateWithPartials(t *testing.T) {
+	partials := map[string]string{
+		"letterhead": `<h1>{{company.name}}</h1>{{> address company}}`,
+		"address":    `<p>{{city}}</p>`,
+	}
+	data := map[string]interface{}{"company": map[string]interface{}{"name": "Acme", "city": "Lyon"}}
+
+	got, err := RenderTemplateWith(`{{> letterhead}}<main>{{uppercase company.name}}</main>`, data, RenderOptions{Partials: partials})
+	if err != nil {
+		t.Fatalf("RenderTemplateWith() error: %v", err)
+	}
+	if want := `<h1>Acme</h1><p>Lyon</p><main>ACME</main>`; got != want {
+		t.Errorf("RenderTemplateWith() = %q, want %q", got, want)
+	}
+
+	if _, err := RenderTemplateWith(`{{> missing}}`, data, RenderOptions{Partials: partials}); err == nil {
+		t.Error("unknown partial should fail")
+	}
+	if _, err := RenderTemplateWith(`{{> a}}`, data, RenderOptions{Partials: map[string]string{"a": `{{> a}}`}}); err == nil {
+		t.Error("cyclic partials should fail instead of recursing")
+	}
+}
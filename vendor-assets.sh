@@ -0,0 +1,118 @@
+#!/bin/bash
+# Télécharge les assets CDN du moteur de rendu dans pkg/assets/vendor (embarqués au build)
+# et, optionnellement, le CLI Tailwind standalone.
+#
+#   FONTS="Inter,Roboto,Open Sans" ./vendor-assets.sh
+#   TAILWIND_BIN_OUT=/usr/local/bin/tailwindcss ./vendor-assets.sh
+#
+# Chaque fichier téléchargé est vérifié contre vendor-assets.sha256 ; un fichier
+# absent du lock ou dont l'empreinte diffère fait échouer le script. Après un
+# changement de version ou de polices, régénérer le lock puis le relire et le committer :
+#
+#   UPDATE_CHECKSUMS=1 FONTS="..." TAILWIND_BIN_OUT=/tmp/tailwindcss ./vendor-assets.sh
+set -euo pipefail
+
+VENDOR_DIR="${VENDOR_DIR:-$(dirname "$0")/pkg/assets/vendor}"
+HLJS_VERSION="11.11.1"
+TAILWIND_VERSION="${TAILWIND_VERSION:-v4.1.11}"
+# Les templates chargent bootstrap@5 et @tailwindcss/browser@4 : on télécharge une
+# version exacte (empreinte stable) rangée sous l'URL des templates.
+BOOTSTRAP_VERSION="${BOOTSTRAP_VERSION:-5.3.3}"
+CHECKSUMS="${CHECKSUMS:-$(dirname "$0")/vendor-assets.sha256}"
+UPDATE_CHECKSUMS="${UPDATE_CHECKSUMS:-}"
+FONTS="${FONTS:-Inter,Roboto,Open Sans,Lato,Montserrat,Poppins}"
+# Un user-agent récent pour que Google Fonts renvoie du woff2.
+FONT_UA="Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36"
+
+if [ -n "$UPDATE_CHECKSUMS" ]; then
+  pinned_sums="$(mktemp)"
+  trap 'rm -f "$pinned_sums"' EXIT
+elif [ ! -f "$CHECKSUMS" ]; then
+  echo "$CHECKSUMS introuvable : lancer avec UPDATE_CHECKSUMS=1 pour l'initialiser" >&2
+  exit 1
+fi
+
+# verify <nom> <fichier> : compare l'empreinte sha256 à celle du lock (format sha256sum),
+# ou l'enregistre avec UPDATE_CHECKSUMS=1.
+verify() {
+  local name="$1" file="$2" sum pinned
+  sum="$(sha256sum "$file" | cut -d' ' -f1)"
+  if [ -n "$UPDATE_CHECKSUMS" ]; then
+    echo "$sum  $name" >> "$pinned_sums"
+    return
+  fi
+  # 64 caractères d'empreinte + 2 espaces : le nom (qui peut contenir des espaces) commence en 67.
+  pinned="$(awk -v n="$name" 'substr($0, 67) == n { print substr($0, 1, 64) }' "$CHECKSUMS")"
+  if [ -z "$pinned" ]; then
+    rm -f "$file"
+    echo "Aucune empreinte pour $name dans $CHECKSUMS (UPDATE_CHECKSUMS=1 pour l'ajouter)" >&2
+    exit 1
+  fi
+  if [ "$sum" != "$pinned" ]; then
+    rm -f "$file"
+    echo "Empreinte invalide pour $name : $sum, attendu $pinned" >&2
+    exit 1
+  fi
+}
+
+# mirror <url> [url des templates] : télécharge url et la range sous l'URL des templates.
+mirror() {
+  local url="$1" as="${2:-$1}"
+  local name="${as#https://}"
+  local dest="$VENDOR_DIR/$name"
+  mkdir -p "$(dirname "$dest")"
+  curl -fsSL "$url" -o "$dest"
+  verify "$name" "$dest"
+  echo "  $as"
+}
+
+echo "Frameworks / highlight.js"
+mirror "https://cdn.jsdelivr.net/npm/@tailwindcss/browser@${TAILWIND_VERSION#v}" \
+  "https://cdn.jsdelivr.net/npm/@tailwindcss/browser@4"
+mirror "https://cdn.jsdelivr.net/npm/bootstrap@$BOOTSTRAP_VERSION/dist/css/bootstrap.min.css" \
+  "https://cdn.jsdelivr.net/npm/bootstrap@5/dist/css/bootstrap.min.css"
+mirror "https://cdnjs.cloudflare.com/ajax/libs/highlight.js/$HLJS_VERSION/highlight.min.js"
+mirror "https://cdnjs.cloudflare.com/ajax/libs/highlight.js/$HLJS_VERSION/styles/github-dark.min.css"
+
+echo "Google Fonts"
+IFS=',' read -ra families <<< "$FONTS"
+for family in "${families[@]}"; do
+  family="$(echo "$family" | xargs)"
+  [ -z "$family" ] && continue
+  css_dest="$VENDOR_DIR/fonts.googleapis.com/css2/$family.css"
+  mkdir -p "$(dirname "$css_dest")"
+  curl -fsSL -A "$FONT_UA" \
+    "https://fonts.googleapis.com/css2?family=${family// /+}:wght@100;200;300;400;500;600;700;800;900&display=swap" \
+    -o "$css_dest"
+  verify "fonts.googleapis.com/css2/$family.css" "$css_dest"
+  echo "  $family"
+  grep -o 'https://fonts.gstatic.com/[^)]*' "$css_dest" | sort -u | while read -r font_url; do
+    mirror "$font_url" > /dev/null
+  done
+done
+
+if [ -n "${TAILWIND_BIN_OUT:-}" ]; then
+  case "$(uname -m)" in
+    x86_64) arch="x64" ;;
+    aarch64|arm64) arch="arm64" ;;
+    *) echo "Architecture non supportée : $(uname -m)" >&2; exit 1 ;;
+  esac
+  echo "Tailwind CLI $TAILWIND_VERSION ($arch)"
+  curl -fsSL "https://github.com/tailwindlabs/tailwindcss/releases/download/$TAILWIND_VERSION/tailwindcss-linux-$arch" -o "$TAILWIND_BIN_OUT"
+  verify "tailwindcss-$TAILWIND_VERSION-linux-$arch" "$TAILWIND_BIN_OUT"
+  chmod +x "$TAILWIND_BIN_OUT"
+fi
+
+if [ -n "$UPDATE_CHECKSUMS" ]; then
+  # Conserve les empreintes déjà pinnées (autre architecture, autres polices).
+  if [ -f "$CHECKSUMS" ]; then
+    grep -v '^#' "$CHECKSUMS" >> "$pinned_sums" || true
+  fi
+  {
+    echo "# Empreintes sha256 des fichiers téléchargés par vendor-assets.sh."
+    echo "# Régénérer avec UPDATE_CHECKSUMS=1 ./vendor-assets.sh, relire le diff puis committer."
+    # Les entrées fraîches passent avant les anciennes : on garde la première par nom.
+    awk '!seen[substr($0, 67)]++' "$pinned_sums" | sort -k2
+  } > "$CHECKSUMS"
+  echo "Empreintes écrites dans $CHECKSUMS"
+fi